@@ -0,0 +1,463 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// the string resources of Android (res/values*/strings.xml):
+//
+//	<resources>
+//	    <!-- comment -->
+//	    <string name="hello">Hello <b>%s</b>, don\'t panic</string>
+//	    <plurals name="files">
+//	        <item quantity="one">%d file</item>
+//	        <item quantity="other">%d files</item>
+//	    </plurals>
+//	    <string-array name="days">
+//	        <item>Monday</item>
+//	    </string-array>
+//	</resources>
+//
+// a <string> becomes a trans-unit with the name as id, <plurals> and
+// <string-array> become a group with a trans-unit per item, the
+// quantity kept in resname. the comment before a resource becomes the
+// note. markup like <b> is kept as native code in <bpt>, <ept> and
+// <ph>; strings in CDATA are marked by restype.
+const (
+	ANDROID_PLURALS_RESTYPE = "x-android-plurals"
+	ANDROID_ARRAY_RESTYPE   = "x-android-string-array"
+	ANDROID_CDATA_RESTYPE   = "x-android-cdata"
+	ANDROID_XLIFF_G_CTYPE   = "x-android-xliff-g"
+
+	ANDROID_STRING  = "string"
+	ANDROID_PLURALS = "plurals"
+	ANDROID_ARRAY   = "string-array"
+)
+
+// androidResource is a <string>, <plurals> or <string-array>
+type androidResource struct {
+	Kind         string
+	Name         string
+	Translatable bool
+	Comment      string
+	Items        []androidItem // a single one for <string>
+}
+
+// androidItem is the value of a <string> or an <item>. the text of
+// Content is unescaped already.
+type androidItem struct {
+	Quantity  string // of the items of <plurals>
+	Content   xliffContent
+	CDATA     bool
+	Reference bool // @string/other, ?attr/other
+}
+
+func androidFromFile(fileName string) ([]*androidResource, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAndroid(f)
+}
+
+// readAndroid reads the string resources of a strings.xml, other
+// resources (<dimen>, <color>, ...) are skipped
+func readAndroid(r io.Reader) ([]*androidResource, error) {
+
+	var resources []*androidResource
+	comment := ""
+
+	d := xml.NewDecoder(r)
+	depth := 0
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return resources, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.Comment:
+			comment = strings.TrimSpace(string(t))
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			if depth == 0 {
+				depth++
+				continue
+			}
+			res := &androidResource{Kind: t.Name.Local, Translatable: true, Comment: comment}
+			comment = ""
+			for _, attr := range t.Attr {
+				switch attr.Name.Local {
+				case "name":
+					res.Name = attr.Value
+				case "translatable":
+					res.Translatable = attr.Value != "false"
+				}
+			}
+
+			switch res.Kind {
+			case ANDROID_STRING:
+				item, err := readAndroidItem(d, t)
+				if err != nil {
+					return nil, err
+				}
+				res.Items = append(res.Items, item)
+			case ANDROID_PLURALS, ANDROID_ARRAY:
+				if res.Items, err = readAndroidItems(d); err != nil {
+					return nil, err
+				}
+			default:
+				if err = d.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			resources = append(resources, res)
+		}
+	}
+}
+
+// readAndroidItems reads the <item>s of a <plurals> or <string-array>
+func readAndroidItems(d *xml.Decoder) ([]androidItem, error) {
+	var items []androidItem
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			item, err := readAndroidItem(d, t)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		case xml.EndElement:
+			return items, nil
+		}
+	}
+}
+
+func readAndroidItem(d *xml.Decoder, start xml.StartElement) (androidItem, error) {
+
+	var raw struct {
+		Quantity string `xml:"quantity,attr"`
+		Inner    string `xml:",innerxml"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return androidItem{}, err
+	}
+
+	item := androidItem{Quantity: raw.Quantity, CDATA: strings.Contains(raw.Inner, "<![CDATA[")}
+	trimmed := strings.TrimSpace(raw.Inner)
+	item.Reference = strings.HasPrefix(trimmed, "@") || strings.HasPrefix(trimmed, "?")
+
+	content, err := parseContent(raw.Inner)
+	if err != nil {
+		return androidItem{}, err
+	}
+	auto := 0
+	item.Content = androidToXliff(content, new(androidText), &auto)
+	return item, nil
+}
+
+// androidToXliff unescapes the text of content and turns markup into
+// native code: <bpt>/<ept> for elements with content, <ph> for empty
+// ones and for <xliff:g>, whose content is not to be translated
+func androidToXliff(content xliffContent, text *androidText, auto *int) xliffContent {
+
+	var mapped xliffContent
+	for _, inline := range content {
+		if inline.Name == "" {
+			mapped = mapped.appendText(text.unescape(inline.Text))
+			continue
+		}
+
+		mapped = mapped.appendText(text.flush())
+		*auto++
+		id := strconv.Itoa(*auto)
+		ctype := androidCtype(inline.Name)
+		attrs := appendAttr([]xml.Attr{tmxAttr("id", id)}, "ctype", ctype)
+
+		name := inline.Name
+		if ctype == ANDROID_XLIFF_G_CTYPE {
+			name = "xliff:g"
+		}
+		open := "<" + name
+		for _, attr := range inline.Attrs {
+			open += " " + xmlName(attr.Name) + `="` + escapedAttr(attr.Value) + `"`
+		}
+
+		switch {
+		case ctype == ANDROID_XLIFF_G_CTYPE:
+			code := open + ">" + inline.Content.XML() + "</" + name + ">"
+			mapped = append(mapped, xliffInline{Name: "ph", Attrs: attrs, Content: xliffContent{{Text: code}}})
+		case len(inline.Content) == 0:
+			mapped = append(mapped, xliffInline{Name: "ph", Attrs: attrs, Content: xliffContent{{Text: open + "/>"}}})
+		default:
+			mapped = append(mapped, xliffInline{Name: "bpt", Attrs: attrs, Content: xliffContent{{Text: open + ">"}}})
+			mapped = append(mapped, androidToXliff(inline.Content, text, auto)...)
+			mapped = append(mapped, xliffInline{Name: "ept", Attrs: []xml.Attr{tmxAttr("id", id)}, Content: xliffContent{{Text: "</" + name + ">"}}})
+		}
+	}
+	return mapped
+}
+
+// androidCtype returns the ctype of the native code for an element of
+// a string
+func androidCtype(name string) string {
+	switch name {
+	case "b":
+		return "bold"
+	case "i":
+		return "italic"
+	case "u":
+		return "underlined"
+	case "a":
+		return "link"
+	case "g": // <xliff:g>, the prefix is lost by readContent
+		return ANDROID_XLIFF_G_CTYPE
+	}
+	return "x-html-" + name
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// androidText unescapes the text of a string. the text of a string may
+// be split by markup, so the state is kept between the pieces.
+type androidText struct {
+	quoted  bool // within "..." whitespace is kept
+	space   bool // whitespace is pending
+	started bool // anything but whitespace was written
+}
+
+func (at *androidText) unescape(s string) string {
+
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		i += n
+
+		switch {
+		case r == '\\' && i < len(s):
+			buf.WriteString(at.flush())
+			e, m := utf8.DecodeRuneInString(s[i:])
+			i += m
+			switch e {
+			case 'n':
+				buf.WriteByte('\n')
+			case 't':
+				buf.WriteByte('\t')
+			case 'u':
+				if i+4 <= len(s) {
+					if v, err := strconv.ParseUint(s[i:i+4], 16, 32); err == nil {
+						buf.WriteRune(rune(v))
+						i += 4
+						break
+					}
+				}
+				buf.WriteByte('u')
+			default: // \' \" \\ \@ \?
+				buf.WriteRune(e)
+			}
+		case r == '"':
+			at.quoted = !at.quoted
+		case unicode.IsSpace(r) && !at.quoted:
+			at.space = true
+		default:
+			buf.WriteString(at.flush())
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
+
+// flush returns the pending whitespace, collapsed to a single space.
+// whitespace at the start (and, as it is never flushed, at the end) is
+// dropped.
+func (at *androidText) flush() string {
+	space := at.space && at.started
+	at.space, at.started = false, true
+	if space {
+		return " "
+	}
+	return ""
+}
+
+// androidValue renders content as the value of a string, escaped and
+// quoted as needed
+func androidValue(content xliffContent, cdata bool) string {
+
+	buf := bytes.NewBuffer(nil)
+	androidMarkup(buf, content, cdata)
+	value := buf.String()
+
+	// whitespace which would be collapsed is kept by quotes
+	plain := content.Plain()
+	if strings.HasPrefix(plain, " ") || strings.HasSuffix(plain, " ") || strings.Contains(plain, "  ") {
+		value = `"` + value + `"`
+	}
+	if cdata {
+		value = "<![CDATA[" + strings.Replace(value, "]]>", "]]]]><![CDATA[>", -1) + "]]>"
+	}
+	return value
+}
+
+// androidMarkup writes the text of content escaped, the native code of
+// <bpt>, <ept>, <ph> and <it> as it is. other codes are dropped, <x/>
+// leaving its equiv-text.
+func androidMarkup(buf *bytes.Buffer, content xliffContent, cdata bool) {
+	for _, inline := range content {
+		switch inline.Name {
+		case "":
+			text := androidEscape(inline.Text, buf.Len() == 0)
+			if cdata {
+				buf.WriteString(text)
+			} else {
+				escapeText(buf, text)
+			}
+		case "bpt", "ept", "ph", "it":
+			buf.WriteString(inline.Content.Plain())
+		case "x":
+			androidMarkup(buf, xliffContent{{Text: inline.attr("equiv-text")}}, cdata)
+		default:
+			androidMarkup(buf, inline.Content, cdata)
+		}
+	}
+}
+
+// androidEscape escapes text by backslashes. at the start of a string
+// @ and ? are escaped as well, they would make it a reference.
+func androidEscape(text string, start bool) string {
+	buf := bytes.NewBuffer(nil)
+	for i, r := range text {
+		switch r {
+		case '\\', '\'', '"':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '@', '?':
+			if start && i == 0 {
+				buf.WriteByte('\\')
+			}
+			buf.WriteRune(r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
+
+// writeAndroid writes resources as strings.xml
+func writeAndroid(w io.Writer, resources []*androidResource) error {
+
+	buf := bytes.NewBufferString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	buf.WriteString(`<resources xmlns:xliff="urn:oasis:names:tc:xliff:document:1.2">` + "\n")
+	for _, res := range resources {
+		if res.Comment != "" {
+			buf.WriteString("    <!-- " + strings.Replace(res.Comment, "--", "- -", -1) + " -->\n")
+		}
+		fmt.Fprintf(buf, `    <%s name="%s"`, res.Kind, escapedAttr(res.Name))
+		if !res.Translatable {
+			buf.WriteString(` translatable="false"`)
+		}
+
+		if res.Kind == ANDROID_STRING {
+			buf.WriteString(">" + res.Items[0].value() + "</string>\n")
+			continue
+		}
+		buf.WriteString(">\n")
+		for _, item := range res.Items {
+			buf.WriteString("        <item")
+			if item.Quantity != "" {
+				buf.WriteString(` quantity="` + escapedAttr(item.Quantity) + `"`)
+			}
+			buf.WriteString(">" + item.value() + "</item>\n")
+		}
+		buf.WriteString("    </" + res.Kind + ">\n")
+	}
+	buf.WriteString("</resources>\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// value renders the item, references as they are
+func (item *androidItem) value() string {
+	if item.Reference {
+		return escapedText(item.Content.Plain())
+	}
+	return androidValue(item.Content, item.CDATA)
+}
+
+// androidQualifier returns the qualifier of the values directory for
+// lang: "de" for de, "pt-rBR" for pt-BR and "b+zh+Hans" for zh-Hans
+func androidQualifier(lang string) string {
+	parts := strings.Split(strings.Replace(lang, "_", "-", -1), "-")
+	parts[0] = strings.ToLower(parts[0])
+	switch {
+	case len(parts) == 1:
+		return parts[0]
+	case len(parts) == 2 && (len(parts[1]) == 2 || len(parts[1]) == 3 && isDigits(parts[1])):
+		return parts[0] + "-r" + strings.ToUpper(parts[1])
+	}
+	return "b+" + strings.Join(parts, "+")
+}
+
+// androidLang returns the language of a values directory (values-pt-rBR
+// is pt-BR), "" if there is none
+func androidLang(dir string) string {
+	if !strings.HasPrefix(dir, "values-") {
+		return ""
+	}
+	q := strings.TrimPrefix(dir, "values-")
+	if strings.HasPrefix(q, "b+") {
+		return strings.Replace(q[2:], "+", "-", -1)
+	}
+	parts := strings.Split(q, "-")
+	if len(parts[0]) < 2 || len(parts[0]) > 3 || strings.ToLower(parts[0]) != parts[0] {
+		return ""
+	}
+	if len(parts) > 1 && strings.HasPrefix(parts[1], "r") && len(parts[1]) >= 3 {
+		return parts[0] + "-" + parts[1][1:]
+	}
+	return parts[0]
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
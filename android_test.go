@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAndroidRoundTrip(t *testing.T) {
+
+	fa := &fromAndroid{inFile: "testdata/android/values/strings.xml", targetFile: "testdata/android/values-de/strings.xml", sourceLang: "en"}
+	fa.targetLang = androidLang(filepath.Base(filepath.Dir(fa.targetFile)))
+	out := bytes.NewBuffer(nil)
+	if err := fa.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := xliffFromReader(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.File[0].TargetLang != "de" {
+		t.Errorf("expected target-language de, got %q", doc.File[0].TargetLang)
+	}
+
+	expected := map[string]string{
+		"welcome":    "Welcome to Notes, don't panic!",
+		"quoted":     "  two  spaces  ",
+		"lines":      "First line\nSecond \"line\"",
+		"collapsed":  "some text on lines",
+		"mail":       "@home",
+		"notes[one]": "%d note",
+	}
+	for _, unit := range doc.File[0].Body.Units() {
+		if text, ok := expected[unit.ID]; ok && unit.Source.Text(INLINE_TEXT_ONLY) != text {
+			t.Errorf("%s: expected %q, got %q", unit.ID, text, unit.Source.Text(INLINE_TEXT_ONLY))
+		}
+		if unit.ID == "title" && attrValue(unit.Attrs, "translate") != "no" {
+			t.Error("expected the reference not to be translated")
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "android")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "strings.xlf")
+	if err = ioutil.WriteFile(xlf, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ta := &toAndroid{inFiles: []string{xlf}, outDir: dir, source: true}
+	if err = ta.Convert(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	// the values read back are the same, except for the color and the
+	// incomplete string-array
+	for _, values := range []string{"values", "values-de"} {
+		original, err := androidFromFile(filepath.Join("testdata/android", values, "strings.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		written, err := androidFromFile(filepath.Join(dir, values, "strings.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := map[string]*androidResource{}
+		for _, res := range written {
+			byName[res.Name] = res
+		}
+		for _, res := range original {
+			w := byName[res.Name]
+			if w == nil {
+				if res.Kind != ANDROID_ARRAY || values == "values" {
+					t.Errorf("%s: %s is missing", values, res.Name)
+				}
+				continue
+			}
+			for i := range res.Items {
+				if got, exp := w.Items[i].Content.XML(), res.Items[i].Content.XML(); got != exp {
+					t.Errorf("%s: %s: expected %q, got %q", values, res.Name, exp, got)
+				}
+			}
+		}
+	}
+}
+
+func TestAndroidQualifier(t *testing.T) {
+	for lang, qualifier := range map[string]string{
+		"de":         "de",
+		"pt-BR":      "pt-rBR",
+		"es_419":     "es-r419",
+		"zh-Hans":    "b+zh+Hans",
+		"sr-Latn-RS": "b+sr+Latn+RS",
+	} {
+		if got := androidQualifier(lang); got != qualifier {
+			t.Errorf("%s: expected %q, got %q", lang, qualifier, got)
+		}
+		if back := androidLang("values-" + qualifier); !sameLang(back, lang) {
+			t.Errorf("%s: expected %q back, got %q", qualifier, lang, back)
+		}
+	}
+}
+
+// the quantities of the target language only are left out of the sources,
+// resources of several inputs are written once
+func TestAndroidTargetQuantities(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "android")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for values, items := range map[string]string{
+		"values":    `<item quantity="one">%d note</item><item quantity="other">%d notes</item>`,
+		"values-de": `<item quantity="one">%d Notiz</item><item quantity="other">%d Notizen</item>`,
+		"values-pl": `<item quantity="one">%d notatka</item><item quantity="few">%d notatki</item><item quantity="many">%d notatek</item><item quantity="other">%d notatki</item>`,
+	} {
+		if err = os.Mkdir(filepath.Join(dir, values), 0755); err != nil {
+			t.Fatal(err)
+		}
+		data := `<resources><plurals name="notes">` + items + `</plurals><string-array name="days"><item>Monday</item><item>Tuesday</item></string-array></resources>`
+		if err = ioutil.WriteFile(filepath.Join(dir, values, "strings.xml"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var inFiles []string
+	for _, lang := range []string{"pl", "de"} {
+		fa := &fromAndroid{inFile: filepath.Join(dir, "values", "strings.xml"), targetFile: filepath.Join(dir, "values-"+lang, "strings.xml"), sourceLang: "en", targetLang: lang}
+		out := bytes.NewBuffer(nil)
+		if err = fa.Convert(out); err != nil {
+			t.Fatal(err)
+		}
+		xlf := filepath.Join(dir, lang+".xlf")
+		if err = ioutil.WriteFile(xlf, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		inFiles = append(inFiles, xlf)
+	}
+
+	outDir := filepath.Join(dir, "out")
+	ta := &toAndroid{inFiles: append(inFiles, inFiles[0]), outDir: outDir, source: true}
+	if err = ta.Convert(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	for values, quantities := range map[string]int{"values": 2, "values-de": 2, "values-pl": 4} {
+		written, err := androidFromFile(filepath.Join(outDir, values, "strings.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(written) != 2 || len(written[0].Items) != quantities || len(written[1].Items) != 2 {
+			t.Errorf("%s: expected %d quantities and 2 days, got %v", values, quantities, written)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// an Application Resource Bundle (.arb) of Flutter is a JSON object of
+// messages, each followed by an object "@<key>" describing it:
+//
+//	{
+//	  "@@locale": "en",
+//	  "itemCount": "{count, plural, =0{No items} one{1 item} other{{count} items}}",
+//	  "@itemCount": {
+//	    "description": "Number of items in the cart",
+//	    "placeholders": {
+//	      "count": {
+//	        "type": "int"
+//	      }
+//	    }
+//	  }
+//	}
+//
+// a message becomes a trans-unit with the key as id and the text as it
+// is, ICU plural and select messages included. the description of a
+// message goes into the note, the rest of its metadata (placeholders,
+// type, ...) is kept as JSON in a <context>.
+const (
+	ARB_CONTEXT_GROUP = "arb"
+	ARB_METADATA      = "x-arb-metadata"
+)
+
+// arbMessage is a message of an ARB
+type arbMessage struct {
+	Key         string
+	Value       string
+	Description string
+	Metadata    []jsonMember // the metadata but the description
+}
+
+// jsonMember is a member of a JSON object, kept in the order read
+type jsonMember struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// readJSONMembers reads the members of a JSON object in order
+func readJSONMembers(r io.Reader) ([]jsonMember, error) {
+
+	dec := json.NewDecoder(r)
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	var members []jsonMember
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		m := jsonMember{Key: token.(string)}
+		if err = dec.Decode(&m.Value); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// writeJSONMembers writes members as an object, indented by two spaces
+func writeJSONMembers(buf *bytes.Buffer, members []jsonMember, indent string) error {
+
+	if len(members) == 0 {
+		buf.WriteString("{}")
+		return nil
+	}
+	buf.WriteString("{\n")
+	for i, m := range members {
+		buf.WriteString(indent + "  ")
+		writeJSONString(buf, m.Key)
+		buf.WriteString(": ")
+		if err := json.Indent(buf, m.Value, indent+"  ", "  "); err != nil {
+			return err
+		}
+		if i+1 < len(members) {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(indent + "}")
+	return nil
+}
+
+// jsonString returns s as JSON
+func jsonString(s string) json.RawMessage {
+	buf := bytes.NewBuffer(nil)
+	writeJSONString(buf, s)
+	return buf.Bytes()
+}
+
+func arbFromFile(fileName string) (locale string, messages []arbMessage, err error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	if locale, messages, err = readARB(f); err != nil {
+		return "", nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	return locale, messages, nil
+}
+
+// readARB reads the messages of an ARB in order, and its @@locale
+func readARB(r io.Reader) (locale string, messages []arbMessage, err error) {
+
+	members, err := readJSONMembers(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	metadata := map[string][]jsonMember{}
+	for _, m := range members {
+		switch {
+		case m.Key == "@@locale":
+			if err = json.Unmarshal(m.Value, &locale); err != nil {
+				return "", nil, fmt.Errorf("@@locale: %s", err)
+			}
+		case strings.HasPrefix(m.Key, "@@"):
+		case strings.HasPrefix(m.Key, "@"):
+			if metadata[m.Key[1:]], err = readJSONMembers(bytes.NewReader(m.Value)); err != nil {
+				return "", nil, fmt.Errorf("%s: %s", m.Key, err)
+			}
+		default:
+			msg := arbMessage{Key: m.Key}
+			if err = json.Unmarshal(m.Value, &msg.Value); err != nil {
+				log.Printf("warning: %q is not a message, ignored", m.Key)
+				continue
+			}
+			messages = append(messages, msg)
+		}
+	}
+
+	for i := range messages {
+		msg := &messages[i]
+		for _, m := range metadata[msg.Key] {
+			if m.Key == "description" {
+				json.Unmarshal(m.Value, &msg.Description)
+				continue
+			}
+			msg.Metadata = append(msg.Metadata, m)
+		}
+	}
+	return locale, messages, nil
+}
+
+// writeARB writes messages as ARB for locale
+func writeARB(w io.Writer, locale string, messages []arbMessage) error {
+
+	members := []jsonMember{{"@@locale", jsonString(locale)}}
+	for _, msg := range messages {
+		members = append(members, jsonMember{msg.Key, jsonString(msg.Value)})
+
+		var metadata []jsonMember
+		if msg.Description != "" {
+			metadata = append(metadata, jsonMember{"description", jsonString(msg.Description)})
+		}
+		metadata = append(metadata, msg.Metadata...)
+		if len(metadata) == 0 {
+			continue
+		}
+		buf := bytes.NewBuffer(nil)
+		if err := writeJSONMembers(buf, metadata, ""); err != nil {
+			return err
+		}
+		members = append(members, jsonMember{"@" + msg.Key, buf.Bytes()})
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := writeJSONMembers(buf, members, ""); err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// arbMetadata returns the metadata kept in the context of a unit and
+// arbMetadataJSON the JSON to keep it as
+func arbMetadata(unit *xliffTransUnit) []jsonMember {
+	value := unit.context(ARB_CONTEXT_GROUP, ARB_METADATA)
+	if value == "" {
+		return nil
+	}
+	members, err := readJSONMembers(strings.NewReader(value))
+	if err != nil {
+		log.Printf("warning: %s: metadata: %s", unit.ID, err)
+		return nil
+	}
+	return members
+}
+
+func arbMetadataJSON(metadata []jsonMember) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := writeJSONMembers(buf, metadata, ""); err != nil {
+		return ""
+	}
+	compact := bytes.NewBuffer(nil)
+	json.Compact(compact, buf.Bytes())
+	return compact.String()
+}
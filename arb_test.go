@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestARBRoundTrip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "arb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "app-de.xlf")
+
+	doc := convertXliff(t, &fromARB{inFile: "testdata/arb/app_en.arb", targetFile: "testdata/arb/app_de.arb"}, xlf)
+	file := doc.File[0]
+	if file.SourceLang != "en" || file.TargetLang != "de" {
+		t.Errorf("expected en -> de, got %s -> %s", file.SourceLang, file.TargetLang)
+	}
+	units := file.Body.Units()
+	if len(units) != 5 {
+		t.Fatalf("expected 5 units, got %d", len(units))
+	}
+	if hello := units[1]; hello.Note != "Greeting on the start page" ||
+		hello.context(ARB_CONTEXT_GROUP, ARB_METADATA) != `{"placeholders":{"name":{"type":"String","example":"Jane"}}}` {
+		t.Errorf("hello: unexpected note %q or metadata %q", hello.Note, hello.context(ARB_CONTEXT_GROUP, ARB_METADATA))
+	}
+	if plural := unitTarget(units[2]); plural != "{count, plural, =0{Keine Notizen} one{1 Notiz} other{{count} Notizen}}" {
+		t.Errorf("itemCount: got %q", plural)
+	}
+
+	checkConverted(t, &toARB{inFile: xlf}, "testdata/arb/app_de.arb")
+	checkConverted(t, &toARB{inFile: xlf, source: true}, "testdata/arb/app_en.arb")
+}
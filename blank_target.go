@@ -9,7 +9,6 @@
 package main
 
 import (
-	"encoding/xml"
 	"flag"
 	"io"
 )
@@ -18,7 +17,8 @@ import (
 // is to take a fully translated .xliff and create new templates for other
 // languages.
 type blankTarget struct {
-	inFile string
+	inFile  string
+	version string
 }
 
 func init() {
@@ -32,6 +32,7 @@ func (b *blankTarget) Description() string {
 func (b *blankTarget) ParseArgs(base string, args []string) error {
 	var fs = flag.NewFlagSet(base+" blank-target", flag.ExitOnError)
 	fs.StringVar(&b.inFile, "in", "", "infile")
+	xliffVersionFlag(fs, &b.version)
 	return fs.Parse(args)
 }
 
@@ -46,21 +47,26 @@ func (b *blankTarget) Convert(w io.Writer) error {
 		return err
 	}
 
-	for i := range doc.File {
-		doc.File[i].TargetLang = ""
-		for j := range doc.File[i].Body.TransUnit {
-			doc.File[i].Body.TransUnit[j].Target.Lang = ""
-			doc.File[i].Body.TransUnit[j].Target.Inner = ""
-		}
-	}
+	blankTargets(doc)
 
-	var out []byte
-	if out, err = xml.MarshalIndent(doc, "", "  "); err != nil {
+	if err = doc.SetVersion(b.version); err != nil {
 		return err
 	}
 
-	io.WriteString(w, xml.Header)
-	w.Write(out)
+	return writeXliff(w, doc, "  ")
+}
 
-	return nil
+// blankTargets removes the target language and all translations of doc
+func blankTargets(doc *xliffDoc) {
+	for i := range doc.File {
+		doc.File[i].TargetLang = ""
+		for _, unit := range doc.File[i].Body.Units() {
+			if unit.Target == nil {
+				continue
+			}
+			unit.Target.Lang = ""
+			unit.Target.Inner = ""
+			unit.Target.Content = nil
+		}
+	}
 }
@@ -8,7 +8,10 @@
 
 package main
 
-import "io"
+import (
+	"errors"
+	"io"
+)
 
 // a converter transforms an input file. multiple converters
 // register themself to a registry and are then exposed as
@@ -28,3 +31,18 @@ type converter interface {
 }
 
 var registeredConverters = make(map[string]converter)
+
+// errDiffer is returned by the converters which compare documents if
+// they found differences. xliffer exits with 1 then, without an error
+// message, so that eg. a CI job fails. errors make it exit with 2, as
+// diff(1) does.
+var errDiffer = errors.New("documents differ")
+
+// errIssues is returned by the converters which check documents if they
+// found issues. like errDiffer, it makes xliffer exit with 1 without an
+// error message.
+var errIssues = errors.New("issues found")
+
+// errConflicts is returned by the converters which merge documents if
+// conflicts are left in the output, exiting with 1 as well.
+var errConflicts = errors.New("conflicts left")
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// helpers for the tests of the converters from and to the formats of
+// other tools: convert to a XLIFF, look at its units, convert back and
+// compare with the original byte for byte
+
+// convertXliff runs conv and writes the XLIFF to xlf as well
+func convertXliff(t *testing.T, conv converter, xlf string) *xliffDoc {
+	out := bytes.NewBuffer(nil)
+	if err := conv.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(xlf, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := xliffFromReader(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// describeUnit describes a unit as "source -> target # note"
+func describeUnit(unit *xliffTransUnit) string {
+	return unit.Source.Text(INLINE_PLAIN) + " -> " + unitTarget(unit) + " # " + unit.Note
+}
+
+// checkUnits compares the units of body, described by describe, with the
+// expected ones by id
+func checkUnits(t *testing.T, body *xliffBody, describe func(*xliffTransUnit) string, expected map[string]string) {
+	got := map[string]string{}
+	for _, unit := range body.Units() {
+		got[unit.ID] = describe(unit)
+	}
+	for id, text := range expected {
+		if got[id] != text {
+			t.Errorf("%s: expected %q, got %q", id, text, got[id])
+		}
+	}
+	if len(got) != len(expected) {
+		t.Errorf("expected %d units, got %d", len(expected), len(got))
+	}
+}
+
+// converted returns what conv writes
+func converted(t *testing.T, conv converter) string {
+	written := bytes.NewBuffer(nil)
+	if err := conv.Convert(written); err != nil {
+		t.Fatal(err)
+	}
+	return written.String()
+}
+
+func readFile(t *testing.T, name string) string {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// checkConverted compares what conv writes with the file expected
+func checkConverted(t *testing.T, conv converter, expected string) {
+	checkText(t, expected, readFile(t, expected), converted(t, conv))
+}
+
+// checkFile compares the file written with the file expected
+func checkFile(t *testing.T, written, expected string) {
+	checkText(t, expected, readFile(t, expected), readFile(t, written))
+}
+
+func checkText(t *testing.T, name, expected, got string) {
+	if got != expected {
+		t.Errorf("%s: expected\n%s\ngot\n%s", name, expected, got)
+	}
+}
@@ -9,7 +9,6 @@
 package main
 
 import (
-	"encoding/xml"
 	"flag"
 	"io"
 )
@@ -17,7 +16,8 @@ import (
 // copyUnits copies the source translation units onto
 // the target translation units
 type copyUnits struct {
-	inFile string
+	inFile  string
+	version string
 }
 
 func init() {
@@ -31,6 +31,7 @@ func (c *copyUnits) Description() string {
 func (c *copyUnits) ParseArgs(base string, args []string) error {
 	var fs = flag.NewFlagSet(base+" copy", flag.ExitOnError)
 	fs.StringVar(&c.inFile, "in", "", "infile")
+	xliffVersionFlag(fs, &c.version)
 	return fs.Parse(args)
 }
 
@@ -48,21 +49,24 @@ func (c *copyUnits) Convert(w io.Writer) error {
 	for i := range doc.File {
 
 		doc.File[i].TargetLang = doc.File[i].SourceLang
-		for j := range doc.File[i].Body.TransUnit {
-			name := doc.File[i].Body.TransUnit[j].Target.XMLName
-			doc.File[i].Body.TransUnit[j].Target = new(xliffTarget)
-			doc.File[i].Body.TransUnit[j].Target.Copy(&doc.File[i].Body.TransUnit[j].Source)
-			doc.File[i].Body.TransUnit[j].Target.XMLName = name
+		for _, unit := range doc.File[i].Body.Units() {
+			copySource(unit)
 		}
 	}
 
-	var out []byte
-	if out, err = xml.MarshalIndent(doc, "", "  "); err != nil {
+	if err = doc.SetVersion(c.version); err != nil {
 		return err
 	}
 
-	io.WriteString(w, xml.Header)
-	w.Write(out)
+	return writeXliff(w, doc, "  ")
+}
 
-	return nil
+// copySource replaces the target of unit by a copy of its source
+func copySource(unit *xliffTransUnit) {
+	if unit.Target == nil {
+		unit.Target = new(xliffTarget)
+	}
+	name := unit.Target.XMLName
+	unit.Target.Copy(&unit.Source)
+	unit.Target.XMLName = name
 }
@@ -0,0 +1,296 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// diffConv compares two XLIFF files unit by unit. units are matched by
+// the original of their <file>, the ids of their groups and their id; if
+// both documents hold just one <file>, the files are matched regardless
+// of their original.
+type diffConv struct {
+	aFile  string
+	bFile  string
+	format string
+	inline string
+}
+
+const (
+	DIFF_TEXT    = "text"
+	DIFF_UNIFIED = "unified"
+	DIFF_JSON    = "json"
+)
+
+// diffEntry is a unit which was added, removed or changed
+type diffEntry struct {
+	File    string      `json:"file,omitempty"`
+	Group   string      `json:"group,omitempty"` // ids of the groups, joined by "/"
+	ID      string      `json:"id"`
+	Status  string      `json:"status"` // "added", "removed" or "changed"
+	Changes []diffField `json:"changes"`
+}
+
+// diffField is a part of a unit (source, target, note, state) which
+// differs. for added units A is empty, for removed units B.
+type diffField struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+func init() {
+	registeredConverters["diff"] = new(diffConv)
+}
+
+func (d *diffConv) Description() string {
+	return "Compares two XLIFF files unit by unit"
+}
+
+func (d *diffConv) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" diff", flag.ExitOnError)
+	fs.StringVar(&d.aFile, "a", "", "a file (old)")
+	fs.StringVar(&d.bFile, "b", "", "b file (new)")
+	fs.StringVar(&d.format, "format", DIFF_TEXT, "output format (text, unified, json)")
+	fs.StringVar(&d.inline, "inline", INLINE_PLACEHOLDER, "compare inline elements as plain, placeholder or xml")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch d.format {
+	case DIFF_TEXT, DIFF_UNIFIED, DIFF_JSON:
+	default:
+		return fmt.Errorf("unsupported 'format': %q", d.format)
+	}
+	if !isValidInline(d.inline) {
+		return fmt.Errorf("unsupported 'inline': %q", d.inline)
+	}
+	return nil
+}
+
+func (d *diffConv) Prepare() error {
+	return nil
+}
+
+// Convert writes the differences and returns errDiffer if there are any
+func (d *diffConv) Convert(w io.Writer) error {
+
+	var (
+		err  error
+		aDoc *xliffDoc
+		bDoc *xliffDoc
+	)
+
+	if aDoc, err = xliffFromFile(d.aFile); err != nil {
+		return fmt.Errorf("%s: %s", d.aFile, err)
+	}
+	if bDoc, err = xliffFromFile(d.bFile); err != nil {
+		return fmt.Errorf("%s: %s", d.bFile, err)
+	}
+
+	entries := d.diff(aDoc, bDoc)
+
+	buf := bytes.NewBuffer(nil)
+	switch d.format {
+	case DIFF_JSON:
+		if entries == nil {
+			entries = []diffEntry{}
+		}
+		out, err := json.MarshalIndent(entries, "", "\t")
+		if err != nil {
+			return err
+		}
+		buf.Write(out)
+		buf.WriteString("\n")
+	case DIFF_UNIFIED:
+		d.writeUnified(buf, entries)
+	default:
+		d.writeText(buf, entries)
+	}
+
+	if _, err = w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return errDiffer
+	}
+	return nil
+}
+
+type diffUnit struct {
+	file  string
+	group string
+	unit  *xliffTransUnit
+}
+
+// diffUnits returns the units of doc by file, groups and id, and the keys
+// in the order of the document
+func diffUnits(doc *xliffDoc, matchFiles bool) ([]string, map[string]diffUnit) {
+	var (
+		keys  []string
+		units = map[string]diffUnit{}
+	)
+	for _, file := range doc.File {
+		fileKey := ""
+		if matchFiles {
+			fileKey = file.Original
+		}
+		file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+			group := strings.Join(groupIDs(groups), "/")
+			key := fileKey + "\n" + group + "\n" + unit.ID
+			if _, exists := units[key]; exists {
+				log.Printf("warning: unit %q is there more than once, only the last one is compared", unit.ID)
+			} else {
+				keys = append(keys, key)
+			}
+			units[key] = diffUnit{fileKey, group, unit}
+		})
+	}
+	return keys, units
+}
+
+// diff returns the removed and changed units in the order of a, followed
+// by the added units in the order of b
+func (d *diffConv) diff(a, b *xliffDoc) []diffEntry {
+
+	matchFiles := len(a.File) != 1 || len(b.File) != 1
+	aKeys, aUnits := diffUnits(a, matchFiles)
+	bKeys, bUnits := diffUnits(b, matchFiles)
+
+	var entries []diffEntry
+	for _, key := range aKeys {
+		aUnit := aUnits[key]
+		bUnit, exists := bUnits[key]
+		if !exists {
+			entries = append(entries, d.entry(aUnit, "removed", aUnit.unit, nil))
+			continue
+		}
+		if entry := d.entry(aUnit, "changed", aUnit.unit, bUnit.unit); len(entry.Changes) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	for _, key := range bKeys {
+		if _, exists := aUnits[key]; !exists {
+			bUnit := bUnits[key]
+			entries = append(entries, d.entry(bUnit, "added", nil, bUnit.unit))
+		}
+	}
+	return entries
+}
+
+func (d *diffConv) entry(u diffUnit, status string, a, b *xliffTransUnit) diffEntry {
+	entry := diffEntry{File: u.file, Group: u.group, ID: u.unit.ID, Status: status}
+	aFields, bFields := d.fields(a), d.fields(b)
+	for i := range aFields {
+		if aFields[i].value != bFields[i].value {
+			entry.Changes = append(entry.Changes, diffField{aFields[i].name, aFields[i].value, bFields[i].value})
+		}
+	}
+	return entry
+}
+
+// name returns the id of the unit of entry, behind the ids of its groups
+func (entry *diffEntry) name() string {
+	if entry.Group == "" {
+		return entry.ID
+	}
+	return entry.Group + "/" + entry.ID
+}
+
+type unitField struct {
+	name  string
+	value string
+}
+
+// fields returns the parts of unit which are compared, empty values for
+// a nil unit
+func (d *diffConv) fields(unit *xliffTransUnit) []unitField {
+	fields := []unitField{{"source", ""}, {"target", ""}, {"note", ""}, {"state", ""}}
+	if unit == nil {
+		return fields
+	}
+	fields[0].value = unit.Source.Text(d.inline)
+	if unit.Target != nil {
+		fields[1].value = unit.Target.Text(d.inline)
+		fields[3].value = unit.Target.State
+	}
+	fields[2].value = unit.Note
+	return fields
+}
+
+func (d *diffConv) writeText(buf *bytes.Buffer, entries []diffEntry) {
+
+	count := map[string]int{}
+	for _, entry := range entries {
+		count[entry.Status]++
+		fmt.Fprintf(buf, "%-8s %s", entry.Status, entry.name())
+		if entry.File != "" {
+			fmt.Fprintf(buf, " (%s)", entry.File)
+		}
+		buf.WriteString("\n")
+		for _, change := range entry.Changes {
+			switch entry.Status {
+			case "added":
+				fmt.Fprintf(buf, "    %-7s %q\n", change.Field+":", change.B)
+			case "removed":
+				fmt.Fprintf(buf, "    %-7s %q\n", change.Field+":", change.A)
+			default:
+				fmt.Fprintf(buf, "    %-7s %q -> %q\n", change.Field+":", change.A, change.B)
+			}
+		}
+	}
+
+	if len(entries) == 0 {
+		buf.WriteString("no differences\n")
+		return
+	}
+	fmt.Fprintf(buf, "%d units differ: %d added, %d removed, %d changed\n",
+		len(entries), count["added"], count["removed"], count["changed"])
+}
+
+func (d *diffConv) writeUnified(buf *bytes.Buffer, entries []diffEntry) {
+
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", d.aFile, d.bFile)
+	for _, entry := range entries {
+		header := entry.name()
+		if entry.File != "" {
+			header = entry.File + ": " + header
+		}
+		fmt.Fprintf(buf, "@@ %s (%s) @@\n", header, entry.Status)
+		for _, change := range entry.Changes {
+			writeUnifiedLines(buf, "-", change.Field, change.A)
+			writeUnifiedLines(buf, "+", change.Field, change.B)
+		}
+	}
+}
+
+// writeUnifiedLines writes a field, each line of its value prefixed.
+// empty values are left out.
+func writeUnifiedLines(buf *bytes.Buffer, prefix, field, value string) {
+	if value == "" {
+		return
+	}
+	for i, line := range strings.Split(value, "\n") {
+		if i == 0 {
+			fmt.Fprintf(buf, "%s%s: %s\n", prefix, field, line)
+		} else {
+			fmt.Fprintf(buf, "%s%s  %s\n", prefix, strings.Repeat(" ", len(field)), line)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+
+	d := &diffConv{aFile: "testdata/diff/a.xlf", bFile: "testdata/diff/b.xlf", format: DIFF_UNIFIED, inline: INLINE_PLACEHOLDER}
+	out := bytes.NewBuffer(nil)
+	if err := d.Convert(out); err != errDiffer {
+		t.Fatalf("expected errDiffer, got %v", err)
+	}
+
+	expected := `--- testdata/diff/a.xlf
++++ testdata/diff/b.xlf
+@@ bye (changed) @@
+-target: Tschüss
++target: Auf Wiedersehen
++        und bis bald
+-note: said when leaving
+-state: translated
++state: final
+@@ old (removed) @@
+-source: Old
+@@ new (added) @@
++source: New
++target: Neu
++state: translated
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	// no differences, no error
+	d.bFile = d.aFile
+	out.Reset()
+	if err := d.Convert(out); err != nil || out.Len() != 0 {
+		t.Errorf("expected no differences, got %v:\n%s", err, out)
+	}
+}
+
+// units of the same id in different groups are told apart
+func TestDiffGroups(t *testing.T) {
+
+	doc := func(dialog string) *xliffDoc {
+		doc := newXliffDoc("app", "en")
+		doc.File[0].Body.Group = []xliffGroup{
+			{ID: "menu", TransUnit: []xliffTransUnit{{ID: "close", Source: xliffSource{Inner: "Close"}}}},
+			{ID: "dialog", TransUnit: []xliffTransUnit{{ID: "close", Source: xliffSource{Inner: dialog}}}},
+		}
+		return doc
+	}
+
+	d := &diffConv{inline: INLINE_PLAIN}
+	entries := d.diff(doc("Close"), doc("Close the dialog"))
+	if len(entries) != 1 || entries[0].name() != "dialog/close" || entries[0].Status != "changed" {
+		t.Errorf("expected dialog/close to be changed, got %+v", entries)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"strings"
 )
 
 type dumpXLIFF struct {
@@ -45,14 +46,25 @@ func (d *dumpXLIFF) Convert(w io.Writer) error {
 
 	for _, file := range doc.File {
 
-		for _, unit := range file.Body.TransUnit {
+		file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
 
 			fmt.Fprintf(w, "unit %s\n", unit.ID)
-			fmt.Fprintf(w, " source: %v\n", unit.Source)
-			fmt.Fprintf(w, " target: %v\n", unit.Target)
+			if len(groups) > 0 {
+				fmt.Fprintf(w, " group: %s\n", strings.Join(groupIDs(groups), "/"))
+			}
+			fmt.Fprintf(w, " source: %s\n", dumpSource(&unit.Source))
+			if unit.Target != nil {
+				fmt.Fprintf(w, " target: %s\n", dumpSource((*xliffSource)(unit.Target)))
+			} else {
+				fmt.Fprintf(w, " target: <nil>\n")
+			}
 
-		}
+		})
 	}
 
 	return err
 }
+
+func dumpSource(src *xliffSource) string {
+	return fmt.Sprintf("%q lang=%q space=%q state=%q", src.Inner, src.Lang, src.Space, src.State)
+}
@@ -0,0 +1,195 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+)
+
+// fromAndroid converts the strings.xml of an Android app to XLIFF, the
+// translations taken from the strings.xml of a values-<lang> directory.
+// see android.go for how resources are represented.
+type fromAndroid struct {
+	inFile     string
+	targetFile string
+	sourceLang string
+	targetLang string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-android"] = new(fromAndroid)
+}
+
+func (fa *fromAndroid) Description() string {
+	return "Converts Android strings.xml to XLIFF"
+}
+
+func (fa *fromAndroid) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-android", flag.ExitOnError)
+	fs.StringVar(&fa.inFile, "in", "", "infile, holding the source texts (values/strings.xml)")
+	fs.StringVar(&fa.targetFile, "target", "", "file holding the translations (values-<lang>/strings.xml, optional)")
+	fs.StringVar(&fa.sourceLang, "source-lang", "en", "source language")
+	fs.StringVar(&fa.targetLang, "target-lang", "", "target language (default: as in the directory of -target)")
+	xliffVersionFlag(fs, &fa.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fa.targetLang == "" && fa.targetFile != "" {
+		fa.targetLang = androidLang(filepath.Base(filepath.Dir(fa.targetFile)))
+	}
+	return nil
+}
+
+func (fa *fromAndroid) Prepare() error {
+	return nil
+}
+
+func (fa *fromAndroid) Convert(w io.Writer) error {
+
+	resources, err := androidFromFile(fa.inFile)
+	if err != nil {
+		return err
+	}
+	targets := map[string]*androidResource{}
+	if fa.targetFile != "" {
+		translated, err := androidFromFile(fa.targetFile)
+		if err != nil {
+			return fmt.Errorf("%s: %s", fa.targetFile, err)
+		}
+		for _, res := range translated {
+			targets[res.Name] = res
+		}
+	}
+
+	doc := newXliffDoc(fa.inFile, fa.sourceLang)
+	file := &doc.File[0]
+	file.DataType = "x-android-res"
+	file.TargetLang = fa.targetLang
+
+	names := map[string]bool{}
+	for _, res := range resources {
+		if names[res.Name] {
+			log.Printf("warning: double entry for key %q", res.Name)
+		}
+		names[res.Name] = true
+		target := targets[res.Name]
+
+		if res.Kind == ANDROID_STRING {
+			var translation *androidItem
+			if target != nil && len(target.Items) > 0 {
+				translation = &target.Items[0]
+			}
+			unit := fa.unit(res.Name, &res.Items[0], translation)
+			unit.Note = res.Comment
+			if !res.Translatable || res.Items[0].Reference {
+				setAttr(&unit.Attrs, "translate", "no")
+			}
+			file.Body.TransUnit = append(file.Body.TransUnit, unit)
+			continue
+		}
+
+		group := xliffGroup{ID: res.Name}
+		if res.Comment != "" {
+			group.Note = []string{res.Comment}
+		}
+		restype := ANDROID_ARRAY_RESTYPE
+		if res.Kind == ANDROID_PLURALS {
+			restype = ANDROID_PLURALS_RESTYPE
+		}
+		setAttr(&group.Attrs, "restype", restype)
+		if !res.Translatable {
+			setAttr(&group.Attrs, "translate", "no")
+		}
+
+		if res.Kind != ANDROID_PLURALS {
+			for i := range res.Items {
+				unit := fa.unit(fa.itemID(res, i, ""), &res.Items[i], fa.translation(res, target, i, ""))
+				group.TransUnit = append(group.TransUnit, unit)
+			}
+			file.Body.Group = append(file.Body.Group, group)
+			continue
+		}
+
+		var quantities, targetQuantities []string
+		for _, item := range res.Items {
+			quantities = append(quantities, item.Quantity)
+		}
+		if target != nil {
+			for _, item := range target.Items {
+				targetQuantities = append(targetQuantities, item.Quantity)
+			}
+		}
+		for _, form := range pluralForms(quantities, targetQuantities) {
+			source := fa.translation(res, res, 0, form.Source)
+			unit := fa.unit(fa.itemID(res, 0, form.Quantity), source, fa.translation(res, target, 0, form.Quantity))
+			if form.Quantity != "" {
+				setAttr(&unit.Attrs, "resname", form.Quantity)
+			}
+			form.mark(&unit)
+			group.TransUnit = append(group.TransUnit, unit)
+		}
+		file.Body.Group = append(file.Body.Group, group)
+	}
+
+	if err = doc.SetVersion(fa.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
+
+// itemID returns the id of the i-th item of res: name[quantity] for
+// plurals, name[i] for string arrays
+func (fa *fromAndroid) itemID(res *androidResource, i int, quantity string) string {
+	if res.Kind == ANDROID_PLURALS {
+		return fmt.Sprintf("%s[%s]", res.Name, quantity)
+	}
+	return fmt.Sprintf("%s[%d]", res.Name, i)
+}
+
+// translation returns the item of target matching the i-th item of res,
+// by quantity for plurals
+func (fa *fromAndroid) translation(res, target *androidResource, i int, quantity string) *androidItem {
+	if target == nil {
+		return nil
+	}
+	if res.Kind != ANDROID_PLURALS {
+		if i < len(target.Items) {
+			return &target.Items[i]
+		}
+		return nil
+	}
+	for j := range target.Items {
+		if target.Items[j].Quantity == quantity {
+			return &target.Items[j]
+		}
+	}
+	return nil
+}
+
+// unit creates the trans-unit of an item. the target is left out if
+// there is no translation.
+func (fa *fromAndroid) unit(id string, item, translation *androidItem) xliffTransUnit {
+
+	unit := xliffTransUnit{ID: id, Source: xliffSource{Content: item.Content, Inner: item.Content.Plain()}}
+	if item.CDATA {
+		setAttr(&unit.Attrs, "restype", ANDROID_CDATA_RESTYPE)
+	}
+	if translation == nil || translation.Content.Plain() == "" {
+		return unit
+	}
+	content := translation.Content
+	unit.Target = &xliffTarget{Content: content, Inner: content.Plain(), State: "translated"}
+	return unit
+}
@@ -0,0 +1,118 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+)
+
+// fromARB converts the template ARB of a Flutter app to XLIFF, the
+// translations taken from the ARB of the target language. see arb.go for
+// how messages are represented.
+type fromARB struct {
+	inFile     string
+	targetFile string
+	sourceLang string
+	targetLang string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-arb"] = new(fromARB)
+}
+
+func (fa *fromARB) Description() string {
+	return "Converts Flutter ARB to XLIFF"
+}
+
+func (fa *fromARB) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-arb", flag.ExitOnError)
+	fs.StringVar(&fa.inFile, "in", "", "infile, holding the source texts (app_en.arb)")
+	fs.StringVar(&fa.targetFile, "target", "", "file holding the translations (app_<lang>.arb, optional)")
+	fs.StringVar(&fa.sourceLang, "source-lang", "", "source language (default: @@locale of -in, or \"en\")")
+	fs.StringVar(&fa.targetLang, "target-lang", "", "target language (default: @@locale of -target)")
+	xliffVersionFlag(fs, &fa.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fa.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (fa *fromARB) Prepare() error {
+	return nil
+}
+
+func (fa *fromARB) Convert(w io.Writer) error {
+
+	locale, messages, err := arbFromFile(fa.inFile)
+	if err != nil {
+		return err
+	}
+	if fa.sourceLang == "" {
+		fa.sourceLang = localeLang(locale)
+	}
+	if fa.sourceLang == "" {
+		fa.sourceLang = "en"
+	}
+
+	translations := map[string]string{}
+	if fa.targetFile != "" {
+		locale, translated, err := arbFromFile(fa.targetFile)
+		if err != nil {
+			return err
+		}
+		if fa.targetLang == "" {
+			fa.targetLang = localeLang(locale)
+		}
+		for _, msg := range translated {
+			translations[msg.Key] = msg.Value
+		}
+	}
+
+	doc := newXliffDoc(fa.inFile, fa.sourceLang)
+	file := &doc.File[0]
+	file.DataType = "plaintext"
+	file.TargetLang = fa.targetLang
+
+	keys := map[string]bool{}
+	for _, msg := range messages {
+		if keys[msg.Key] {
+			log.Printf("warning: double entry for key %q", msg.Key)
+		}
+		keys[msg.Key] = true
+
+		unit := xliffTransUnit{ID: msg.Key, Source: xliffSource{Inner: msg.Value, Space: "preserve"}}
+		if translation := translations[msg.Key]; translation != "" {
+			unit.Target = &xliffTarget{Inner: translation, Space: "preserve", State: "translated"}
+		}
+		unit.Note = msg.Description
+		if metadata := arbMetadataJSON(msg.Metadata); metadata != "" {
+			unit.addContext(ARB_CONTEXT_GROUP, "information", ARB_METADATA, metadata)
+		}
+		file.Body.TransUnit = append(file.Body.TransUnit, unit)
+	}
+
+	for key := range translations {
+		if !keys[key] {
+			log.Printf("warning: key %q of %s is not part of %s, ignored", key, fa.targetFile, fa.inFile)
+		}
+	}
+
+	if err = doc.SetVersion(fa.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
@@ -0,0 +1,136 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+)
+
+// fromGoI18n converts a go-i18n message file to XLIFF, the translations
+// taken from the message file of the target language. see goi18n.go for
+// how messages are represented.
+type fromGoI18n struct {
+	inFile     string
+	targetFile string
+	sourceLang string
+	targetLang string
+	format     string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-go-i18n"] = new(fromGoI18n)
+}
+
+func (fg *fromGoI18n) Description() string {
+	return "Converts go-i18n message files (TOML, JSON, YAML) to XLIFF"
+}
+
+func (fg *fromGoI18n) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-go-i18n", flag.ExitOnError)
+	fs.StringVar(&fg.inFile, "in", "", "infile, holding the source texts (active.en.toml)")
+	fs.StringVar(&fg.targetFile, "target", "", "file holding the translations (active.<lang>.toml or translate.<lang>.toml, optional)")
+	fs.StringVar(&fg.sourceLang, "source-lang", "", "source language (default: as in the name of -in, or \"en\")")
+	fs.StringVar(&fg.targetLang, "target-lang", "", "target language (default: as in the name of -target)")
+	fs.StringVar(&fg.format, "format", "", "format of the files: toml, json or yaml (default: by the extension)")
+	xliffVersionFlag(fs, &fg.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fg.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	if fg.format != "" && !isValidGoI18nFormat(fg.format) {
+		return fmt.Errorf("unsupported 'format': %q", fg.format)
+	}
+	return nil
+}
+
+func (fg *fromGoI18n) Prepare() error {
+	return nil
+}
+
+func (fg *fromGoI18n) Convert(w io.Writer) error {
+
+	messages, err := goI18nFromFile(fg.inFile, fg.format)
+	if err != nil {
+		return err
+	}
+	if fg.sourceLang == "" {
+		if fg.sourceLang, _ = goI18nPath(fg.inFile); fg.sourceLang == "" {
+			fg.sourceLang = "en"
+		}
+	}
+
+	translations := map[string]goI18nMessage{}
+	if fg.targetFile != "" {
+		translated, err := goI18nFromFile(fg.targetFile, fg.format)
+		if err != nil {
+			return err
+		}
+		if fg.targetLang == "" {
+			fg.targetLang, _ = goI18nPath(fg.targetFile)
+		}
+		for _, msg := range translated {
+			translations[msg.ID] = msg
+		}
+	}
+
+	doc := newXliffDoc(fg.inFile, fg.sourceLang)
+	file := &doc.File[0]
+	file.DataType = "x-go-i18n"
+	file.TargetLang = fg.targetLang
+
+	for _, msg := range messages {
+		tmsg, translated := translations[msg.ID]
+		delete(translations, msg.ID)
+
+		meta := msg
+		if translated && tmsg.Hash != "" {
+			meta.Hash = tmsg.Hash
+		}
+		unit := func(id, form, source string) xliffTransUnit {
+			unit := xliffTransUnit{ID: id, Source: xliffSource{Inner: source, Space: "preserve"}}
+			// translate.<lang>.toml holds the source texts of the
+			// messages still to be translated
+			if translation := tmsg.Forms[form]; translation != "" && !(tmsg.Hash != "" && translation == source) {
+				unit.Target = &xliffTarget{Inner: translation, Space: "preserve", State: "translated"}
+			}
+			meta.metaTo(&unit)
+			return unit
+		}
+
+		if !msg.isPlural() && !tmsg.isPlural() {
+			file.Body.TransUnit = append(file.Body.TransUnit, unit(msg.ID, "other", msg.Forms["other"]))
+			continue
+		}
+
+		group := xliffGroup{ID: msg.ID}
+		setAttr(&group.Attrs, "restype", GO_I18N_PLURAL_RESTYPE)
+		for _, form := range pluralForms(pluralQuantitiesOf(msg.Forms), pluralQuantitiesOf(tmsg.Forms)) {
+			unit := unit(msg.ID+"."+form.Quantity, form.Quantity, msg.Forms[form.Source])
+			form.mark(&unit)
+			group.TransUnit = append(group.TransUnit, unit)
+		}
+		file.Body.Group = append(file.Body.Group, group)
+	}
+
+	for id := range translations {
+		log.Printf("warning: message %q of %s is not part of %s, ignored", id, fg.targetFile, fg.inFile)
+	}
+
+	if err = doc.SetVersion(fg.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
@@ -0,0 +1,156 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// fromGotext converts the messages of gotext to XLIFF. see gotext.go for
+// how messages are represented.
+type fromGotext struct {
+	inFile     string
+	sourceLang string
+	targetLang string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-gotext"] = new(fromGotext)
+}
+
+func (fg *fromGotext) Description() string {
+	return "Converts gotext messages (messages.gotext.json) to XLIFF"
+}
+
+func (fg *fromGotext) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-gotext", flag.ExitOnError)
+	fs.StringVar(&fg.inFile, "in", "", "infile (out.gotext.json or messages.gotext.json)")
+	fs.StringVar(&fg.sourceLang, "source-lang", "en", "source language")
+	fs.StringVar(&fg.targetLang, "target-lang", "", "target language (default: \"language\" of -in)")
+	xliffVersionFlag(fs, &fg.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fg.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (fg *fromGotext) Prepare() error {
+	return nil
+}
+
+func (fg *fromGotext) Convert(w io.Writer) error {
+
+	messages, err := gotextFromFile(fg.inFile)
+	if err != nil {
+		return err
+	}
+	if fg.targetLang == "" {
+		fg.targetLang = messages.Language
+	}
+
+	doc := newXliffDoc(fg.inFile, fg.sourceLang)
+	file := &doc.File[0]
+	file.DataType = "x-gotext"
+	file.TargetLang = fg.targetLang
+
+	state := func(msg *gotextMessage) string {
+		if msg.Fuzzy {
+			return STATE_NEEDS_REVIEW
+		}
+		return "translated"
+	}
+
+	for i := range messages.Messages {
+		msg := &messages.Messages[i]
+		ids := msg.ids()
+		if len(ids) == 0 {
+			log.Printf("warning: message %d has no id, ignored", i+1)
+			continue
+		}
+		source, err := readGotextText(msg.Message)
+		if err != nil {
+			log.Printf("warning: %s: message: %s, ignored", ids[0], err)
+			continue
+		}
+		translation, err := readGotextText(msg.Translation)
+		if err != nil {
+			log.Printf("warning: %s: translation: %s, ignored", ids[0], err)
+			continue
+		}
+
+		if !source.isSelect() && !translation.isSelect() {
+			unit := xliffTransUnit{ID: ids[0], Source: xliffSource{Inner: source.Msg, Space: "preserve"}}
+			if translation.Msg != "" {
+				unit.Target = &xliffTarget{Inner: translation.Msg, Space: "preserve", State: state(msg)}
+			}
+			msg.metaTo(&unit)
+			file.Body.TransUnit = append(file.Body.TransUnit, unit)
+			continue
+		}
+
+		sel := source
+		if !sel.isSelect() {
+			sel = translation
+		}
+		var keys []string
+		seen := map[string]bool{}
+		inSource := map[string]bool{"other": !source.isSelect()}
+		for _, c := range source.Cases {
+			inSource[c.Key] = true
+		}
+		for _, c := range append(source.Cases[:len(source.Cases):len(source.Cases)], translation.Cases...) {
+			if !seen[c.Key] {
+				seen[c.Key] = true
+				keys = append(keys, c.Key)
+			}
+		}
+
+		group := xliffGroup{ID: ids[0]}
+		setAttr(&group.Attrs, "restype", GOTEXT_SELECT_RESTYPE)
+		for j, key := range keys {
+			unit := xliffTransUnit{ID: ids[0] + "[" + key + "]", Source: xliffSource{Inner: source.caseMsg(key), Space: "preserve"}}
+			if text := translation.caseMsg(key); text != "" && (translation.isSelect() || key == "other") {
+				unit.Target = &xliffTarget{Inner: text, Space: "preserve", State: state(msg)}
+			}
+			if !inSource[key] {
+				pluralForm{key, "other"}.mark(&unit)
+			}
+			if j == 0 {
+				msg.metaTo(&unit)
+				if sel.Feature != "" {
+					unit.addContext(GOTEXT_CONTEXT_GROUP, "information", GOTEXT_FEATURE, sel.Feature)
+				}
+				if sel.Arg != "" {
+					unit.addContext(GOTEXT_CONTEXT_GROUP, "information", GOTEXT_ARG, sel.Arg)
+				}
+			}
+			group.TransUnit = append(group.TransUnit, unit)
+		}
+		file.Body.Group = append(file.Body.Group, group)
+	}
+
+	if err = doc.SetVersion(fg.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
+
+// gotextCaseKey returns the case of the unit of a select
+func gotextCaseKey(group *xliffGroup, unit *xliffTransUnit) string {
+	return strings.TrimSuffix(strings.TrimPrefix(unit.ID, group.ID+"["), "]")
+}
@@ -0,0 +1,228 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fromIOSStrings converts .strings and .stringsdict files to XLIFF, a
+// <file> per input. the translations are read from the files of the same
+// name in the <target-lang>.lproj next to the .lproj of the input. see
+// ios.go for how the entries are represented.
+type fromIOSStrings struct {
+	inFiles    []string
+	sourceLang string
+	targetLang string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-ios-strings"] = new(fromIOSStrings)
+}
+
+func (fi *fromIOSStrings) Description() string {
+	return "Converts iOS .strings and .stringsdict to XLIFF"
+}
+
+func (fi *fromIOSStrings) ParseArgs(base string, args []string) error {
+	var inFile string
+	var fs = flag.NewFlagSet(base+" from-ios-strings", flag.ExitOnError)
+	fs.StringVar(&inFile, "in", "", "infile, holding the source texts (more files can follow as arguments)")
+	fs.StringVar(&fi.sourceLang, "source-lang", "", "source language (default: as in the .lproj of -in, or \"en\")")
+	fs.StringVar(&fi.targetLang, "target-lang", "", "target language, whose .lproj holds the translations (optional)")
+	xliffVersionFlag(fs, &fi.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if inFile != "" {
+		fi.inFiles = append(fi.inFiles, inFile)
+	}
+	fi.inFiles = append(fi.inFiles, fs.Args()...)
+	if len(fi.inFiles) == 0 {
+		return fmt.Errorf("missing -in")
+	}
+	if fi.sourceLang == "" {
+		fi.sourceLang = strings.TrimSuffix(filepath.Base(filepath.Dir(fi.inFiles[0])), ".lproj")
+		if !strings.HasSuffix(filepath.Dir(fi.inFiles[0]), ".lproj") || fi.sourceLang == "Base" {
+			fi.sourceLang = "en"
+		}
+	}
+	return nil
+}
+
+func (fi *fromIOSStrings) Prepare() error {
+	return nil
+}
+
+func (fi *fromIOSStrings) Convert(w io.Writer) error {
+
+	doc := newXliffDoc("", fi.sourceLang)
+	doc.File = nil
+
+	for _, name := range fi.inFiles {
+		targetName := ""
+		if fi.targetLang != "" {
+			targetName = filepath.Join(filepath.Dir(filepath.Dir(name)), iosLproj(fi.targetLang), filepath.Base(name))
+		}
+
+		file := xliffFile{
+			Original:   filepath.Join(filepath.Base(filepath.Dir(name)), filepath.Base(name)),
+			SourceLang: fi.sourceLang,
+			TargetLang: fi.targetLang,
+			DataType:   "plaintext",
+		}
+		var err error
+		if isStringsdict(name) {
+			err = fi.addStringsdict(&file, name, targetName)
+		} else {
+			err = fi.addStrings(&file, name, targetName)
+		}
+		if err != nil {
+			return err
+		}
+		doc.File = append(doc.File, file)
+	}
+
+	if err := doc.SetVersion(fi.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
+
+// readOptional reads fileName, which may be missing
+func readOptional(fileName string) ([]byte, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		log.Printf("warning: %s is missing", fileName)
+		return nil, nil
+	}
+	return data, err
+}
+
+// addStrings adds the entries of a .strings to file
+func (fi *fromIOSStrings) addStrings(file *xliffFile, name, targetName string) error {
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	entries, err := readIOSStrings(data)
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+
+	translations := map[string]string{}
+	if data, err = readOptional(targetName); err != nil {
+		return err
+	}
+	if data != nil {
+		translated, err := readIOSStrings(data)
+		if err != nil {
+			return fmt.Errorf("%s: %s", targetName, err)
+		}
+		for _, e := range translated {
+			translations[e.Key] = e.Value
+		}
+	}
+
+	keys := map[string]bool{}
+	for _, e := range entries {
+		if keys[e.Key] {
+			log.Printf("warning: double entry for key %q", e.Key)
+		}
+		keys[e.Key] = true
+
+		unit := fi.unit(e.Key, e.Value, translations[e.Key])
+		unit.Note = e.Comment
+		file.Body.TransUnit = append(file.Body.TransUnit, unit)
+	}
+	return nil
+}
+
+// addStringsdict adds the entries of a .stringsdict to file
+func (fi *fromIOSStrings) addStringsdict(file *xliffFile, name, targetName string) error {
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	plurals, err := readStringsdict(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+
+	translations := map[string]*iosPlural{}
+	if data, err = readOptional(targetName); err != nil {
+		return err
+	}
+	if data != nil {
+		translated, err := readStringsdict(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("%s: %s", targetName, err)
+		}
+		for i := range translated {
+			translations[translated[i].Key] = &translated[i]
+		}
+	}
+
+	for _, p := range plurals {
+		target := translations[p.Key]
+		if target == nil {
+			target = &iosPlural{}
+		}
+
+		group := xliffGroup{ID: p.Key}
+		setAttr(&group.Attrs, "restype", IOS_STRINGSDICT_RESTYPE)
+		unit := fi.unit(p.Key+"[format]", p.Format, target.Format)
+		setAttr(&unit.Attrs, "resname", IOS_FORMAT_KEY)
+		group.TransUnit = append(group.TransUnit, unit)
+
+		for _, v := range p.Vars {
+			forms := map[string]string{}
+			for _, tv := range target.Vars {
+				if tv.Name == v.Name {
+					forms = tv.Forms
+				}
+			}
+
+			for _, form := range pluralForms(pluralQuantitiesOf(v.Forms), pluralQuantitiesOf(forms)) {
+				resname := v.Name + ":" + form.Quantity
+				unit := fi.unit(p.Key+"["+resname+"]", v.Forms[form.Source], forms[form.Quantity])
+				setAttr(&unit.Attrs, "resname", resname)
+				unit.addContext(IOS_CONTEXT_GROUP, "information", IOS_VALUE_TYPE, v.ValueType)
+				form.mark(&unit)
+				group.TransUnit = append(group.TransUnit, unit)
+			}
+		}
+		file.Body.Group = append(file.Body.Group, group)
+	}
+	return nil
+}
+
+// unit creates a trans-unit, without target if there is no translation
+func (fi *fromIOSStrings) unit(id, source, translation string) xliffTransUnit {
+	unit := xliffTransUnit{ID: id, Source: xliffSource{Inner: source, Space: "preserve"}}
+	if translation != "" {
+		unit.Target = &xliffTarget{Inner: translation, Space: "preserve", State: "translated"}
+	}
+	return unit
+}
@@ -0,0 +1,344 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// fromJSON is the reverse of to-json: it builds a XLIFF from a key:value
+// JSON holding the source texts and, optionally, a second one holding the
+// translations. nested objects are flattened, their keys joined by
+// -group-sep (or turned into <group>s with -group-prefix).
+//
+// with -merge the entries are merged into an existing XLIFF: units which
+// did not change are kept as they are (notes, states, ...), changed texts
+// are updated and new keys are appended.
+type fromJSON struct {
+	inFile      string
+	targetFile  string
+	mergeFile   string
+	sourceLang  string
+	targetLang  string
+	keyMatch    string
+	keyTo       string
+	groupPrefix bool
+	groupSep    string
+	inline      string
+	version     string
+}
+
+// jsonEntry is a string of a JSON, path holds the keys leading to it
+type jsonEntry struct {
+	path  []string
+	value string
+}
+
+func init() {
+	registeredConverters["from-json"] = new(fromJSON)
+}
+
+func (fj *fromJSON) Description() string {
+	return "Converts JSON (key,value) to XLIFF"
+}
+
+func (fj *fromJSON) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-json", flag.ExitOnError)
+	fs.StringVar(&fj.inFile, "in", "", "infile, holding the source texts")
+	fs.StringVar(&fj.targetFile, "target", "", "file holding the translations (optional)")
+	fs.StringVar(&fj.mergeFile, "merge", "", "XLIFF to merge the entries into (optional)")
+	fs.StringVar(&fj.sourceLang, "source-lang", "", "source language (default: \"en\" or as in -merge)")
+	fs.StringVar(&fj.targetLang, "target-lang", "", "target language (default: as in -merge)")
+	fs.StringVar(&fj.keyMatch, "key-match", "", "the -key-match given to to-json (regexp)")
+	fs.StringVar(&fj.keyTo, "key-to", "", "the -key-to given to to-json (string)")
+	fs.BoolVar(&fj.groupPrefix, "group-prefix", false, "turn nested objects into <group>s")
+	fs.StringVar(&fj.groupSep, "group-sep", ".", "separator between the keys of nested objects")
+	fs.StringVar(&fj.inline, "inline", INLINE_PLAIN, "inline elements in the JSON are plain, placeholder or xml")
+	xliffVersionFlag(fs, &fj.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !isValidInline(fj.inline) {
+		return fmt.Errorf("unsupported 'inline': %q", fj.inline)
+	}
+	return nil
+}
+
+func (fj *fromJSON) Prepare() error {
+	return nil
+}
+
+func (fj *fromJSON) Convert(w io.Writer) error {
+
+	sources, err := jsonEntriesFromFile(fj.inFile)
+	if err != nil {
+		return err
+	}
+
+	targets := map[string]string{}
+	if fj.targetFile != "" {
+		entries, err := jsonEntriesFromFile(fj.targetFile)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			targets[strings.Join(entry.path, fj.groupSep)] = entry.value
+		}
+	}
+
+	var doc *xliffDoc
+	if fj.mergeFile != "" {
+		if doc, err = xliffFromFile(fj.mergeFile); err != nil {
+			return err
+		}
+	} else {
+		doc = newXliffDoc(fj.inFile, "en")
+	}
+	file := &doc.File[0]
+	if fj.sourceLang != "" {
+		file.SourceLang = fj.sourceLang
+	}
+	if fj.targetLang != "" {
+		file.TargetLang = fj.targetLang
+	}
+
+	keyTrans, keyBack, err := fj.keyTransforms()
+	if err != nil {
+		return err
+	}
+
+	// the units which exist already, by the key to-json would give them
+	existing := map[string]*xliffTransUnit{}
+	ids := map[string]string{} // the keys by the ids they belong to
+	taken := ""                // an id holding -key-to already
+	for i := range doc.File {
+		doc.File[i].Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+			id := unit.ID
+			if fj.groupPrefix {
+				id = strings.Join(append(groupIDs(groups), id), fj.groupSep)
+			}
+			existing[keyTrans(id)] = unit
+			ids[id] = keyTrans(id)
+			if fj.keyMatch != "" && fj.keyTo != "" && strings.Contains(id, fj.keyTo) {
+				taken = id
+			}
+		})
+	}
+
+	// -key-to might stem from the ids as well as from to-json
+	if keyBack != nil && taken != "" {
+		log.Printf("warning: id %q holds the -key-to %q already, keys of new units are kept", taken, fj.keyTo)
+		keyBack = nil
+	}
+	if keyBack == nil {
+		keyBack = func(in string) string { return in }
+	}
+
+	var added []jsonEntry
+	seen := map[string]bool{}
+	for _, entry := range sources {
+		key := strings.Join(entry.path, fj.groupSep)
+		target, hasTarget := targets[key]
+		seen[key] = true
+
+		unit := existing[key]
+		if unit == nil {
+			added = append(added, entry)
+			continue
+		}
+		if unit.Source.Text(fj.inline) != entry.value {
+			fj.setText(&unit.Source, entry.value)
+		}
+		if !hasTarget {
+			continue
+		}
+		if unit.Target == nil {
+			unit.Target = &xliffTarget{Lang: file.TargetLang}
+		}
+		if unit.Target.Text(fj.inline) != target {
+			fj.setText((*xliffSource)(unit.Target), target)
+		}
+	}
+
+	for key := range targets {
+		if seen[key] {
+			continue
+		}
+		log.Printf("warning: key %q of %s is not part of %s, ignored", key, fj.targetFile, fj.inFile)
+	}
+
+	// new units are appended only after the existing ones were updated:
+	// appending might move the units pointed to by existing
+	for _, entry := range added {
+		key := strings.Join(entry.path, fj.groupSep)
+		if other, ok := ids[keyBack(key)]; ok {
+			log.Printf("warning: keys %q and %q both become the id %q", other, key, keyBack(key))
+		}
+		ids[keyBack(key)] = key
+		unit := xliffTransUnit{
+			ID:     keyBack(key),
+			Source: xliffSource{Lang: file.SourceLang},
+		}
+		fj.setText(&unit.Source, entry.value)
+		if target, ok := targets[key]; ok {
+			unit.Target = &xliffTarget{Lang: file.TargetLang}
+			fj.setText((*xliffSource)(unit.Target), target)
+		}
+
+		if !fj.groupPrefix || len(entry.path) == 1 {
+			file.Body.TransUnit = append(file.Body.TransUnit, unit)
+			continue
+		}
+
+		// the last key is the id of the unit, the others are the ids
+		// of the enclosing groups
+		unit.ID = keyBack(entry.path[len(entry.path)-1])
+		units, groups := &file.Body.TransUnit, &file.Body.Group
+		for _, id := range entry.path[:len(entry.path)-1] {
+			group := findGroup(*groups, keyBack(id))
+			if group == nil {
+				*groups = append(*groups, xliffGroup{ID: keyBack(id)})
+				group = &(*groups)[len(*groups)-1]
+			}
+			units, groups = &group.TransUnit, &group.Group
+		}
+		*units = append(*units, unit)
+	}
+
+	if err = doc.SetVersion(fj.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
+
+// setText sets the text of a source or target to a value of the JSON,
+// its inline elements parsed with -inline xml
+func (fj *fromJSON) setText(src *xliffSource, value string) {
+	if fj.inline == INLINE_XML {
+		setContent(src, value)
+		return
+	}
+	src.Inner, src.Content = value, nil
+}
+
+// keyTransforms returns the transformation to-json applies to the ids
+// (keyTrans) and its reverse (keyBack). the reverse is only possible if
+// both -key-match and -key-to are plain strings, otherwise keyBack is
+// nil.
+func (fj *fromJSON) keyTransforms() (keyTrans, keyBack func(string) string, err error) {
+
+	keyTrans = func(in string) string { return in }
+	if fj.keyMatch == "" {
+		return keyTrans, keyTrans, nil
+	}
+
+	rx, err := regexp.CompilePOSIX(fj.keyMatch)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyTrans = func(in string) string {
+		return rx.ReplaceAllString(in, fj.keyTo)
+	}
+
+	match, literal := rx.LiteralPrefix()
+	if literal && fj.keyTo != "" && !strings.Contains(fj.keyTo, "$") {
+		keyBack = func(in string) string {
+			return strings.Replace(in, fj.keyTo, match, -1)
+		}
+	} else {
+		log.Printf("warning: -key-match %q can not be reversed, keys of new units are kept", fj.keyMatch)
+	}
+
+	return keyTrans, keyBack, nil
+}
+
+func findGroup(groups []xliffGroup, id string) *xliffGroup {
+	for i := range groups {
+		if groups[i].ID == id {
+			return &groups[i]
+		}
+	}
+	return nil
+}
+
+func jsonEntriesFromFile(fileName string) ([]jsonEntry, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readJSONEntries(f)
+}
+
+// readJSONEntries reads all strings of a JSON object in the order they
+// appear. numbers and booleans are taken as they are written, nulls are
+// skipped.
+func readJSONEntries(r io.Reader) ([]jsonEntry, error) {
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	var entries []jsonEntry
+	err = readJSONObject(dec, nil, '}', &entries)
+	return entries, err
+}
+
+// readJSONObject reads the members of an object (or the elements of an
+// array, keyed by their index) up to end
+func readJSONObject(dec *json.Decoder, path []string, end json.Delim, entries *[]jsonEntry) error {
+
+	for i := 0; ; i++ {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if token == end {
+			return nil
+		}
+
+		key := fmt.Sprint(i)
+		if end == '}' {
+			key = token.(string)
+			if token, err = dec.Token(); err != nil {
+				return err
+			}
+		}
+		keyPath := append(path[:len(path):len(path)], key)
+
+		switch t := token.(type) {
+		case json.Delim:
+			closing := json.Delim('}')
+			if t == '[' {
+				closing = ']'
+			}
+			if err = readJSONObject(dec, keyPath, closing, entries); err != nil {
+				return err
+			}
+		case nil:
+		default:
+			*entries = append(*entries, jsonEntry{keyPath, fmt.Sprint(t)})
+		}
+	}
+}
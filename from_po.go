@@ -0,0 +1,161 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// fromPO converts a gettext PO (or POT) to XLIFF. the msgctxt (or, if
+// there is none, the msgid) becomes the id of a trans-unit. see po.go
+// for how the rest of an entry is represented.
+type fromPO struct {
+	inFile     string
+	sourceLang string
+	targetLang string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-po"] = new(fromPO)
+}
+
+func (fp *fromPO) Description() string {
+	return "Converts gettext PO (or POT) to XLIFF"
+}
+
+func (fp *fromPO) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-po", flag.ExitOnError)
+	fs.StringVar(&fp.inFile, "in", "", "infile")
+	fs.StringVar(&fp.sourceLang, "source-lang", "en", "source language")
+	fs.StringVar(&fp.targetLang, "target-lang", "", "target language (default: \"Language\" of the PO header)")
+	xliffVersionFlag(fs, &fp.version)
+	return fs.Parse(args)
+}
+
+func (fp *fromPO) Prepare() error {
+	return nil
+}
+
+func (fp *fromPO) Convert(w io.Writer) error {
+
+	f, err := os.Open(fp.inFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries, err := readPO(f)
+	if err != nil {
+		return err
+	}
+
+	targetLang := fp.targetLang
+	contexts := map[string]int{}
+	for _, e := range entries {
+		if e.isHeader() && targetLang == "" && len(e.Str) > 0 {
+			targetLang = poHeaderField(e.Str[0], "Language")
+		}
+		contexts[e.Ctxt]++
+	}
+
+	doc := newXliffDoc(fp.inFile, fp.sourceLang)
+	file := &doc.File[0]
+	file.DataType = "po"
+	file.TargetLang = targetLang
+
+	ids := map[string]bool{}
+	for _, e := range entries {
+
+		if e.isHeader() {
+			header := ""
+			if len(e.Str) > 0 {
+				header = e.Str[0]
+			}
+			unit := fp.unit(e, "po-header", "", 0, "")
+			unit.Source, unit.Target = fp.source(header), nil
+			setAttr(&unit.Attrs, "restype", PO_HEADER_RESTYPE)
+			setAttr(&unit.Attrs, "translate", "no")
+			file.Body.TransUnit = append(file.Body.TransUnit, unit)
+			continue
+		}
+
+		// the msgctxt is used as id as long as it is unique, the
+		// msgctxt itself is kept in resname
+		id := e.ID
+		switch {
+		case e.Ctxt != "" && contexts[e.Ctxt] == 1:
+			id = e.Ctxt
+		case e.Ctxt != "":
+			id = e.Ctxt + "|" + e.ID
+		}
+		if ids[id] {
+			log.Printf("warning: double entry for key %q", id)
+		}
+		ids[id] = true
+
+		if e.IDPlural == "" {
+			unit := fp.unit(e, id, e.ID, 0, targetLang)
+			if e.Ctxt != "" {
+				setAttr(&unit.Attrs, "resname", e.Ctxt)
+			}
+			e.metaTo(&unit)
+			file.Body.TransUnit = append(file.Body.TransUnit, unit)
+			continue
+		}
+
+		group := xliffGroup{ID: id, ResName: e.Ctxt}
+		setAttr(&group.Attrs, "restype", PO_PLURALS_RESTYPE)
+		for i := 0; i < len(e.Str) || i < 2; i++ {
+			source := e.ID
+			if i > 0 {
+				source = e.IDPlural
+			}
+			group.TransUnit = append(group.TransUnit, fp.unit(e, fmt.Sprintf("%s[%d]", id, i), source, i, targetLang))
+		}
+		e.metaTo(&group.TransUnit[0])
+		file.Body.Group = append(file.Body.Group, group)
+	}
+
+	if err = doc.SetVersion(fp.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
+
+func (fp *fromPO) source(text string) xliffSource {
+	return xliffSource{Lang: fp.sourceLang, Inner: text, Space: "preserve"}
+}
+
+// unit creates the trans-unit for the i-th msgstr of e. the target is
+// left out if there is no translation.
+func (fp *fromPO) unit(e *poEntry, id, source string, i int, targetLang string) xliffTransUnit {
+
+	unit := xliffTransUnit{ID: id, Source: fp.source(source)}
+
+	str := ""
+	if i < len(e.Str) {
+		str = e.Str[i]
+	}
+	fuzzy := e.hasFlag("fuzzy")
+	if str == "" && !fuzzy {
+		return unit
+	}
+
+	unit.Target = &xliffTarget{Lang: targetLang, Inner: str, Space: "preserve", State: "translated"}
+	if fuzzy {
+		unit.Target.State = STATE_NEEDS_REVIEW
+	}
+	return unit
+}
@@ -0,0 +1,137 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+)
+
+// fromProperties converts the .properties of a Java ResourceBundle to
+// XLIFF, the translations taken from the .properties of the target
+// language. see properties.go for how the files are read.
+type fromProperties struct {
+	inFile      string
+	targetFile  string
+	sourceLang  string
+	targetLang  string
+	encoding    string
+	apostrophes string
+	version     string
+}
+
+func init() {
+	registeredConverters["from-properties"] = new(fromProperties)
+}
+
+func (fp *fromProperties) Description() string {
+	return "Converts Java .properties to XLIFF"
+}
+
+func (fp *fromProperties) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-properties", flag.ExitOnError)
+	fs.StringVar(&fp.inFile, "in", "", "infile, holding the source texts (messages.properties)")
+	fs.StringVar(&fp.targetFile, "target", "", "file holding the translations (messages_<lang>.properties, optional)")
+	fs.StringVar(&fp.sourceLang, "source-lang", "", "source language (default: as in the name of -in, or \"en\")")
+	fs.StringVar(&fp.targetLang, "target-lang", "", "target language (default: as in the name of -target)")
+	fs.StringVar(&fp.encoding, "encoding", PROPERTIES_AUTO, "encoding of the files: auto, utf-8 or iso-8859-1")
+	fs.StringVar(&fp.apostrophes, "apostrophes", APOSTROPHES_ARGS, "undouble the apostrophes of MessageFormat messages: args, all or none")
+	xliffVersionFlag(fs, &fp.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fp.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	if !isValidPropertiesEncoding(fp.encoding) {
+		return fmt.Errorf("unsupported 'encoding': %q", fp.encoding)
+	}
+	if !isValidApostrophes(fp.apostrophes) {
+		return fmt.Errorf("unsupported 'apostrophes': %q", fp.apostrophes)
+	}
+	if fp.sourceLang == "" {
+		if fp.sourceLang = propertiesLang(fp.inFile); fp.sourceLang == "" {
+			fp.sourceLang = "en"
+		}
+	}
+	if fp.targetLang == "" && fp.targetFile != "" {
+		fp.targetLang = propertiesLang(fp.targetFile)
+	}
+	return nil
+}
+
+func (fp *fromProperties) Prepare() error {
+	return nil
+}
+
+func (fp *fromProperties) read(fileName string) ([]propEntry, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readProperties(data, fp.encoding)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	return entries, nil
+}
+
+func (fp *fromProperties) Convert(w io.Writer) error {
+
+	entries, err := fp.read(fp.inFile)
+	if err != nil {
+		return err
+	}
+
+	translations := map[string]string{}
+	if fp.targetFile != "" {
+		translated, err := fp.read(fp.targetFile)
+		if err != nil {
+			return err
+		}
+		for _, e := range translated {
+			translations[e.Key] = e.Value
+		}
+	}
+
+	doc := newXliffDoc(fp.inFile, fp.sourceLang)
+	file := &doc.File[0]
+	file.DataType = "javapropertyresourcebundle"
+	file.TargetLang = fp.targetLang
+
+	keys := map[string]bool{}
+	for _, e := range entries {
+		if keys[e.Key] {
+			log.Printf("warning: double entry for key %q", e.Key)
+		}
+		keys[e.Key] = true
+
+		unit := xliffTransUnit{ID: e.Key, Source: xliffSource{Inner: singleApostrophes(e.Value, fp.apostrophes), Space: "preserve"}}
+		if translation := translations[e.Key]; translation != "" {
+			unit.Target = &xliffTarget{Inner: singleApostrophes(translation, fp.apostrophes), Space: "preserve", State: "translated"}
+		}
+		unit.Note = e.Comment
+		file.Body.TransUnit = append(file.Body.TransUnit, unit)
+	}
+
+	for key := range translations {
+		if !keys[key] {
+			log.Printf("warning: key %q of %s is not part of %s, ignored", key, fp.targetFile, fp.inFile)
+		}
+	}
+
+	if err = doc.SetVersion(fp.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
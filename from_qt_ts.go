@@ -0,0 +1,118 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// fromQtTS converts a Qt Linguist .ts to XLIFF. see qt.go for how
+// contexts and messages are represented.
+type fromQtTS struct {
+	inFile     string
+	sourceLang string
+	targetLang string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-qt-ts"] = new(fromQtTS)
+}
+
+func (fq *fromQtTS) Description() string {
+	return "Converts Qt Linguist .ts to XLIFF"
+}
+
+func (fq *fromQtTS) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-qt-ts", flag.ExitOnError)
+	fs.StringVar(&fq.inFile, "in", "", "infile")
+	fs.StringVar(&fq.sourceLang, "source-lang", "", "source language (default: \"sourcelanguage\" of the .ts, or \"en\")")
+	fs.StringVar(&fq.targetLang, "target-lang", "", "target language (default: \"language\" of the .ts)")
+	xliffVersionFlag(fs, &fq.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fq.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (fq *fromQtTS) Prepare() error {
+	return nil
+}
+
+func (fq *fromQtTS) Convert(w io.Writer) error {
+
+	ts, err := qtFromFile(fq.inFile)
+	if err != nil {
+		return err
+	}
+	if fq.sourceLang == "" {
+		if fq.sourceLang = localeLang(ts.SourceLanguage); fq.sourceLang == "" {
+			fq.sourceLang = "en"
+		}
+	}
+	if fq.targetLang == "" {
+		fq.targetLang = localeLang(ts.Language)
+	}
+
+	doc := newXliffDoc(fq.inFile, fq.sourceLang)
+	file := &doc.File[0]
+	file.DataType = "x-qt-ts"
+	file.TargetLang = fq.targetLang
+
+	for _, ctx := range ts.Contexts {
+		group := xliffGroup{ID: ctx.Name}
+		setAttr(&group.Attrs, "restype", QT_CONTEXT_RESTYPE)
+
+		for _, msg := range ctx.Messages {
+			id := msg.unitID(ctx.Name)
+			tr := msg.Translation
+			if !msg.isNumerus() {
+				unit := xliffTransUnit{ID: id, Source: xliffSource{Inner: msg.Source, Space: "preserve"}}
+				if tr.Text != "" || (tr.Type != "" && tr.Type != "unfinished") {
+					unit.Target = &xliffTarget{Inner: tr.Text, Space: "preserve", State: tr.xliffState()}
+				}
+				msg.metaTo(&unit)
+				group.TransUnit = append(group.TransUnit, unit)
+				continue
+			}
+
+			// a unit per numerus form the target language has; the
+			// comments and locations are kept with the first one
+			numerus := xliffGroup{ID: id}
+			setAttr(&numerus.Attrs, "restype", QT_NUMERUS_RESTYPE)
+			forms := qtNumerusForms(fq.targetLang)
+			if len(tr.Forms) > forms {
+				forms = len(tr.Forms)
+			}
+			for i := 0; i < forms; i++ {
+				unit := xliffTransUnit{ID: fmt.Sprintf("%s[%d]", id, i), Source: xliffSource{Inner: msg.Source, Space: "preserve"}}
+				if i < len(tr.Forms) && tr.Forms[i] != "" {
+					unit.Target = &xliffTarget{Inner: tr.Forms[i], Space: "preserve", State: tr.xliffState()}
+				}
+				if i == 0 {
+					msg.metaTo(&unit)
+				}
+				numerus.TransUnit = append(numerus.TransUnit, unit)
+			}
+			group.Group = append(group.Group, numerus)
+		}
+		file.Body.Group = append(file.Body.Group, group)
+	}
+
+	if err = doc.SetVersion(fq.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
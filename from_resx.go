@@ -0,0 +1,114 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+)
+
+// fromResx converts the strings of a neutral .resx to XLIFF, the
+// translations taken from the .resx of the target language. see resx.go
+// for how resources are represented.
+type fromResx struct {
+	inFile     string
+	targetFile string
+	sourceLang string
+	targetLang string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-resx"] = new(fromResx)
+}
+
+func (fr *fromResx) Description() string {
+	return "Converts .NET .resx to XLIFF"
+}
+
+func (fr *fromResx) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-resx", flag.ExitOnError)
+	fs.StringVar(&fr.inFile, "in", "", "infile, holding the source texts (Resources.resx)")
+	fs.StringVar(&fr.targetFile, "target", "", "file holding the translations (Resources.<lang>.resx, optional)")
+	fs.StringVar(&fr.sourceLang, "source-lang", "en", "source language")
+	fs.StringVar(&fr.targetLang, "target-lang", "", "target language (default: as in the name of -target)")
+	xliffVersionFlag(fs, &fr.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fr.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	if fr.targetLang == "" && fr.targetFile != "" {
+		fr.targetLang = resxLang(fr.targetFile)
+	}
+	return nil
+}
+
+func (fr *fromResx) Prepare() error {
+	return nil
+}
+
+func (fr *fromResx) Convert(w io.Writer) error {
+
+	resx, err := resxFromFile(fr.inFile)
+	if err != nil {
+		return err
+	}
+
+	translations := map[string]string{}
+	if fr.targetFile != "" {
+		translated, err := resxFromFile(fr.targetFile)
+		if err != nil {
+			return err
+		}
+		for _, data := range translated.Data {
+			if data.isString() {
+				translations[data.Name] = data.Value
+			}
+		}
+	}
+
+	doc := newXliffDoc(fr.inFile, fr.sourceLang)
+	file := &doc.File[0]
+	file.DataType = "resx"
+	file.TargetLang = fr.targetLang
+
+	names := map[string]bool{}
+	for _, data := range resx.Data {
+		if !data.isString() {
+			continue
+		}
+		if names[data.Name] {
+			log.Printf("warning: double entry for key %q", data.Name)
+		}
+		names[data.Name] = true
+
+		unit := xliffTransUnit{ID: data.Name, Source: xliffSource{Inner: data.Value, Space: "preserve"}}
+		if translation := translations[data.Name]; translation != "" {
+			unit.Target = &xliffTarget{Inner: translation, Space: "preserve", State: "translated"}
+		}
+		unit.Note = data.Comment
+		file.Body.TransUnit = append(file.Body.TransUnit, unit)
+	}
+
+	for name := range translations {
+		if !names[name] {
+			log.Printf("warning: key %q of %s is not part of %s, ignored", name, fr.targetFile, fr.inFile)
+		}
+	}
+
+	if err = doc.SetVersion(fr.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
@@ -0,0 +1,262 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+)
+
+// fromTMX converts a language pair of a TMX to XLIFF. with -merge the
+// translations fill the empty targets of an existing XLIFF instead,
+// matched by the plain text of the sources.
+type fromTMX struct {
+	inFile     string
+	sourceLang string
+	targetLang string
+	mergeFile  string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-tmx"] = new(fromTMX)
+}
+
+func (ft *fromTMX) Description() string {
+	return "Converts TMX to XLIFF"
+}
+
+func (ft *fromTMX) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-tmx", flag.ExitOnError)
+	fs.StringVar(&ft.inFile, "in", "", "infile")
+	fs.StringVar(&ft.sourceLang, "source-lang", "", "source language (default: \"srclang\" of the TMX header)")
+	fs.StringVar(&ft.targetLang, "target-lang", "", "target language (default: the only other language of the TMX)")
+	fs.StringVar(&ft.mergeFile, "merge", "", "XLIFF whose empty targets are filled, instead of creating one")
+	xliffVersionFlag(fs, &ft.version)
+	return fs.Parse(args)
+}
+
+func (ft *fromTMX) Prepare() error {
+	return nil
+}
+
+func (ft *fromTMX) Convert(w io.Writer) error {
+
+	tmx, err := tmxFromFile(ft.inFile)
+	if err != nil {
+		return err
+	}
+
+	var doc *xliffDoc
+	if ft.mergeFile != "" {
+		if doc, err = xliffFromFile(ft.mergeFile); err != nil {
+			return err
+		}
+	}
+
+	sourceLang, targetLang := ft.sourceLang, ft.targetLang
+	if sourceLang == "" && doc != nil && len(doc.File) > 0 {
+		sourceLang = doc.File[0].SourceLang
+	}
+	if sourceLang == "" {
+		sourceLang = tmx.Header.SrcLang
+	}
+	if (sourceLang == "" || sourceLang == TMX_ALL_LANGS) && len(tmx.TU) > 0 && len(tmx.TU[0].TUV) > 0 {
+		sourceLang = tmx.TU[0].TUV[0].Lang
+	}
+	if targetLang == "" && doc != nil && len(doc.File) > 0 {
+		targetLang = doc.File[0].TargetLang
+	}
+	if targetLang == "" {
+		if targetLang, err = ft.otherLang(tmx, sourceLang); err != nil {
+			return err
+		}
+	}
+
+	if doc != nil {
+		ft.merge(tmx, doc, sourceLang, targetLang)
+	} else {
+		doc = ft.convert(tmx, sourceLang, targetLang)
+	}
+
+	if err = doc.SetVersion(ft.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
+
+// otherLang returns the language of the TMX besides sourceLang, if there
+// is exactly one
+func (ft *fromTMX) otherLang(tmx *tmxDoc, sourceLang string) (string, error) {
+
+	langs := map[string]string{}
+	for _, tu := range tmx.TU {
+		for _, tuv := range tu.TUV {
+			if !sameLang(tuv.Lang, sourceLang) {
+				langs[normLang(tuv.Lang)] = tuv.Lang
+			}
+		}
+	}
+	if len(langs) == 1 {
+		for _, lang := range langs {
+			return lang, nil
+		}
+	}
+
+	found := make([]string, 0, len(langs))
+	for _, lang := range langs {
+		found = append(found, lang)
+	}
+	sort.Strings(found)
+	return "", fmt.Errorf("missing -target-lang, found: %s", strings.Join(found, ", "))
+}
+
+// pairs calls fn for each tu holding a non-empty source in sourceLang,
+// with its target variant (nil if the tu lacks targetLang)
+func (ft *fromTMX) pairs(tmx *tmxDoc, sourceLang, targetLang string, fn func(tu *tmxTU, source, target *tmxTUV)) {
+	for i := range tmx.TU {
+		tu := &tmx.TU[i]
+		if lang := tu.srcLang(tmx); lang != TMX_ALL_LANGS && !sameLang(lang, sourceLang) {
+			continue
+		}
+		source := tu.variant(sourceLang)
+		if source == nil || strings.TrimSpace(source.Seg.Inner) == "" {
+			continue
+		}
+		target := tu.variant(targetLang)
+		if target != nil && strings.TrimSpace(target.Seg.Inner) == "" {
+			target = nil
+		}
+		fn(tu, source, target)
+	}
+}
+
+// convert creates a XLIFF holding a trans-unit per tu. the tuid (or, if
+// there is none, the number of the tu) becomes the id.
+func (ft *fromTMX) convert(tmx *tmxDoc, sourceLang, targetLang string) *xliffDoc {
+
+	doc := newXliffDoc(ft.inFile, sourceLang)
+	file := &doc.File[0]
+	file.DataType = "xml"
+	file.TargetLang = targetLang
+
+	ids := map[string]bool{}
+	ft.pairs(tmx, sourceLang, targetLang, func(tu *tmxTU, source, target *tmxTUV) {
+
+		id := tu.TUID
+		if id == "" {
+			id = fmt.Sprint(len(file.Body.TransUnit) + 1)
+		}
+		if ids[id] {
+			log.Printf("warning: double entry for key %q", id)
+		}
+		ids[id] = true
+
+		content := source.content()
+		unit := xliffTransUnit{ID: id, Source: xliffSource{Content: content, Inner: content.Plain()}}
+		if target != nil {
+			content = target.content()
+			unit.Target = &xliffTarget{Content: content, Inner: content.Plain(), State: "translated"}
+		}
+		file.Body.TransUnit = append(file.Body.TransUnit, unit)
+	})
+
+	return doc
+}
+
+// merge fills the empty targets of doc. the inline elements of a
+// translation are replaced by the ones of the unit, matched by their
+// position in the sources.
+func (ft *fromTMX) merge(tmx *tmxDoc, doc *xliffDoc, sourceLang, targetLang string) {
+
+	type pair struct{ source, target xliffContent }
+	translations := map[string]pair{} // by plain text of the source
+	ft.pairs(tmx, sourceLang, targetLang, func(_ *tmxTU, source, target *tmxTUV) {
+		if target != nil {
+			content := source.content()
+			translations[content.TextOnly()] = pair{content, target.content()}
+		}
+	})
+
+	for i := range doc.File {
+		file := &doc.File[i]
+		if !sameLang(file.SourceLang, sourceLang) {
+			continue
+		}
+		for _, unit := range file.Body.Units() {
+			if unit.Target != nil && strings.TrimSpace(unit.Target.Inner) != "" {
+				continue
+			}
+			t, ok := translations[unit.Source.Text(INLINE_TEXT_ONLY)]
+			if !ok {
+				continue
+			}
+
+			from, to := inlineElements(t.source), inlineElements(unit.Source.content())
+			if len(from) != len(to) {
+				log.Printf("warning: codes of %q differ from the TMX, not merged", unit.ID)
+				continue
+			}
+			replace := map[string]xliffInline{}
+			for j := range from {
+				replace[from[j].Name+"\n"+from[j].attr("id")] = to[j]
+			}
+			content := replaceInline(t.target, replace)
+
+			if unit.Target == nil {
+				unit.Target = &xliffTarget{}
+			}
+			if file.TargetLang == "" {
+				unit.Target.Lang = targetLang
+			}
+			unit.Target.Content, unit.Target.Inner = content, content.Plain()
+			unit.Target.State = "translated"
+		}
+	}
+}
+
+// inlineElements returns the inline elements of content in order
+func inlineElements(content xliffContent) []xliffInline {
+	var elements []xliffInline
+	for _, inline := range content {
+		if inline.Name != "" {
+			elements = append(elements, inline)
+			elements = append(elements, inlineElements(inline.Content)...)
+		}
+	}
+	return elements
+}
+
+// replaceInline returns a copy of content with its inline elements
+// replaced, looked up by name and id. codes are replaced as a whole,
+// of other elements (<g>, <mrk>) the content is kept.
+func replaceInline(content xliffContent, replace map[string]xliffInline) xliffContent {
+	replaced := make(xliffContent, len(content))
+	for i, inline := range content {
+		if inline.Name != "" {
+			with, ok := replace[inline.Name+"\n"+inline.attr("id")]
+			switch {
+			case ok && with.isCode():
+				inline = with
+			case ok:
+				with.Content = replaceInline(inline.Content, replace)
+				inline = with
+			default:
+				inline.Content = replaceInline(inline.Content, replace)
+			}
+		}
+		replaced[i] = inline
+	}
+	return replaced
+}
@@ -0,0 +1,213 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fromXcstrings splits a String Catalog into an XLIFF per target
+// language. see xcstrings.go for how the strings are represented.
+type fromXcstrings struct {
+	inFile      string
+	targetLangs []string
+	outDir      string
+	version     string
+}
+
+func init() {
+	registeredConverters["from-xcstrings"] = new(fromXcstrings)
+}
+
+func (fx *fromXcstrings) Description() string {
+	return "Converts an Xcode String Catalog (.xcstrings) to XLIFF"
+}
+
+func (fx *fromXcstrings) ParseArgs(base string, args []string) error {
+	var targetLangs string
+	var fs = flag.NewFlagSet(base+" from-xcstrings", flag.ExitOnError)
+	fs.StringVar(&fx.inFile, "in", "", "infile (.xcstrings)")
+	fs.StringVar(&targetLangs, "target-lang", "", "comma separated target languages (default: all languages of the catalog)")
+	fs.StringVar(&fx.outDir, "out", "", "directory to write <lang>.xlf to (default: write a single language to stdout)")
+	xliffVersionFlag(fs, &fx.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fx.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	for _, lang := range strings.Split(targetLangs, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			fx.targetLangs = append(fx.targetLangs, lang)
+		}
+	}
+	return nil
+}
+
+func (fx *fromXcstrings) Prepare() error {
+	return nil
+}
+
+func (fx *fromXcstrings) Convert(w io.Writer) error {
+
+	cat, err := xcstringsFromFile(fx.inFile)
+	if err != nil {
+		return err
+	}
+	if cat.sourceLang() == "" {
+		return fmt.Errorf("%s: missing sourceLanguage", fx.inFile)
+	}
+
+	langs := fx.targetLangs
+	if len(langs) == 0 {
+		for _, lang := range cat.langs() {
+			if lang != cat.sourceLang() {
+				langs = append(langs, lang)
+			}
+		}
+		if len(langs) == 0 {
+			return fmt.Errorf("no translations in %s, missing -target-lang", fx.inFile)
+		}
+	}
+
+	if fx.outDir == "" && len(langs) != 1 {
+		return fmt.Errorf("%d languages to write, missing -out", len(langs))
+	}
+
+	for _, lang := range langs {
+		doc := fx.xliff(cat, lang)
+		if err := doc.SetVersion(fx.version); err != nil {
+			return err
+		}
+		if fx.outDir == "" {
+			return writeXliff(w, doc, "  ")
+		}
+
+		buf := bytes.NewBuffer(nil)
+		if err := writeXliff(buf, doc, "  "); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(fx.outDir, 0755); err != nil {
+			return err
+		}
+		fileName := filepath.Join(fx.outDir, lang+".xlf")
+		if err := ioutil.WriteFile(fileName, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s: %d units\n", fileName, len(doc.File[0].Body.Units()))
+	}
+	return nil
+}
+
+// xliff returns the strings of the catalog with the translations into lang
+func (fx *fromXcstrings) xliff(cat *xcCatalog, lang string) *xliffDoc {
+
+	doc := newXliffDoc(filepath.Base(fx.inFile), cat.sourceLang())
+	file := &doc.File[0]
+	file.TargetLang = lang
+	file.DataType = "plaintext"
+
+	for _, key := range cat.keys() {
+		if key == "" {
+			continue
+		}
+		entry := xcChild(cat.strings(), key)
+		sources := map[string]string{}
+		var paths []string
+		for _, leaf := range xcLeaves(xcChild(entry, "localizations", cat.sourceLang()), "") {
+			sources[leaf.path], _ = leaf.unit["value"].(string)
+			paths = append(paths, leaf.path)
+		}
+		if len(paths) == 0 {
+			sources[""] = key
+			paths = append(paths, "")
+		}
+
+		// the target may have variations the source has not (plural
+		// forms of the language, devices), they are translated from
+		// "other" or the string without variations
+		targets := map[string]xcObject{}
+		for _, leaf := range xcLeaves(xcChild(entry, "localizations", lang), "") {
+			targets[leaf.path] = leaf.unit
+			if _, inSource := sources[leaf.path]; !inSource {
+				paths = append(paths, leaf.path)
+			}
+		}
+
+		comment, _ := entry["comment"].(string)
+		extractionState, _ := entry["extractionState"].(string)
+		translate, _ := entry["shouldTranslate"].(bool)
+		translate = translate || entry["shouldTranslate"] == nil
+
+		var units []xliffTransUnit
+		for i, path := range paths {
+			unit := xliffTransUnit{ID: key, Source: xliffSource{Inner: xcSource(sources, path, key), Space: "preserve"}}
+			if target := targets[path]; target != nil {
+				value, _ := target["value"].(string)
+				state, _ := target["state"].(string)
+				if value != "" {
+					unit.Target = &xliffTarget{Inner: value, Space: "preserve", State: xliffState(state)}
+				}
+			}
+			if extractionState != "" && i == 0 {
+				unit.addContext(XCSTRINGS_CONTEXT_GROUP, "information", XCSTRINGS_EXTRACTION_STATE, extractionState)
+			}
+			if path != "" || len(paths) > 1 {
+				unit.ID = key + "[" + path + "]"
+				setAttr(&unit.Attrs, "resname", path)
+			}
+			units = append(units, unit)
+		}
+
+		if len(units) == 1 && paths[0] == "" {
+			unit := units[0]
+			unit.Note = comment
+			if !translate {
+				setAttr(&unit.Attrs, "translate", "no")
+			}
+			file.Body.TransUnit = append(file.Body.TransUnit, unit)
+			continue
+		}
+
+		group := xliffGroup{ID: key, TransUnit: units}
+		setAttr(&group.Attrs, "restype", XCSTRINGS_VARIATIONS_RESTYPE)
+		if comment != "" {
+			group.Note = []string{comment}
+		}
+		if !translate {
+			setAttr(&group.Attrs, "translate", "no")
+		}
+		file.Body.Group = append(file.Body.Group, group)
+	}
+	return doc
+}
+
+// xcSource returns the source of a path: the one of the path itself,
+// of "other" instead of another plural form, of the string without
+// variations or the key
+func xcSource(sources map[string]string, path, key string) string {
+	candidates := []string{path}
+	if dot := strings.LastIndexByte(path, '.'); dot >= 0 {
+		candidates = append(candidates, path[:dot+1]+"other")
+	}
+	candidates = append(candidates, "")
+	for _, p := range candidates {
+		if source, ok := sources[p]; ok && source != "" {
+			return source
+		}
+	}
+	return key
+}
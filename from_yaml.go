@@ -0,0 +1,155 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// fromYAML converts a Rails locale file to XLIFF, the translations taken
+// from the locale file of the target language. see yaml.go for how texts
+// are represented.
+type fromYAML struct {
+	inFile     string
+	targetFile string
+	sourceLang string
+	targetLang string
+	version    string
+}
+
+func init() {
+	registeredConverters["from-yaml"] = new(fromYAML)
+}
+
+func (fy *fromYAML) Description() string {
+	return "Converts Rails YAML locale files to XLIFF"
+}
+
+func (fy *fromYAML) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" from-yaml", flag.ExitOnError)
+	fs.StringVar(&fy.inFile, "in", "", "infile, holding the source texts (en.yml)")
+	fs.StringVar(&fy.targetFile, "target", "", "file holding the translations (<lang>.yml, optional)")
+	fs.StringVar(&fy.sourceLang, "source-lang", "", "source language (default: the top key of -in)")
+	fs.StringVar(&fy.targetLang, "target-lang", "", "target language (default: the top key of -target)")
+	xliffVersionFlag(fs, &fy.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fy.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (fy *fromYAML) Prepare() error {
+	return nil
+}
+
+func (fy *fromYAML) Convert(w io.Writer) error {
+
+	lang, entries, err := yamlFromFile(fy.inFile)
+	if err != nil {
+		return err
+	}
+	if fy.sourceLang == "" {
+		fy.sourceLang = lang
+	}
+
+	translations := map[string]string{}
+	var targetPlurals []yamlEntry
+	if fy.targetFile != "" {
+		lang, translated, err := yamlFromFile(fy.targetFile)
+		if err != nil {
+			return err
+		}
+		if fy.targetLang == "" {
+			fy.targetLang = lang
+		}
+		for _, e := range translated {
+			translations[e.key()] = e.Value
+			if e.Plural {
+				targetPlurals = append(targetPlurals, e)
+			}
+		}
+	}
+
+	doc := newXliffDoc(fy.inFile, fy.sourceLang)
+	file := &doc.File[0]
+	file.DataType = "x-yaml"
+	file.TargetLang = fy.targetLang
+
+	unit := func(e yamlEntry, source string) xliffTransUnit {
+		unit := xliffTransUnit{ID: e.key(), Source: xliffSource{Inner: source, Space: "preserve"}}
+		if translation := translations[e.key()]; translation != "" {
+			unit.Target = &xliffTarget{Inner: translation, Space: "preserve", State: "translated"}
+		}
+		unit.Note = e.Comment
+		return unit
+	}
+
+	keys := map[string]bool{}
+	for i := 0; i < len(entries); i++ {
+		e := entries[i]
+		keys[e.key()] = true
+		if !e.Plural {
+			file.Body.TransUnit = append(file.Body.TransUnit, unit(e, e.Value))
+			continue
+		}
+
+		// the plural forms of a key follow each other
+		parent := strings.Join(e.Path[:len(e.Path)-1], ".")
+		forms := map[string]yamlEntry{}
+		var quantities, targetQuantities []string
+		for ; i < len(entries) && entries[i].Plural && strings.Join(entries[i].Path[:len(entries[i].Path)-1], ".") == parent; i++ {
+			quantity := entries[i].Path[len(entries[i].Path)-1]
+			forms[quantity] = entries[i]
+			quantities = append(quantities, quantity)
+			keys[entries[i].key()] = true
+		}
+		i--
+		for _, te := range targetPlurals {
+			if strings.Join(te.Path[:len(te.Path)-1], ".") == parent {
+				targetQuantities = append(targetQuantities, te.Path[len(te.Path)-1])
+				keys[te.key()] = true
+			}
+		}
+
+		group := xliffGroup{ID: parent}
+		setAttr(&group.Attrs, "restype", YAML_PLURAL_RESTYPE)
+		for _, form := range pluralForms(quantities, targetQuantities) {
+			source := forms[form.Source]
+			entry := yamlEntry{Path: append(e.Path[:len(e.Path)-1:len(e.Path)-1], form.Quantity)}
+			if form.Source == form.Quantity {
+				entry.Comment = source.Comment
+			}
+			unit := unit(entry, source.Value)
+			unit.addContext(YAML_CONTEXT_GROUP, "information", YAML_POSITION, strconv.Itoa(len(file.Body.TransUnit)))
+			form.mark(&unit)
+			group.TransUnit = append(group.TransUnit, unit)
+		}
+		file.Body.Group = append(file.Body.Group, group)
+	}
+
+	for key := range translations {
+		if !keys[key] {
+			log.Printf("warning: key %q of %s is not part of %s, ignored", key, fy.targetFile, fy.inFile)
+		}
+	}
+
+	if err = doc.SetVersion(fy.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
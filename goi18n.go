@@ -0,0 +1,287 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// the message files of go-i18n (v2) map message ids to a text, or to the
+// plural forms and the description of a message, as TOML, JSON or YAML:
+//
+//	HelloPerson = "Hello {{.Name}}"
+//
+//	[PersonCats]
+//	description = "The number of cats a person has"
+//	one = "{{.Name}} has {{.Count}} cat."
+//	other = "{{.Name}} has {{.Count}} cats."
+//
+// a text becomes a trans-unit with the message id as id, the description
+// as note. a message with plural forms becomes a <group
+// restype="x-go-i18n-plural"> holding a unit per form ("PersonCats.one").
+// the hash of the source message, which "goi18n merge" writes into
+// translate.<lang>.toml, and the delimiters of the templates are kept in
+// a <context>.
+const (
+	GO_I18N_PLURAL_RESTYPE = "x-go-i18n-plural"
+	GO_I18N_CONTEXT_GROUP  = "go-i18n"
+	GO_I18N_HASH           = "x-go-i18n-hash"
+	GO_I18N_LEFT_DELIM     = "x-go-i18n-leftdelim"
+	GO_I18N_RIGHT_DELIM    = "x-go-i18n-rightdelim"
+
+	GO_I18N_TOML = "toml"
+	GO_I18N_JSON = "json"
+	GO_I18N_YAML = "yaml"
+)
+
+// goI18nMessage is a message of a go-i18n message file
+type goI18nMessage struct {
+	ID          string
+	Description string
+	Hash        string
+	LeftDelim   string
+	RightDelim  string
+	Forms       map[string]string // zero, one, two, few, many, other
+}
+
+func isValidGoI18nFormat(format string) bool {
+	return format == GO_I18N_TOML || format == GO_I18N_JSON || format == GO_I18N_YAML
+}
+
+// isPlural tells whether msg has other forms than "other"
+func (msg *goI18nMessage) isPlural() bool {
+	for form := range msg.Forms {
+		if form != "other" {
+			return true
+		}
+	}
+	return false
+}
+
+// metaTo stores description, hash and delimiters of msg in unit, metaFrom
+// takes them from it
+func (msg *goI18nMessage) metaTo(unit *xliffTransUnit) {
+	unit.Note = msg.Description
+	for _, c := range []struct{ ctxType, value string }{
+		{GO_I18N_HASH, msg.Hash},
+		{GO_I18N_LEFT_DELIM, msg.LeftDelim},
+		{GO_I18N_RIGHT_DELIM, msg.RightDelim},
+	} {
+		if c.value != "" {
+			unit.addContext(GO_I18N_CONTEXT_GROUP, "information", c.ctxType, c.value)
+		}
+	}
+}
+
+func (msg *goI18nMessage) metaFrom(unit *xliffTransUnit) {
+	msg.Description = unit.Note
+	msg.Hash = unit.context(GO_I18N_CONTEXT_GROUP, GO_I18N_HASH)
+	msg.LeftDelim = unit.context(GO_I18N_CONTEXT_GROUP, GO_I18N_LEFT_DELIM)
+	msg.RightDelim = unit.context(GO_I18N_CONTEXT_GROUP, GO_I18N_RIGHT_DELIM)
+}
+
+// goI18nPath returns language and format of a message file by its name,
+// as go-i18n does (active.en.toml -> en, toml)
+func goI18nPath(fileName string) (lang, format string) {
+	parts := strings.Split(filepath.Base(fileName), ".")
+	if len(parts) < 2 {
+		return "", ""
+	}
+	format = strings.ToLower(parts[len(parts)-1])
+	if format == "yml" {
+		format = GO_I18N_YAML
+	}
+	return parts[len(parts)-2], format
+}
+
+func goI18nFromFile(fileName, format string) ([]goI18nMessage, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		_, format = goI18nPath(fileName)
+	}
+	messages, err := readGoI18n(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	return messages, nil
+}
+
+// readGoI18n reads the messages of a message file, sorted by id
+func readGoI18n(data []byte, format string) ([]goI18nMessage, error) {
+
+	var v interface{}
+	var err error
+	switch format {
+	case GO_I18N_TOML:
+		err = toml.Unmarshal(data, &v)
+	case GO_I18N_JSON:
+		err = json.Unmarshal(data, &v)
+	case GO_I18N_YAML:
+		err = yaml.Unmarshal(data, &v)
+	default:
+		return nil, fmt.Errorf("unsupported format: %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []goI18nMessage
+	switch v := v.(type) {
+	case map[string]interface{}:
+		goI18nMessages(v, "", &messages)
+	case []interface{}:
+		// a list of messages with "id"
+		for _, m := range v {
+			if m, ok := m.(map[string]interface{}); ok && isGoI18nMessage(m) {
+				messages = append(messages, newGoI18nMessage("", m))
+			}
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("expected messages")
+	}
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+// goI18nMessages collects the messages of m, nested keys joined by dots
+func goI18nMessages(m map[string]interface{}, prefix string, messages *[]goI18nMessage) {
+	for key, value := range m {
+		id := prefix + key
+		switch value := value.(type) {
+		case string:
+			*messages = append(*messages, goI18nMessage{ID: id, Forms: map[string]string{"other": value}})
+		case map[string]interface{}:
+			if isGoI18nMessage(value) {
+				*messages = append(*messages, newGoI18nMessage(id, value))
+				continue
+			}
+			goI18nMessages(value, id+".", messages)
+		default:
+			log.Printf("warning: %s: only texts are supported, ignored", id)
+		}
+	}
+}
+
+var goI18nFields = []string{"id", "description", "hash", "leftdelim", "rightdelim", "zero", "one", "two", "few", "many", "other"}
+
+// isGoI18nMessage tells whether m is a message, which it is if one of
+// the fields of a message is a text
+func isGoI18nMessage(m map[string]interface{}) bool {
+	for key, value := range m {
+		if _, ok := value.(string); !ok {
+			continue
+		}
+		for _, field := range goI18nFields {
+			if strings.ToLower(key) == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newGoI18nMessage(id string, m map[string]interface{}) goI18nMessage {
+	msg := goI18nMessage{ID: id, Forms: map[string]string{}}
+	for key, value := range m {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		switch key = strings.ToLower(key); key {
+		case "id":
+			msg.ID = s
+		case "description":
+			msg.Description = s
+		case "hash":
+			msg.Hash = s
+		case "leftdelim":
+			msg.LeftDelim = s
+		case "rightdelim":
+			msg.RightDelim = s
+		default:
+			if isQuantity(key) {
+				msg.Forms[key] = s
+			}
+		}
+	}
+	return msg
+}
+
+// writeGoI18n writes messages as message file in format, as goi18n does:
+// sorted by id, a message which is nothing but a text as text
+func writeGoI18n(w io.Writer, messages []goI18nMessage, format string) error {
+
+	values := map[string]interface{}{}
+	for _, msg := range messages {
+		if len(msg.Forms) == 1 && msg.Forms["other"] != "" && msg.Description == "" && msg.Hash == "" && msg.LeftDelim == "" && msg.RightDelim == "" {
+			values[msg.ID] = msg.Forms["other"]
+			continue
+		}
+		fields := map[string]string{}
+		for field, value := range map[string]string{
+			"description": msg.Description,
+			"hash":        msg.Hash,
+			"leftdelim":   msg.LeftDelim,
+			"rightdelim":  msg.RightDelim,
+		} {
+			if value != "" {
+				fields[field] = value
+			}
+		}
+		for form, value := range msg.Forms {
+			fields[form] = value
+		}
+		values[msg.ID] = fields
+	}
+
+	buf := bytes.NewBuffer(nil)
+	switch format {
+	case GO_I18N_TOML:
+		enc := toml.NewEncoder(buf)
+		enc.Indent = ""
+		if err := enc.Encode(values); err != nil {
+			return err
+		}
+	case GO_I18N_JSON:
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(values); err != nil {
+			return err
+		}
+	case GO_I18N_YAML:
+		enc := yaml.NewEncoder(buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(values); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format: %q", format)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
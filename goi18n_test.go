@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoI18nRoundTrip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "goi18n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "de.xlf")
+
+	doc := convertXliff(t, &fromGoI18n{inFile: "testdata/goi18n/active.en.toml", targetFile: "testdata/goi18n/translate.de.toml"}, xlf)
+	file := doc.File[0]
+	if file.SourceLang != "en" || file.TargetLang != "de" {
+		t.Errorf("expected en -> de, got %s -> %s", file.SourceLang, file.TargetLang)
+	}
+	checkUnits(t, &file.Body, describeUnit, map[string]string{
+		"HelloPerson":      "Hello {{.Name}} -> Hallo {{.Name}} # ",
+		"PersonCats.one":   "{{.Name}} has {{.Count}} cat. -> {{.Name}} hat {{.Count}} Katze. # The number of cats a person has",
+		"PersonCats.other": "{{.Name}} has {{.Count}} cats. -> {{.Name}} hat {{.Count}} Katzen. # The number of cats a person has",
+		"Unread":           "You have <<.Count>> unread messages ->  # ",
+		"Welcome":          "Welcome! ->  # ",
+		"errors.notFound":  "Page \"{{.Path}}\"\nnot found -> Seite \"{{.Path}}\"\nnicht gefunden # shown if a page does not exist",
+	})
+
+	// the untranslated message is left out, the hashes are kept
+	translated := strings.Replace(readFile(t, "testdata/goi18n/translate.de.toml"), "[Welcome]\nhash = \"sha1-f1b9a6b0c6e5e3e0a9c5b4b5a2d8f7f1e4c3b2a1\"\nother = \"Welcome!\"\n\n", "", 1)
+	checkText(t, "translate.de.toml", translated, converted(t, &toGoI18n{inFile: xlf, format: GO_I18N_TOML}))
+
+	for _, format := range []string{GO_I18N_JSON, GO_I18N_YAML} {
+		messages, err := readGoI18n([]byte(converted(t, &toGoI18n{inFile: xlf, format: format})), format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if len(messages) != 3 || messages[1].ID != "PersonCats" || messages[1].Forms["one"] != "{{.Name}} hat {{.Count}} Katze." || messages[1].Hash == "" {
+			t.Errorf("%s: unexpected messages %v", format, messages)
+		}
+	}
+}
+
+func TestReadGoI18n(t *testing.T) {
+
+	data := `{
+  "nested": {
+    "title": "Title",
+    "Cats": {"ID": "cats", "One": "a cat", "Other": "cats"}
+  },
+  "count": 3
+}`
+	messages, err := readGoI18n([]byte(data), GO_I18N_JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].ID != "cats" || messages[0].Forms["one"] != "a cat" || messages[1].ID != "nested.title" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+
+	// inline tables and values other than texts are valid TOML
+	data = "# comment\na.b = 'C:\\path'\nCats = { one = \"a cat\", other = \"cats\" }\ncount = 3\n[c]\nd = \"\"\"\\\n  x \\u00e9\"\"\"\n"
+	if messages, err = readGoI18n([]byte(data), GO_I18N_TOML); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || messages[0].ID != "Cats" || messages[0].Forms["one"] != "a cat" || messages[1].Forms["other"] != `C:\path` || messages[2].Forms["other"] != "x é" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+
+	if _, err = readGoI18n([]byte("a = \"x\"\na = \"y\"\n"), GO_I18N_TOML); err == nil {
+		t.Errorf("expected an error for a duplicate key")
+	}
+}
+
+func TestGoI18nTargetForms(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "goi18n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "translate.pl.toml")
+	data := "[PersonCats]\none = \"{{.Name}} ma {{.Count}} kota.\"\nfew = \"{{.Name}} ma {{.Count}} koty.\"\nmany = \"{{.Name}} ma {{.Count}} kotów.\"\nother = \"{{.Name}} ma {{.Count}} kota.\"\n"
+	if err = ioutil.WriteFile(target, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	xlf := filepath.Join(dir, "pl.xlf")
+	convertXliff(t, &fromGoI18n{inFile: "testdata/goi18n/active.en.toml", targetFile: target, targetLang: "pl"}, xlf)
+
+	// the forms of polish only are translated, but no sources
+	for source, expected := range map[bool]string{false: "one few many other", true: "one other"} {
+		messages, err := readGoI18n([]byte(converted(t, &toGoI18n{inFile: xlf, source: source, format: GO_I18N_TOML})), GO_I18N_TOML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var forms []string
+		for _, msg := range messages {
+			if msg.ID == "PersonCats" {
+				for _, quantity := range pluralQuantities {
+					if _, ok := msg.Forms[quantity]; ok {
+						forms = append(forms, quantity)
+					}
+				}
+			}
+		}
+		if got := strings.Join(forms, " "); got != expected {
+			t.Errorf("source %v: expected the forms %s, got %s", source, expected, got)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// gotext (golang.org/x/text/message/pipeline) extracts the messages of a
+// Go program into locales/<lang>/out.gotext.json, translations are read
+// from locales/<lang>/messages.gotext.json:
+//
+//	{
+//	    "language": "de",
+//	    "messages": [
+//	        {
+//	            "id": "Hello {City}!",
+//	            "message": "Hello {City}!",
+//	            "translation": "Hallo {City}!",
+//	            "placeholders": [
+//	                {
+//	                    "id": "City",
+//	                    "string": "%[1]s",
+//	                    "type": "string",
+//	                    "underlyingType": "string",
+//	                    "argNum": 1,
+//	                    "expr": "city"
+//	                }
+//	            ]
+//	        }
+//	    ]
+//	}
+//
+// a message becomes a trans-unit with the id as id, the text with its
+// placeholders ({City}) as it is. the comment goes into the note, the
+// placeholders are kept as JSON in a <context>, as well as the other
+// fields (key, meaning, translatorComment, position). a fuzzy translation
+// needs a review.
+//
+// a message selecting on an argument (plural) becomes a <group
+// restype="x-gotext-select"> holding a unit per case ("<id>[one]"), the
+// feature and the argument kept in contexts of the first one.
+const (
+	GOTEXT_SELECT_RESTYPE     = "x-gotext-select"
+	GOTEXT_CONTEXT_GROUP      = "gotext"
+	GOTEXT_IDS                = "x-gotext-ids"
+	GOTEXT_KEY                = "x-gotext-key"
+	GOTEXT_MEANING            = "x-gotext-meaning"
+	GOTEXT_TRANSLATOR_COMMENT = "x-gotext-translator-comment"
+	GOTEXT_PLACEHOLDERS       = "x-gotext-placeholders"
+	GOTEXT_POSITION           = "x-gotext-position"
+	GOTEXT_FEATURE            = "x-gotext-feature"
+	GOTEXT_ARG                = "x-gotext-arg"
+)
+
+// gotextMessages is a messages.gotext.json, the texts and the
+// placeholders kept as they are
+type gotextMessages struct {
+	Language string          `json:"language"`
+	Messages []gotextMessage `json:"messages"`
+}
+
+type gotextMessage struct {
+	ID                json.RawMessage `json:"id"`
+	Key               string          `json:"key,omitempty"`
+	Meaning           string          `json:"meaning,omitempty"`
+	Message           json.RawMessage `json:"message"`
+	Translation       json.RawMessage `json:"translation"`
+	Comment           string          `json:"comment,omitempty"`
+	TranslatorComment string          `json:"translatorComment,omitempty"`
+	Placeholders      json.RawMessage `json:"placeholders,omitempty"`
+	Fuzzy             bool            `json:"fuzzy,omitempty"`
+	Position          string          `json:"position,omitempty"`
+}
+
+// gotextText is a text of a message, either a plain one or selecting
+// the cases of a feature (plural) of an argument
+type gotextText struct {
+	Msg     string
+	Feature string
+	Arg     string
+	Cases   []gotextCase
+}
+
+type gotextCase struct {
+	Key string
+	Msg string
+}
+
+func (text *gotextText) isSelect() bool {
+	return len(text.Cases) > 0
+}
+
+// caseMsg returns the text of a case, the one of "other" if there is no
+// such case
+func (text *gotextText) caseMsg(key string) string {
+	if !text.isSelect() {
+		return text.Msg
+	}
+	other := ""
+	for _, c := range text.Cases {
+		if c.Key == key {
+			return c.Msg
+		}
+		if c.Key == "other" {
+			other = c.Msg
+		}
+	}
+	return other
+}
+
+func gotextFromFile(fileName string) (*gotextMessages, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	messages := new(gotextMessages)
+	if err = json.Unmarshal(data, messages); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	return messages, nil
+}
+
+// ids returns the ids of a message, which are a string or a list
+func (msg *gotextMessage) ids() []string {
+	var id string
+	if err := json.Unmarshal(msg.ID, &id); err == nil {
+		return []string{id}
+	}
+	var ids []string
+	json.Unmarshal(msg.ID, &ids)
+	return ids
+}
+
+// readGotextText reads a text as the pipeline writes it: a string, or an
+// object with "msg" or "select"
+func readGotextText(raw json.RawMessage) (*gotextText, error) {
+
+	text := new(gotextText)
+	if len(raw) == 0 || string(raw) == "null" {
+		return text, nil
+	}
+	if err := json.Unmarshal(raw, &text.Msg); err == nil {
+		return text, nil
+	}
+
+	members, err := readJSONMembers(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		switch m.Key {
+		case "msg":
+			if err = json.Unmarshal(m.Value, &text.Msg); err != nil {
+				return nil, err
+			}
+		case "select":
+			var sel struct {
+				Feature string          `json:"feature"`
+				Arg     string          `json:"arg"`
+				Cases   json.RawMessage `json:"cases"`
+			}
+			if err = json.Unmarshal(m.Value, &sel); err != nil {
+				return nil, err
+			}
+			text.Feature, text.Arg = sel.Feature, sel.Arg
+			cases, err := readJSONMembers(bytes.NewReader(sel.Cases))
+			if err != nil {
+				return nil, fmt.Errorf("cases: %s", err)
+			}
+			for _, c := range cases {
+				caseText, err := readGotextText(c.Value)
+				if err != nil {
+					return nil, err
+				}
+				if caseText.isSelect() {
+					return nil, fmt.Errorf("nested selects are not supported")
+				}
+				text.Cases = append(text.Cases, gotextCase{c.Key, caseText.Msg})
+			}
+		default:
+			return nil, fmt.Errorf("unsupported %q", m.Key)
+		}
+	}
+	return text, nil
+}
+
+// json returns text as the pipeline writes it
+func (text *gotextText) json() json.RawMessage {
+	if !text.isSelect() {
+		return jsonString(text.Msg)
+	}
+	var sel []jsonMember
+	if text.Feature != "" {
+		sel = append(sel, jsonMember{"feature", jsonString(text.Feature)})
+	}
+	if text.Arg != "" {
+		sel = append(sel, jsonMember{"arg", jsonString(text.Arg)})
+	}
+	var cases []jsonMember
+	for _, c := range text.Cases {
+		cases = append(cases, jsonMember{c.Key, jsonString(c.Msg)})
+	}
+	buf := bytes.NewBuffer(nil)
+	writeJSONMembers(buf, cases, "")
+	sel = append(sel, jsonMember{"cases", buf.Bytes()})
+
+	buf = bytes.NewBuffer(nil)
+	writeJSONMembers(buf, sel, "")
+	raw := buf.Bytes()
+
+	buf = bytes.NewBuffer(nil)
+	writeJSONMembers(buf, []jsonMember{{"select", raw}}, "")
+	return buf.Bytes()
+}
+
+// metaTo stores the fields of msg but the texts in unit, metaFrom takes
+// them from it
+func (msg *gotextMessage) metaTo(unit *xliffTransUnit) {
+
+	unit.Note = msg.Comment
+	if ids := msg.ids(); len(ids) > 1 {
+		unit.addContext(GOTEXT_CONTEXT_GROUP, "information", GOTEXT_IDS, compactJSON(msg.ID))
+	}
+	for _, c := range []struct{ ctxType, value string }{
+		{GOTEXT_KEY, msg.Key},
+		{GOTEXT_MEANING, msg.Meaning},
+		{GOTEXT_TRANSLATOR_COMMENT, msg.TranslatorComment},
+		{GOTEXT_PLACEHOLDERS, compactJSON(msg.Placeholders)},
+		{GOTEXT_POSITION, msg.Position},
+	} {
+		if c.value != "" {
+			unit.addContext(GOTEXT_CONTEXT_GROUP, "information", c.ctxType, c.value)
+		}
+	}
+}
+
+func (msg *gotextMessage) metaFrom(unit *xliffTransUnit) {
+
+	msg.ID = jsonString(unit.ID)
+	if ids := unit.context(GOTEXT_CONTEXT_GROUP, GOTEXT_IDS); ids != "" {
+		msg.ID = json.RawMessage(ids)
+	}
+	msg.Comment = unit.Note
+	msg.Key = unit.context(GOTEXT_CONTEXT_GROUP, GOTEXT_KEY)
+	msg.Meaning = unit.context(GOTEXT_CONTEXT_GROUP, GOTEXT_MEANING)
+	msg.TranslatorComment = unit.context(GOTEXT_CONTEXT_GROUP, GOTEXT_TRANSLATOR_COMMENT)
+	if placeholders := unit.context(GOTEXT_CONTEXT_GROUP, GOTEXT_PLACEHOLDERS); placeholders != "" {
+		msg.Placeholders = json.RawMessage(placeholders)
+	}
+	msg.Position = unit.context(GOTEXT_CONTEXT_GROUP, GOTEXT_POSITION)
+}
+
+func compactJSON(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.Compact(buf, raw); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// writeGotext writes messages as the pipeline does, indented by four
+// spaces
+func writeGotext(w io.Writer, messages *gotextMessages) error {
+
+	data, err := json.MarshalIndent(messages, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGotextRoundTrip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gotext")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "de.xlf")
+
+	doc := convertXliff(t, &fromGotext{inFile: "testdata/gotext/messages.gotext.json", sourceLang: "en"}, xlf)
+	if file := doc.File[0]; file.TargetLang != "de" {
+		t.Errorf("expected target language de, got %s", file.TargetLang)
+	}
+	checkUnits(t, &doc.File[0].Body, func(unit *xliffTransUnit) string {
+		return unit.Source.Text(INLINE_PLAIN) + " -> " + unitTarget(unit) + " [" + unitState(unit) + "]"
+	}, map[string]string{
+		"Hello {City}!":          "Hello {City}! -> Hallo {City}! [translated]",
+		"msg-quit":               "Quit ->  []",
+		"{N} files <new>[one]":   "{N} file <new> -> {N} Datei &lt;neu&gt; [needs-review-translation]",
+		"{N} files <new>[other]": "{N} files <new> -> {N} Dateien &lt;neu&gt; [needs-review-translation]",
+	})
+
+	checkConverted(t, &toGotext{inFile: xlf}, "testdata/gotext/messages.gotext.json")
+}
+
+func TestReadGotextText(t *testing.T) {
+
+	text, err := readGotextText([]byte(`{"select": {"feature": "plural", "arg": "N", "cases": {"=0": {"msg": "none"}, "other": "{N}"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text.Feature != "plural" || text.Arg != "N" || len(text.Cases) != 2 || text.caseMsg("=0") != "none" || text.caseMsg("few") != "{N}" {
+		t.Errorf("unexpected text %+v", text)
+	}
+
+	if _, err = readGotextText([]byte(`{"var": {"x": "y"}, "msg": "{x}"}`)); err == nil {
+		t.Errorf("expected an error for var")
+	}
+}
+
+func TestGotextTargetCases(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gotext")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	in := filepath.Join(dir, "messages.gotext.json")
+	data := `{"language": "pl", "messages": [
+  {"id": "{N} files", "message": {"select": {"feature": "plural", "arg": "N", "cases": {"one": "{N} file", "other": "{N} files"}}},
+   "translation": {"select": {"feature": "plural", "arg": "N", "cases": {"one": "{N} plik", "few": "{N} pliki", "many": "{N} plików", "other": "{N} pliku"}}}},
+  {"id": "{N} days", "message": "{N} days",
+   "translation": {"select": {"feature": "plural", "arg": "N", "cases": {"one": "{N} dzień", "other": "{N} dni"}}}}
+]}`
+	if err = ioutil.WriteFile(in, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	xlf := filepath.Join(dir, "pl.xlf")
+	convertXliff(t, &fromGotext{inFile: in, sourceLang: "en"}, xlf)
+	written := filepath.Join(dir, "out.gotext.json")
+	if err = ioutil.WriteFile(written, []byte(converted(t, &toGotext{inFile: xlf})), 0644); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := gotextFromFile(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cases of polish only go into the translation, not the message
+	if len(messages.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages.Messages))
+	}
+	for i, expected := range []string{
+		`{"select":{"feature":"plural","arg":"N","cases":{"one":"{N} file","other":"{N} files"}}}`,
+		`"{N} days"`,
+	} {
+		if got := compactJSON(messages.Messages[i].Message); got != expected {
+			t.Errorf("expected the message %s, got %s", expected, got)
+		}
+	}
+	if translation := compactJSON(messages.Messages[0].Translation); !strings.Contains(translation, `"many":"{N} plików"`) {
+		t.Errorf("expected the case many in the translation, got %s", translation)
+	}
+}
@@ -0,0 +1,456 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// the localized strings of iOS and macOS are kept in <lang>.lproj
+// directories, simple ones in .strings files (UTF-16 or UTF-8):
+//
+//	/* comment */
+//	"key" = "value";
+//
+// plurals in .stringsdict files, property lists holding a format per key
+// whose variables are expanded by plural rules:
+//
+//	<key>files</key>
+//	<dict>
+//		<key>NSStringLocalizedFormatKey</key>
+//		<string>%#@count@</string>
+//		<key>count</key>
+//		<dict>
+//			<key>NSStringFormatSpecTypeKey</key>
+//			<string>NSStringPluralRuleType</string>
+//			<key>NSStringFormatValueTypeKey</key>
+//			<string>d</string>
+//			<key>one</key>
+//			<string>%d file</string>
+//			<key>other</key>
+//			<string>%d files</string>
+//		</dict>
+//	</dict>
+//
+// a .strings becomes a <file> with a trans-unit per key and the comment as
+// note. an entry of a .stringsdict becomes a <group> with a trans-unit
+// for the format (id "files[format]") and one per variable and plural
+// form (id "files[count:one]", resname "count:one"), the value type kept
+// in the context.
+const (
+	IOS_STRINGSDICT_RESTYPE = "x-ios-stringsdict"
+	IOS_CONTEXT_GROUP       = "ios-stringsdict"
+	IOS_VALUE_TYPE          = "x-ios-format-value-type"
+
+	IOS_FORMAT_KEY     = "NSStringLocalizedFormatKey"
+	IOS_SPEC_TYPE_KEY  = "NSStringFormatSpecTypeKey"
+	IOS_VALUE_TYPE_KEY = "NSStringFormatValueTypeKey"
+	IOS_PLURAL_RULE    = "NSStringPluralRuleType"
+)
+
+type iosEntry struct {
+	Key     string
+	Value   string
+	Comment string
+}
+
+// isStringsdict reports if fileName is a .stringsdict
+func isStringsdict(fileName string) bool {
+	return strings.HasSuffix(fileName, ".stringsdict")
+}
+
+// iosLproj returns the .lproj directory of lang
+func iosLproj(lang string) string {
+	return strings.Replace(lang, "_", "-", -1) + ".lproj"
+}
+
+// decodeText decodes a text file in UTF-8 or, if it starts with a byte
+// order mark, in UTF-16
+func decodeText(data []byte) string {
+
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		order = binary.BigEndian
+	default:
+		return strings.TrimPrefix(string(data), "\ufeff")
+	}
+
+	units := make([]uint16, (len(data)-2)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2+2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// encodeUTF16 encodes text as UTF-16 (little endian) with byte order mark
+func encodeUTF16(text string) []byte {
+	units := utf16.Encode([]rune(text))
+	data := make([]byte, 2+2*len(units))
+	data[0], data[1] = 0xff, 0xfe
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(data[2+2*i:], u)
+	}
+	return data
+}
+
+// iosStringsReader reads the entries of a .strings
+type iosStringsReader struct {
+	text string
+	pos  int
+	line int
+}
+
+func readIOSStrings(data []byte) ([]iosEntry, error) {
+
+	r := &iosStringsReader{text: decodeText(data), line: 1}
+	var entries []iosEntry
+	comment := ""
+
+	for {
+		r.skipSpace()
+		switch {
+		case r.pos >= len(r.text):
+			return entries, nil
+		case strings.HasPrefix(r.text[r.pos:], "/*"):
+			end := strings.Index(r.text[r.pos+2:], "*/")
+			if end < 0 {
+				return nil, r.errorf("unterminated comment")
+			}
+			comment = r.comment(r.text[r.pos+2 : r.pos+2+end])
+			r.advance(end + 4)
+			continue
+		case strings.HasPrefix(r.text[r.pos:], "//"):
+			end := strings.IndexByte(r.text[r.pos:], '\n')
+			if end < 0 {
+				end = len(r.text) - r.pos
+			}
+			comment = r.comment(r.text[r.pos+2 : r.pos+end])
+			r.advance(end)
+			continue
+		}
+
+		key, err := r.token()
+		if err != nil {
+			return nil, err
+		}
+		e := iosEntry{Key: key, Value: key, Comment: comment}
+		comment = ""
+
+		r.skipSpace()
+		if r.pos < len(r.text) && r.text[r.pos] == '=' {
+			r.advance(1)
+			r.skipSpace()
+			if e.Value, err = r.token(); err != nil {
+				return nil, err
+			}
+			r.skipSpace()
+		}
+		if r.pos >= len(r.text) || r.text[r.pos] != ';' {
+			return nil, r.errorf("missing ';' after %q", e.Key)
+		}
+		r.advance(1)
+		entries = append(entries, e)
+	}
+}
+
+func (r *iosStringsReader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", r.line, fmt.Sprintf(format, args...))
+}
+
+func (r *iosStringsReader) advance(n int) {
+	r.line += strings.Count(r.text[r.pos:r.pos+n], "\n")
+	r.pos += n
+}
+
+func (r *iosStringsReader) skipSpace() {
+	n := len(r.text[r.pos:]) - len(strings.TrimLeft(r.text[r.pos:], " \t\r\n"))
+	r.advance(n)
+}
+
+// comment returns the text of a comment. the one genstrings writes if
+// there is none is dropped.
+func (r *iosStringsReader) comment(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "No comment provided by engineer." {
+		return ""
+	}
+	return text
+}
+
+// token reads a quoted string or an unquoted word
+func (r *iosStringsReader) token() (string, error) {
+
+	if r.pos >= len(r.text) {
+		return "", r.errorf("unexpected end of file")
+	}
+	if r.text[r.pos] != '"' {
+		end := strings.IndexFunc(r.text[r.pos:], func(c rune) bool {
+			return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_$:./-", c))
+		})
+		if end == 0 {
+			return "", r.errorf("unexpected %q", r.text[r.pos:r.pos+1])
+		}
+		if end < 0 {
+			end = len(r.text) - r.pos
+		}
+		word := r.text[r.pos : r.pos+end]
+		r.advance(end)
+		return word, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for i := r.pos + 1; i < len(r.text); i++ {
+		switch c := r.text[i]; c {
+		case '"':
+			r.advance(i + 1 - r.pos)
+			return buf.String(), nil
+		case '\\':
+			i++
+			if i >= len(r.text) {
+				break
+			}
+			switch e := r.text[i]; e {
+			case 'n':
+				buf.WriteByte('\n')
+			case 't':
+				buf.WriteByte('\t')
+			case 'r':
+				buf.WriteByte('\r')
+			case 'U', 'u':
+				if i+5 <= len(r.text) {
+					if v, err := strconv.ParseUint(r.text[i+1:i+5], 16, 32); err == nil {
+						buf.WriteRune(rune(v))
+						i += 4
+						continue
+					}
+				}
+				buf.WriteByte(e)
+			default:
+				buf.WriteByte(e)
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", r.errorf("unterminated string")
+}
+
+// iosQuote quotes s for a .strings
+func iosQuote(s string) string {
+	buf := bytes.NewBufferString(`"`)
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteString(`"`)
+	return buf.String()
+}
+
+// writeIOSStrings writes entries as .strings, in UTF-8 or UTF-16
+func writeIOSStrings(w io.Writer, entries []iosEntry, asUTF16 bool) error {
+
+	buf := bytes.NewBuffer(nil)
+	for i, e := range entries {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		if e.Comment != "" {
+			buf.WriteString("/* " + strings.Replace(e.Comment, "*/", "* /", -1) + " */\n")
+		}
+		buf.WriteString(iosQuote(e.Key) + " = " + iosQuote(e.Value) + ";\n")
+	}
+
+	data := buf.Bytes()
+	if asUTF16 {
+		data = encodeUTF16(buf.String())
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// iosPlural is an entry of a .stringsdict
+type iosPlural struct {
+	Key    string
+	Format string
+	Vars   []iosPluralVar
+}
+
+// iosPluralVar is a variable of the format of an iosPlural, the plural
+// forms by quantity
+type iosPluralVar struct {
+	Name      string
+	ValueType string
+	Forms     map[string]string
+}
+
+// plistDict is a <dict> of a property list, values are strings or
+// *plistDict
+type plistDict struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func readStringsdict(r io.Reader) ([]iosPlural, error) {
+
+	d := xml.NewDecoder(r)
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "dict" {
+			root, err := readPlistDict(d)
+			if err != nil {
+				return nil, err
+			}
+			return iosPlurals(root), nil
+		}
+	}
+}
+
+// readPlistDict reads the keys and values of a <dict> up to its end.
+// values other than <string> and <dict> are skipped.
+func readPlistDict(d *xml.Decoder) (*plistDict, error) {
+
+	dict := &plistDict{values: map[string]interface{}{}}
+	key := ""
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			return dict, nil
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "key", "string":
+				var text string
+				if err = d.DecodeElement(&text, &t); err != nil {
+					return nil, err
+				}
+				if t.Name.Local == "key" {
+					key = text
+					continue
+				}
+				dict.set(key, text)
+			case "dict":
+				inner, err := readPlistDict(d)
+				if err != nil {
+					return nil, err
+				}
+				dict.set(key, inner)
+			default:
+				if err = d.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+}
+
+func (dict *plistDict) set(key string, value interface{}) {
+	if _, exists := dict.values[key]; !exists {
+		dict.keys = append(dict.keys, key)
+	}
+	dict.values[key] = value
+}
+
+func (dict *plistDict) str(key string) string {
+	s, _ := dict.values[key].(string)
+	return s
+}
+
+// iosPlurals interprets the root <dict> of a .stringsdict. variables
+// with other rules than plural rules are left out.
+func iosPlurals(root *plistDict) []iosPlural {
+
+	var plurals []iosPlural
+	for _, key := range root.keys {
+		entry, ok := root.values[key].(*plistDict)
+		if !ok {
+			continue
+		}
+		p := iosPlural{Key: key, Format: entry.str(IOS_FORMAT_KEY)}
+		for _, name := range entry.keys {
+			v, ok := entry.values[name].(*plistDict)
+			if !ok {
+				continue
+			}
+			if spec := v.str(IOS_SPEC_TYPE_KEY); spec != IOS_PLURAL_RULE {
+				log.Printf("warning: %s: unsupported %s %q", key, IOS_SPEC_TYPE_KEY, spec)
+				continue
+			}
+			pv := iosPluralVar{Name: name, ValueType: v.str(IOS_VALUE_TYPE_KEY), Forms: map[string]string{}}
+			for _, quantity := range pluralQuantities {
+				if form, ok := v.values[quantity].(string); ok {
+					pv.Forms[quantity] = form
+				}
+			}
+			p.Vars = append(p.Vars, pv)
+		}
+		plurals = append(plurals, p)
+	}
+	return plurals
+}
+
+// writeStringsdict writes plurals as .stringsdict, the way Xcode does
+func writeStringsdict(w io.Writer, plurals []iosPlural) error {
+
+	buf := bytes.NewBufferString(xml.Header)
+	buf.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	buf.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+
+	pair := func(indent, key, value string) {
+		buf.WriteString(indent + "<key>" + escapedText(key) + "</key>\n")
+		buf.WriteString(indent + "<string>" + escapedText(value) + "</string>\n")
+	}
+	for _, p := range plurals {
+		buf.WriteString("\t<key>" + escapedText(p.Key) + "</key>\n\t<dict>\n")
+		pair("\t\t", IOS_FORMAT_KEY, p.Format)
+		for _, v := range p.Vars {
+			buf.WriteString("\t\t<key>" + escapedText(v.Name) + "</key>\n\t\t<dict>\n")
+			pair("\t\t\t", IOS_SPEC_TYPE_KEY, IOS_PLURAL_RULE)
+			pair("\t\t\t", IOS_VALUE_TYPE_KEY, v.ValueType)
+			for _, quantity := range pluralQuantities {
+				if form, ok := v.Forms[quantity]; ok {
+					pair("\t\t\t", quantity, form)
+				}
+			}
+			buf.WriteString("\t\t</dict>\n")
+		}
+		buf.WriteString("\t</dict>\n")
+	}
+	buf.WriteString("</dict>\n</plist>\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
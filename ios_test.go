@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIOSStringsRoundTrip(t *testing.T) {
+
+	fi := &fromIOSStrings{
+		inFiles:    []string{"testdata/ios/en.lproj/Localizable.strings", "testdata/ios/en.lproj/Localizable.stringsdict"},
+		sourceLang: "en",
+		targetLang: "de",
+	}
+	out := bytes.NewBuffer(nil)
+	if err := fi.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "ios")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "de.xlf")
+	if err = ioutil.WriteFile(xlf, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ti := &toIOSStrings{inFiles: []string{xlf}, outDir: dir, source: true, utf16: true}
+	if err = ti.Convert(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lang := range []string{"en", "de"} {
+		name := filepath.Join(lang+".lproj", "Localizable.strings")
+		original, err := ioutil.ReadFile(filepath.Join("testdata/ios", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		written, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(written, []byte{0xff, 0xfe}) {
+			t.Errorf("%s: expected UTF-16 with byte order mark", name)
+		}
+		expected, err := readIOSStrings(original)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := readIOSStrings(written)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected\n%v\ngot\n%v", name, expected, got)
+		}
+
+		name = filepath.Join(lang+".lproj", "Localizable.stringsdict")
+		original, err = ioutil.ReadFile(filepath.Join("testdata/ios", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		written, err = ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(written) != string(original) {
+			t.Errorf("%s: expected\n%s\ngot\n%s", name, original, written)
+		}
+	}
+}
+
+func TestStringsdictPluralForms(t *testing.T) {
+
+	// Polish needs "few" and "many", which English lacks
+	fi := &fromIOSStrings{inFiles: []string{"testdata/ios/en.lproj/Localizable.stringsdict"}, sourceLang: "en", targetLang: "pl"}
+	out := bytes.NewBuffer(nil)
+	if err := fi.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := xliffFromReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"notes_count[format]":      "%#@notes@ = %#@notes@",
+		"notes_count[notes:one]":   "%d note = %d notatka",
+		"notes_count[notes:few]":   "%d notes = %d notatki",
+		"notes_count[notes:many]":  "%d notes = %d notatek",
+		"notes_count[notes:other]": "%d notes = %d notatki",
+	}
+	units := doc.File[0].Body.Units()
+	if len(units) != len(expected) {
+		t.Fatalf("expected %d units, got %d", len(expected), len(units))
+	}
+	for _, unit := range units {
+		if got := unit.Source.Inner + " = " + unitTarget(unit); got != expected[unit.ID] {
+			t.Errorf("%s: expected %q, got %q", unit.ID, expected[unit.ID], got)
+		}
+	}
+
+	// the sources keep the forms of English
+	dir, err := ioutil.TempDir("", "ios")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "pl.xlf")
+	buf := bytes.NewBuffer(nil)
+	if err = writeXliff(buf, doc, "  "); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(xlf, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ti := &toIOSStrings{inFiles: []string{xlf}, outDir: dir, source: true}
+	if err = ti.Convert(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	for lang, forms := range map[string]string{"en": "one,other", "pl": "one,few,many,other"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, lang+".lproj", "Localizable.stringsdict"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plurals, err := readStringsdict(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, quantity := range pluralQuantities {
+			if _, ok := plurals[0].Vars[0].Forms[quantity]; ok {
+				got = append(got, quantity)
+			}
+		}
+		if strings.Join(got, ",") != forms {
+			t.Errorf("%s: expected the forms %s, got %v", lang, forms, got)
+		}
+	}
+}
+
+func TestReadIOSStrings(t *testing.T) {
+
+	entries, err := readIOSStrings([]byte("// unquoted\nkey = value;\n\"only key\";\n\"tab\" = \"a\\tb\";"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []iosEntry{{"key", "value", "unquoted"}, {"only key", "only key", ""}, {"tab", "a\tb", ""}}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %v, got %v", expected, entries)
+	}
+
+	if _, err = readIOSStrings([]byte("\"a\" = \"b\"\n\"c\" = \"d\";")); err == nil || err.Error() != `line 2: missing ';' after "a"` {
+		t.Errorf("expected an error for the missing ';', got %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFromJSONMerge(t *testing.T) {
+
+	fj := &fromJSON{
+		inFile:     "testdata/json/angular.en.json",
+		targetFile: "testdata/json/angular.fr.json",
+		mergeFile:  "testdata/angular.xlf",
+		groupSep:   ".",
+		inline:     INLINE_PLAIN,
+	}
+	out := bytes.NewBuffer(nil)
+	if err := fj.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, part := range []string{
+		// unchanged, kept as it is
+		`<target state="translated">Bonjour i18n !</target>`,
+		`<note priority="1" from="meaning">User welcome</note>`,
+		// changed, the state is kept
+		`<source>Logo of Heroes</source>
+        <target state="final">Logo des héros</target>`,
+		// new
+		`<trans-unit id="footer.copyright">
+        <source xml:lang="en">(c) the heroes</source>
+        <target xml:lang="fr">(c) les héros</target>
+      </trans-unit>`,
+	} {
+		if !strings.Contains(out.String(), part) {
+			t.Errorf("expected %q in\n%s", part, out)
+		}
+	}
+}
+
+func TestFromJSONInlineXML(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, "en.json"), filepath.Join(dir, "de.json")
+	if err = ioutil.WriteFile(source, []byte(`{"a": "Click <g id=\"1\">here</g>", "b": "1 < 2"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(target, []byte(`{"a": "Klicke <g id=\"1\">hier</g>"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fj := &fromJSON{inFile: source, targetFile: target, groupSep: ".", inline: INLINE_XML}
+	out := bytes.NewBuffer(nil)
+	if err := fj.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{
+		`<source xml:lang="en">Click <g id="1">here</g></source>`,
+		`<target>Klicke <g id="1">hier</g></target>`,
+		// not XML, taken as text
+		`<source xml:lang="en">1 &lt; 2</source>`,
+	} {
+		if !strings.Contains(out.String(), part) {
+			t.Errorf("expected %q in\n%s", part, out)
+		}
+	}
+}
+
+func TestReadJSONEntries(t *testing.T) {
+
+	entries, err := readJSONEntries(strings.NewReader(`{"b": "1", "a": {"z": 2, "y": [true, null, "x"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, strings.Join(entry.path, "/")+"="+entry.value)
+	}
+	expected := "b=1 a/z=2 a/y/0=true a/y/2=x"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("expected %q, got %q", expected, strings.Join(got, " "))
+	}
+}
+
+func TestNestKeyValues(t *testing.T) {
+
+	out, err := marshalKeyValues(map[string]string{
+		"b":       "1",
+		"a.y":     "2",
+		"a.x.z":   "3",
+		"a::flat": "4",
+	}, false, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"a":{"x":{"z":"3"},"y":"2"},"a::flat":"4","b":"1"}`
+	if string(out) != expected {
+		t.Errorf("expected %s, got %s", expected, out)
+	}
+
+	for _, conflict := range []map[string]string{
+		{"a": "1", "a.b": "2"},
+		{"a.b": "1", "a.b.c": "2"},
+	} {
+		if _, err = nestKeyValues(conflict, "."); err == nil {
+			t.Errorf("expected a conflict for %v", conflict)
+		}
+	}
+}
+
+// the reverse of -key-to is ambiguous if the ids hold it already
+func TestFromJSONKeyBack(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source, xlf := filepath.Join(dir, "en.json"), filepath.Join(dir, "app.xlf")
+	if err = ioutil.WriteFile(source, []byte(`{"menu_open": "Open", "menu_save": "Save", "menu.save": "Save"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := bytes.NewBuffer(nil)
+	log.SetOutput(report)
+	defer log.SetOutput(os.Stderr)
+
+	fj := &fromJSON{inFile: source, groupSep: "/", inline: INLINE_PLAIN, keyMatch: `\.`, keyTo: "_"}
+	out := bytes.NewBuffer(nil)
+	if err = fj.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `<trans-unit id="menu.open">`) {
+		t.Errorf("expected the key to be reversed in\n%s", out)
+	}
+	if !strings.Contains(report.String(), `keys "menu_save" and "menu.save" both become the id "menu.save"`) {
+		t.Errorf("expected a warning about the same ids, got %q", report)
+	}
+
+	// an id holding "_" already
+	doc := newXliffDoc("app", "en")
+	doc.File[0].Body.TransUnit = append(doc.File[0].Body.TransUnit, xliffTransUnit{ID: "menu_open", Source: xliffSource{Inner: "Open"}})
+	buf := bytes.NewBuffer(nil)
+	if err = writeXliff(buf, doc, "  "); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(xlf, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report.Reset()
+	fj.mergeFile = xlf
+	out.Reset()
+	if err = fj.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `<trans-unit id="menu_save">`) {
+		t.Errorf("expected the key to be kept in\n%s", out)
+	}
+	if !strings.Contains(report.String(), `id "menu_open" holds the -key-to "_" already`) {
+		t.Errorf("expected a warning about the ambiguous keys, got %q", report)
+	}
+}
@@ -0,0 +1,115 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"strings"
+)
+
+// sameLang compares language codes, ignoring case and "_" vs. "-"
+func sameLang(a, b string) bool {
+	return normLang(a) == normLang(b)
+}
+
+func normLang(lang string) string {
+	return strings.ToLower(strings.Replace(lang, "_", "-", -1))
+}
+
+// the plural forms (CLDR categories) in the order they are written
+var pluralQuantities = []string{"zero", "one", "two", "few", "many", "other"}
+
+// a form the target language has beyond the ones of the source language
+// (few, many, ...) is translated from "other". its units are marked by a
+// <context>, so that they are not written as sources.
+const (
+	PLURAL_CONTEXT_GROUP = "plural"
+	PLURAL_SOURCE_FORM   = "x-plural-source-form"
+)
+
+// pluralForm is a form of a plural message and the form of the source
+// language it is translated from
+type pluralForm struct {
+	Quantity string
+	Source   string
+}
+
+// pluralForms returns the forms of a message in the order they are
+// written: the ones of the source and the ones the target has beyond
+// them, taken from "other" (or the last form if there is no "other")
+func pluralForms(source, target []string) []pluralForm {
+
+	inSource := map[string]bool{}
+	for _, quantity := range source {
+		inSource[quantity] = true
+	}
+	inTarget := map[string]bool{}
+	for _, quantity := range target {
+		inTarget[quantity] = true
+	}
+	other := "other"
+	if !inSource[other] && len(source) > 0 {
+		other = source[len(source)-1]
+	}
+
+	var forms []pluralForm
+	for _, quantity := range pluralQuantities {
+		switch {
+		case inSource[quantity]:
+			forms = append(forms, pluralForm{quantity, quantity})
+		case inTarget[quantity] && len(source) > 0:
+			forms = append(forms, pluralForm{quantity, other})
+		}
+	}
+	for _, quantity := range source {
+		if !isQuantity(quantity) {
+			forms = append(forms, pluralForm{quantity, quantity})
+		}
+	}
+	return forms
+}
+
+// pluralQuantitiesOf returns the quantities of the forms of a message
+func pluralQuantitiesOf(forms map[string]string) []string {
+	quantities := make([]string, 0, len(forms))
+	for quantity := range forms {
+		quantities = append(quantities, quantity)
+	}
+	return quantities
+}
+
+// mark marks unit as translated from another form, if it is
+func (form pluralForm) mark(unit *xliffTransUnit) {
+	if form.Source != form.Quantity {
+		unit.addContext(PLURAL_CONTEXT_GROUP, "information", PLURAL_SOURCE_FORM, form.Source)
+	}
+}
+
+// isTargetForm tells whether unit is a form of the target language only
+func isTargetForm(unit *xliffTransUnit) bool {
+	return unit.context(PLURAL_CONTEXT_GROUP, PLURAL_SOURCE_FORM) != ""
+}
+
+// langLocale returns the locale a language is written as by ARB, Qt, ...
+// ("pt-BR" -> "pt_BR"), localeLang the other way around
+func langLocale(lang string) string {
+	return strings.Replace(lang, "-", "_", -1)
+}
+
+func localeLang(locale string) string {
+	return strings.Replace(locale, "_", "-", -1)
+}
+
+func isQuantity(key string) bool {
+	for _, quantity := range pluralQuantities {
+		if key == quantity {
+			return true
+		}
+	}
+	return false
+}
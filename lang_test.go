@@ -0,0 +1,14 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLangLocale(t *testing.T) {
+	if locale := langLocale("pt-BR"); locale != "pt_BR" {
+		t.Errorf("expected pt_BR, got %s", locale)
+	}
+	if lang := localeLang("zh_Hant_TW"); lang != "zh-Hant-TW" {
+		t.Errorf("expected zh-Hant-TW, got %s", lang)
+	}
+}
@@ -0,0 +1,252 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// leverage fills the empty targets of a XLIFF from a translation memory
+// (see tm.go). exact matches are taken as they are, the best fuzzy match
+// is taken as a translation which needs a review; the fuzzy matches are
+// added as <alt-trans> suggestions.
+type leverage struct {
+	inFile      string
+	tmFile      string
+	threshold   int
+	suggestions int
+	reportOnly  bool
+	version     string
+}
+
+// the categories of the leverage report
+const (
+	LEVERAGE_TRANSLATED = "translated" // translated already
+	LEVERAGE_EXACT      = "exact"
+	LEVERAGE_FUZZY      = "fuzzy"
+	LEVERAGE_NEW        = "new"
+)
+
+var leverageCategories = []string{LEVERAGE_TRANSLATED, LEVERAGE_EXACT, LEVERAGE_FUZZY, LEVERAGE_NEW}
+
+func init() {
+	registeredConverters["leverage"] = new(leverage)
+}
+
+func (l *leverage) Description() string {
+	return "Fills empty targets from a translation memory"
+}
+
+func (l *leverage) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" leverage", flag.ExitOnError)
+	fs.StringVar(&l.inFile, "in", "", "infile")
+	fs.StringVar(&l.tmFile, "tm", "", "translation memory (see tm-import)")
+	fs.IntVar(&l.threshold, "threshold", 75, "minimal similarity of fuzzy matches (percent)")
+	fs.IntVar(&l.suggestions, "suggestions", 3, "maximal number of fuzzy matches added as <alt-trans>")
+	fs.BoolVar(&l.reportOnly, "report-only", false, "write the report instead of the XLIFF (and change nothing)")
+	xliffVersionFlag(fs, &l.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if l.threshold < 1 || l.threshold > 100 {
+		return fmt.Errorf("unsupported 'threshold': %d", l.threshold)
+	}
+	if l.tmFile == "" {
+		return fmt.Errorf("missing -tm")
+	}
+	return nil
+}
+
+func (l *leverage) Prepare() error {
+	return nil
+}
+
+// leverageCount is a line of the report
+type leverageCount struct {
+	units int
+	words int
+}
+
+// Convert writes the XLIFF and the report to stderr, with -report-only
+// just the report
+func (l *leverage) Convert(w io.Writer) error {
+
+	tm, err := loadTM(l.tmFile)
+	if err != nil {
+		return err
+	}
+	doc, err := xliffFromFile(l.inFile)
+	if err != nil {
+		return err
+	}
+
+	// counts by target language and category
+	report := map[string]map[string]*leverageCount{}
+
+	for i := range doc.File {
+		file := &doc.File[i]
+		counts := report[file.TargetLang]
+		if counts == nil {
+			counts = map[string]*leverageCount{}
+			for _, category := range leverageCategories {
+				counts[category] = new(leverageCount)
+			}
+			report[file.TargetLang] = counts
+		}
+
+		for _, unit := range file.Body.Units() {
+			if attrValue(unit.Attrs, "translate") == "no" {
+				continue
+			}
+			category := l.leverage(tm, file, unit)
+			counts[category].units++
+			counts[category].words += countWords(unit.Source.Text(INLINE_TEXT_ONLY))
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	writeLeverageReport(buf, report)
+	if l.reportOnly {
+		_, err = w.Write(buf.Bytes())
+		return err
+	}
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			log.Print(line)
+		}
+	}
+
+	if err = doc.SetVersion(l.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
+
+// leverage fills the target of unit if it is empty and returns the
+// category of the unit
+func (l *leverage) leverage(tm *transMemory, file *xliffFile, unit *xliffTransUnit) string {
+
+	if unit.Target != nil && strings.TrimSpace(unit.Target.Inner) != "" {
+		return LEVERAGE_TRANSLATED
+	}
+
+	if e := tm.exact(file.SourceLang, file.TargetLang, unit.Source.Text(INLINE_XML)); e != nil {
+		if !l.reportOnly {
+			l.setTarget(unit, file.TargetLang, e.Target, "translated")
+		}
+		return LEVERAGE_EXACT
+	}
+
+	matches := tm.fuzzy(file.SourceLang, file.TargetLang, unit.Source.Text(INLINE_TEXT_ONLY), l.threshold, l.suggestions)
+	if len(matches) == 0 {
+		return LEVERAGE_NEW
+	}
+	if l.reportOnly {
+		return LEVERAGE_FUZZY
+	}
+
+	l.setTarget(unit, file.TargetLang, matches[0].entry.Target, STATE_NEEDS_REVIEW)
+	for _, m := range matches {
+		alt := xliffAltTrans{
+			MatchQuality: fmt.Sprint(m.similarity),
+			Origin:       m.entry.Origin,
+			Source:       &xliffSource{Lang: file.SourceLang},
+			Target:       xliffTarget{Lang: file.TargetLang},
+		}
+		setContent(alt.Source, m.entry.Source)
+		setContent((*xliffSource)(&alt.Target), m.entry.Target)
+		unit.AltTrans = append(unit.AltTrans, alt)
+	}
+	return LEVERAGE_FUZZY
+}
+
+func (l *leverage) setTarget(unit *xliffTransUnit, lang, text, state string) {
+	if unit.Target == nil {
+		unit.Target = &xliffTarget{Lang: lang}
+	}
+	setContent((*xliffSource)(unit.Target), text)
+	unit.Target.State = state
+}
+
+// setContent sets the content of src from text with inline elements (as
+// rendered by INLINE_XML)
+func setContent(src *xliffSource, text string) {
+	content, err := parseContent(text)
+	if err != nil {
+		src.Inner, src.Content = text, nil
+		return
+	}
+	src.Content, src.Inner = content, content.Plain()
+}
+
+func writeLeverageReport(buf *bytes.Buffer, report map[string]map[string]*leverageCount) {
+
+	langs := make([]string, 0, len(report))
+	for lang := range report {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	for _, lang := range langs {
+		counts := report[lang]
+		total := leverageCount{}
+		for _, c := range counts {
+			total.units += c.units
+			total.words += c.words
+		}
+		if lang == "" {
+			lang = "(no target-language)"
+		}
+		fmt.Fprintf(buf, "%s: %d units, %d words\n", lang, total.units, total.words)
+		for _, category := range leverageCategories {
+			c := counts[category]
+			fmt.Fprintf(buf, "  %-10s %6d units %7d words %5.1f%%\n", category, c.units, c.words, percent(c.words, total.words))
+		}
+	}
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// countWords counts the words of text. in scripts without spaces between
+// words (Chinese, Japanese, Thai, ...) every character counts as word.
+func countWords(text string) int {
+	words := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Thai, r):
+			words++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		case r == '\'' || r == '’' || r == '-':
+			// part of a word: don't, e-mail
+		default:
+			inWord = false
+		}
+	}
+	return words
+}
@@ -43,17 +43,17 @@ func main() {
 	if conv, exist = registeredConverters[args[0]]; !exist {
 		fmt.Fprintf(os.Stderr, "error: unknown converter %v\n", args[0])
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(2)
 	}
 
 	if err = conv.ParseArgs(os.Args[0], args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "error: parsing %v\n", err)
-		os.Exit(1)
+		os.Exit(2)
 	}
 
 	if err = conv.Prepare(); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		os.Exit(2)
 	}
 
 	var outWriter = os.Stdout
@@ -62,14 +62,20 @@ func main() {
 		if err2 != nil {
 			fmt.Fprintf(os.Stderr, "error: can't create %q: %v\n",
 				*outFileName, err2)
-			os.Exit(1)
+			os.Exit(2)
 		}
 		defer outFile.Close()
 		outWriter = outFile
 	}
 
 	if err := conv.Convert(outWriter); err != nil {
+		outWriter.Close()
+		switch err {
+		case errDiffer, errIssues, errConflicts:
+			os.Exit(1)
+		}
 		fmt.Fprintf(os.Stderr, "error: converting %v\n", err)
+		os.Exit(2)
 	}
 }
 
@@ -92,7 +98,8 @@ func usage() {
 	sort.Strings(converters)
 	format := fmt.Sprintf("  %%-%ds - %%s\n", longest)
 
-	fmt.Println("Available converters:\n")
+	fmt.Println("Available converters:")
+	fmt.Println()
 	for _, c := range converters {
 		fmt.Printf(format, c, registeredConverters[c].Description())
 	}
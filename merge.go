@@ -9,31 +9,61 @@
 package main
 
 import (
-	"encoding/xml"
 	"flag"
 	"fmt"
 	"io"
+	"log"
 )
 
-// mergeConv is converter which merges 2 .xliff files
+// mergeConv is converter which merges .xliff files. units are matched
+// by the original of their <file> and their id: files with the same
+// original are combined into one, units which exist in more than one
+// input are resolved according to the strategy. everything else is
+// appended.
 type mergeConv struct {
-	aFile string
-	bFile string
+	aFile    string
+	bFile    string
+	inFiles  []string // further inputs, given as arguments
+	strategy string
+	version  string
 }
 
+// the strategies to resolve units which differ. "a" is the first input
+// which holds the unit, "b" any later input.
+const (
+	MERGE_PREFER_A         = "prefer-a"
+	MERGE_PREFER_B         = "prefer-b"
+	MERGE_PREFER_NEWER     = "prefer-newer-state"
+	MERGE_PREFER_NON_EMPTY = "prefer-non-empty-target"
+	MERGE_FAIL             = "fail-on-conflict"
+)
+
 func init() {
 	registeredConverters["merge"] = new(mergeConv)
 }
 
 func (m *mergeConv) Description() string {
-	return "Merge two XLIFF files"
+	return "Merge XLIFF files"
 }
 
 func (m *mergeConv) ParseArgs(base string, args []string) error {
 	var fs = flag.NewFlagSet(base+" merge", flag.ExitOnError)
 	fs.StringVar(&m.aFile, "a", "", "a file")
-	fs.StringVar(&m.bFile, "b", "", "b file")
-	return fs.Parse(args)
+	fs.StringVar(&m.bFile, "b", "", "b file (more files can follow as arguments)")
+	fs.StringVar(&m.strategy, "strategy", MERGE_PREFER_A, "resolve conflicts: "+
+		MERGE_PREFER_A+", "+MERGE_PREFER_B+", "+MERGE_PREFER_NEWER+", "+MERGE_PREFER_NON_EMPTY+", "+MERGE_FAIL)
+	xliffVersionFlag(fs, &m.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	m.inFiles = fs.Args()
+
+	switch m.strategy {
+	case MERGE_PREFER_A, MERGE_PREFER_B, MERGE_PREFER_NEWER, MERGE_PREFER_NON_EMPTY, MERGE_FAIL:
+	default:
+		return fmt.Errorf("unsupported 'strategy': %q", m.strategy)
+	}
+	return nil
 }
 
 func (m *mergeConv) Prepare() error {
@@ -42,30 +72,181 @@ func (m *mergeConv) Prepare() error {
 
 func (m *mergeConv) Convert(w io.Writer) error {
 
-	var (
-		err  error
-		aDoc *xliffDoc
-		bDoc *xliffDoc
-		out  []byte
-	)
-
-	if aDoc, err = xliffFromFile(m.aFile); err != nil {
-		return fmt.Errorf("%s: %s", m.aFile, err)
+	var names []string
+	for _, name := range append([]string{m.aFile, m.bFile}, m.inFiles...) {
+		if name != "" {
+			names = append(names, name)
+		}
 	}
-	if bDoc, err = xliffFromFile(m.bFile); err != nil {
-		return fmt.Errorf("%s: %s", m.bFile, err)
+	if len(names) < 2 {
+		return fmt.Errorf("merge needs at least two files")
+	}
+
+	docs := make([]*xliffDoc, len(names))
+	for i, name := range names {
+		doc, err := xliffFromFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		docs[i] = doc
 	}
 
-	// TODO: check uniqueness of keys|translation unit ids?
+	// the first document is the base, so that it is written back with
+	// all the details the typed model does not know about
+	result := docs[0]
+	origin := map[mergeKey]string{}
+	conflicts := 0
+	for i, doc := range docs[1:] {
+		for j := range doc.File {
+			file := &doc.File[j]
+			into := findFile(result, file.Original)
+			if into == nil {
+				file.Body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+					origin[mergeKey{file.Original, unit.ID}] = names[i+1]
+				})
+				result.File = append(result.File, *file)
+				continue
+			}
+			if into.SourceLang != file.SourceLang || into.TargetLang != file.TargetLang {
+				log.Printf("warning: %q has different languages in %s and %s", file.Original, names[0], names[i+1])
+			}
+			conflicts += m.mergeFile(into, file, names[0], names[i+1], origin)
+		}
+	}
 
-	aDoc.File = append(aDoc.File, bDoc.File...)
+	if conflicts > 0 && m.strategy == MERGE_FAIL {
+		return fmt.Errorf("failed on %d conflicts", conflicts)
+	}
 
-	if out, err = xml.MarshalIndent(aDoc, "", "  "); err != nil {
+	if err := result.SetVersion(m.version); err != nil {
 		return err
 	}
 
-	io.WriteString(w, xml.Header)
-	w.Write(out)
+	return writeXliff(w, result, "  ")
+}
 
+func findFile(doc *xliffDoc, original string) *xliffFile {
+	for i := range doc.File {
+		if doc.File[i].Original == original {
+			return &doc.File[i]
+		}
+	}
 	return nil
 }
+
+// mergeKey identifies a unit by the original of its file and its id
+type mergeKey struct {
+	original, id string
+}
+
+// mergeFile merges the units of from into into and returns the number of
+// conflicts. origin keeps track of which input a unit of into was taken
+// from, for the report.
+func (m *mergeConv) mergeFile(into, from *xliffFile, aName, bName string, origin map[mergeKey]string) int {
+
+	units := map[string]*xliffTransUnit{}
+	into.Body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+		if _, exists := units[unit.ID]; !exists {
+			units[unit.ID] = unit
+		}
+	})
+
+	type newUnit struct {
+		groups []*xliffGroup
+		unit   *xliffTransUnit
+	}
+	var added []newUnit
+	conflicts := 0
+
+	from.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+		key := mergeKey{into.Original, unit.ID}
+		existing := units[unit.ID]
+		if existing == nil {
+			added = append(added, newUnit{groups, unit})
+			origin[key] = bName
+			return
+		}
+		if !unitsDiffer(existing, unit) {
+			return
+		}
+
+		conflicts++
+		take := false
+		switch m.strategy {
+		case MERGE_PREFER_B:
+			take = true
+		case MERGE_PREFER_NEWER:
+			take = stateRank(unitState(unit)) > stateRank(unitState(existing))
+		case MERGE_PREFER_NON_EMPTY:
+			take = unitTarget(existing) == "" && unitTarget(unit) != ""
+		}
+
+		a := aName
+		if name, ok := origin[key]; ok {
+			a = name
+		}
+		took := a
+		if take {
+			took = bName
+		}
+		if m.strategy == MERGE_FAIL {
+			took = "none"
+		}
+		log.Printf("conflict: %s %q: %s: %q (%s), %s: %q (%s), took %s",
+			from.Original, unit.ID,
+			a, unitTarget(existing), unitState(existing),
+			bName, unitTarget(unit), unitState(unit), took)
+
+		if take {
+			*existing = *unit
+			origin[key] = bName
+		}
+	})
+
+	// new units are appended only after the existing ones were resolved:
+	// appending might move the units pointed to by units
+	for _, n := range added {
+		into.Body.Add(n.groups, *n.unit)
+	}
+
+	return conflicts
+}
+
+// unitsDiffer reports if a and b do not agree on source, target or state
+func unitsDiffer(a, b *xliffTransUnit) bool {
+	return a.Source.Text(INLINE_XML) != b.Source.Text(INLINE_XML) ||
+		unitTarget(a) != unitTarget(b) ||
+		unitState(a) != unitState(b)
+}
+
+func unitTarget(unit *xliffTransUnit) string {
+	if unit.Target == nil {
+		return ""
+	}
+	return unit.Target.Text(INLINE_XML)
+}
+
+func unitState(unit *xliffTransUnit) string {
+	if unit.Target == nil {
+		return ""
+	}
+	return unit.Target.State
+}
+
+// stateRank orders the states of XLIFF 1.2 by how far the translation
+// has come
+func stateRank(state string) int {
+	switch state {
+	case "needs-translation", "needs-adaptation", "needs-l10n":
+		return 1
+	case "needs-review-translation", "needs-review-adaptation", "needs-review-l10n":
+		return 2
+	case "translated":
+		return 3
+	case "signed-off":
+		return 4
+	case "final":
+		return 5
+	}
+	return 0 // "new", none or a custom state
+}
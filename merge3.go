@@ -0,0 +1,302 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+// merge3Conv merges two XLIFF files which were derived from a common
+// base, trans-unit by trans-unit: a unit which was changed (added,
+// removed) on one side only takes that change, a unit which was changed
+// differently on both sides is a conflict, resolved by -conflict.
+//
+// with -git the files are given as git passes them to a merge driver
+// (%O %A %B): the result is written to the second file and xliffer exits
+// with 1 if conflicts are left.
+type merge3Conv struct {
+	baseFile   string
+	oursFile   string
+	theirsFile string
+	conflict   string
+	git        bool
+	version    string
+}
+
+// the ways to resolve a conflict
+const (
+	MERGE3_NOTE   = "note"   // keep ours, write both sides as conflict markers into the note
+	MERGE3_OURS   = "ours"   // keep ours
+	MERGE3_THEIRS = "theirs" // take theirs
+	MERGE3_FAIL   = "fail"   // write nothing, fail
+)
+
+func init() {
+	registeredConverters["merge3"] = new(merge3Conv)
+}
+
+func (m *merge3Conv) Description() string {
+	return "Three-way merge of XLIFF files (git merge driver)"
+}
+
+func (m *merge3Conv) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" merge3", flag.ExitOnError)
+	fs.StringVar(&m.baseFile, "base", "", "common ancestor")
+	fs.StringVar(&m.oursFile, "ours", "", "our version")
+	fs.StringVar(&m.theirsFile, "theirs", "", "their version")
+	fs.StringVar(&m.conflict, "conflict", MERGE3_NOTE, "resolve conflicts: note, ours, theirs, fail")
+	fs.BoolVar(&m.git, "git", false, "act as git merge driver: <base> <ours> <theirs> (%O %A %B), the result replaces <ours>")
+	xliffVersionFlag(fs, &m.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch m.conflict {
+	case MERGE3_NOTE, MERGE3_OURS, MERGE3_THEIRS, MERGE3_FAIL:
+	default:
+		return fmt.Errorf("unsupported 'conflict': %q", m.conflict)
+	}
+
+	if m.git {
+		if fs.NArg() < 3 {
+			return fmt.Errorf("-git expects <base> <ours> <theirs>")
+		}
+		m.baseFile, m.oursFile, m.theirsFile = fs.Arg(0), fs.Arg(1), fs.Arg(2)
+	}
+	return nil
+}
+
+func (m *merge3Conv) Prepare() error {
+	return nil
+}
+
+func (m *merge3Conv) Convert(w io.Writer) error {
+
+	var docs [3]*xliffDoc
+	for i, name := range []string{m.baseFile, m.oursFile, m.theirsFile} {
+		doc, err := xliffFromFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		docs[i] = doc
+	}
+	base, ours, theirs := docs[0], docs[1], docs[2]
+
+	// ours is the base of the result, so that it is written back with
+	// all the details the typed model does not know about
+	conflicts := 0
+	var deleted []string
+	for i := range ours.File {
+		oursFile := &ours.File[i]
+		baseFile := findFile(base, oursFile.Original)
+		theirsFile := findFile(theirs, oursFile.Original)
+		if theirsFile != nil {
+			conflicts += m.mergeFile(baseFile, oursFile, theirsFile)
+			continue
+		}
+		if baseFile == nil {
+			continue // added by ours
+		}
+
+		// deleted by theirs: merged as if theirs had removed all of its
+		// units, the file goes if none is left
+		conflicts += m.mergeFile(baseFile, oursFile, &xliffFile{Original: oursFile.Original})
+		if len(oursFile.Body.Units()) == 0 {
+			deleted = append(deleted, oursFile.Original)
+		}
+	}
+	for _, original := range deleted {
+		for i := range ours.File {
+			if ours.File[i].Original == original {
+				ours.File = append(ours.File[:i], ours.File[i+1:]...)
+				break
+			}
+		}
+	}
+
+	for i := range theirs.File {
+		theirsFile := &theirs.File[i]
+		if findFile(ours, theirsFile.Original) != nil {
+			continue
+		}
+		baseFile := findFile(base, theirsFile.Original)
+		if baseFile == nil {
+			ours.File = append(ours.File, *theirsFile) // added by theirs
+			continue
+		}
+
+		// deleted by ours: the changes of theirs are merged into an
+		// empty file, which is kept if any units are left
+		file := *theirsFile
+		file.Body = xliffBody{}
+		conflicts += m.mergeFile(baseFile, &file, theirsFile)
+		if len(file.Body.Units()) > 0 {
+			ours.File = append(ours.File, file)
+		}
+	}
+
+	if conflicts > 0 && m.conflict == MERGE3_FAIL {
+		return fmt.Errorf("failed on %d conflicts", conflicts)
+	}
+
+	if err := ours.SetVersion(m.version); err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := writeXliff(buf, ours, "  "); err != nil {
+		return err
+	}
+	if m.git {
+		if err := ioutil.WriteFile(m.oursFile, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	} else if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if conflicts > 0 && m.conflict == MERGE3_NOTE {
+		log.Printf("%d conflicts, marked in the notes", conflicts)
+		return errConflicts
+	}
+	return nil
+}
+
+// mergeFile merges the changes theirs made to base into ours and returns
+// the number of conflicts. base is nil if the file is new on both sides.
+func (m *merge3Conv) mergeFile(base, ours, theirs *xliffFile) int {
+
+	baseUnits := unitsByID(base)
+	theirsUnits := unitsByID(theirs)
+	oursUnits := unitsByID(ours)
+
+	conflicts := 0
+	removed := map[*xliffTransUnit]bool{}
+	for _, unit := range ours.Body.Units() {
+		b, t := baseUnits[unit.ID], theirsUnits[unit.ID]
+		switch {
+		case sameUnit(unit, t):
+		case sameUnit(unit, b): // changed by theirs only
+			if t == nil {
+				removed[unit] = true
+			} else {
+				*unit = *t
+			}
+		case sameUnit(t, b): // changed by ours only
+		default:
+			conflicts++
+			log.Printf("conflict: %s %q: changed in %s and %s", ours.Original, unit.ID, m.oursFile, m.theirsFile)
+			switch m.conflict {
+			case MERGE3_THEIRS:
+				if t == nil {
+					removed[unit] = true
+				} else {
+					*unit = *t
+				}
+			case MERGE3_NOTE:
+				unit.Note = conflictNote(unit, t)
+			}
+		}
+	}
+	ours.Body.Remove(func(unit *xliffTransUnit) bool {
+		return removed[unit]
+	})
+
+	// units added by theirs, or removed by ours and changed by theirs
+	type newUnit struct {
+		groups []*xliffGroup
+		unit   xliffTransUnit
+	}
+	var added []newUnit
+	theirs.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+		if oursUnits[unit.ID] != nil {
+			return
+		}
+		b := baseUnits[unit.ID]
+		switch {
+		case b == nil:
+			added = append(added, newUnit{groups, *unit})
+		case sameUnit(unit, b): // removed by ours only
+		default:
+			conflicts++
+			log.Printf("conflict: %s %q: removed in %s, changed in %s", ours.Original, unit.ID, m.oursFile, m.theirsFile)
+			switch m.conflict {
+			case MERGE3_THEIRS:
+				added = append(added, newUnit{groups, *unit})
+			case MERGE3_NOTE:
+				n := newUnit{groups, *unit}
+				n.unit.Note = conflictNote(nil, unit)
+				added = append(added, n)
+			}
+		}
+	})
+	for _, n := range added {
+		ours.Body.Add(n.groups, n.unit)
+	}
+
+	return conflicts
+}
+
+// unitsByID returns the units of file by their id, the first one if an
+// id is used twice
+func unitsByID(file *xliffFile) map[string]*xliffTransUnit {
+	units := map[string]*xliffTransUnit{}
+	if file == nil {
+		return units
+	}
+	file.Body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+		if _, exists := units[unit.ID]; !exists {
+			units[unit.ID] = unit
+		}
+	})
+	return units
+}
+
+// sameUnit reports if a and b agree on source, target, state and note.
+// nil stands for a unit which does not exist.
+func sameUnit(a, b *xliffTransUnit) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return !unitsDiffer(a, b) && a.Note == b.Note
+}
+
+// conflictNote writes both sides of a conflict in the manner of git's
+// conflict markers
+func conflictNote(ours, theirs *xliffTransUnit) string {
+	buf := bytes.NewBufferString("<<<<<<< ours\n")
+	writeConflictSide(buf, ours)
+	buf.WriteString("=======\n")
+	writeConflictSide(buf, theirs)
+	buf.WriteString(">>>>>>> theirs")
+	return buf.String()
+}
+
+func writeConflictSide(buf *bytes.Buffer, unit *xliffTransUnit) {
+	if unit == nil {
+		buf.WriteString("(removed)\n")
+		return
+	}
+	fmt.Fprintf(buf, "source: %s\n", unit.Source.Text(INLINE_PLACEHOLDER))
+	if unit.Target != nil {
+		fmt.Fprintf(buf, "target: %s\n", unit.Target.Text(INLINE_PLACEHOLDER))
+		if unit.Target.State != "" {
+			fmt.Fprintf(buf, "state: %s\n", unit.Target.State)
+		}
+	}
+	if note := strings.TrimSpace(unit.Note); note != "" {
+		fmt.Fprintf(buf, "note: %s\n", note)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+
+	m := &merge3Conv{
+		baseFile:   "testdata/merge3/base.xlf",
+		oursFile:   "testdata/merge3/ours.xlf",
+		theirsFile: "testdata/merge3/theirs.xlf",
+		conflict:   MERGE3_THEIRS,
+	}
+	out := bytes.NewBuffer(nil)
+	if err := m.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := xliffFromReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, unit := range doc.File[0].Body.Units() {
+		got = append(got, unit.ID+"="+unitTarget(unit))
+	}
+	expected := "hello=Hallo!,bye=Auf Wiedersehen,one=1,ours=Unseres,theirs=Ihres"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, ","))
+	}
+
+	// as merge driver the result replaces ours, conflicts are marked
+	dir, err := ioutil.TempDir("", "merge3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ours, _ := ioutil.ReadFile(m.oursFile)
+	oursFile := filepath.Join(dir, "ours.xlf")
+	if err = ioutil.WriteFile(oursFile, ours, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m = new(merge3Conv)
+	if err = m.ParseArgs("xliffer", []string{"-git", "testdata/merge3/base.xlf", oursFile, "testdata/merge3/theirs.xlf"}); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err = m.Convert(out); err == nil {
+		t.Errorf("expected the conflict to be reported")
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing written to the output, got\n%s", out)
+	}
+	merged, _ := ioutil.ReadFile(oursFile)
+	if !bytes.Contains(merged, []byte("<note>&lt;&lt;&lt;&lt;&lt;&lt;&lt; ours\nsource: One\ntarget: Eins!")) {
+		t.Errorf("expected a conflict note, got\n%s", merged)
+	}
+}
+
+func TestMerge3Files(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "merge3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	xliff := func(name string, files ...string) string {
+		s := `<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">`
+		for _, f := range files {
+			parts := strings.Split(f, ":")
+			s += `<file original="` + parts[0] + `" source-language="en" target-language="de"><body>` +
+				`<trans-unit id="` + parts[0] + `"><source>S</source><target>` + parts[1] + `</target></trans-unit></body></file>`
+		}
+		name = filepath.Join(dir, name)
+		if err := ioutil.WriteFile(name, []byte(s+"</xliff>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	// a: deleted by theirs; b: changed by ours, deleted by theirs; c:
+	// deleted by ours, changed by theirs
+	base := xliff("base.xlf", "a:1", "b:1", "c:1")
+	ours := xliff("ours.xlf", "a:1", "b:2")
+	theirs := xliff("theirs.xlf", "c:2")
+
+	for conflict, expected := range map[string]string{
+		MERGE3_THEIRS: "c=2",
+		MERGE3_OURS:   "b=2",
+		MERGE3_NOTE:   "b=2,c=2",
+	} {
+		m := &merge3Conv{baseFile: base, oursFile: ours, theirsFile: theirs, conflict: conflict}
+		out := bytes.NewBuffer(nil)
+		err := m.Convert(out)
+		if (err != nil) != (conflict == MERGE3_NOTE) {
+			t.Errorf("%s: unexpected error %v", conflict, err)
+		}
+		doc, err := xliffFromReader(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range doc.File {
+			for _, unit := range file.Body.Units() {
+				got = append(got, file.Original+"="+unitTarget(unit))
+			}
+		}
+		if strings.Join(got, ",") != expected {
+			t.Errorf("%s: expected %s, got %s", conflict, expected, strings.Join(got, ","))
+		}
+	}
+}
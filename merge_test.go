@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+
+	tests := []struct {
+		strategy string
+		bye      string
+	}{
+		{MERGE_PREFER_A, "Tschüss"},
+		{MERGE_PREFER_B, "Auf Wiedersehen\nund bis bald"},
+		{MERGE_PREFER_NEWER, "Auf Wiedersehen\nund bis bald"},
+		{MERGE_PREFER_NON_EMPTY, "Tschüss"},
+	}
+
+	for _, test := range tests {
+		m := &mergeConv{aFile: "testdata/diff/a.xlf", bFile: "testdata/diff/b.xlf", strategy: test.strategy}
+		out := bytes.NewBuffer(nil)
+		if err := m.Convert(out); err != nil {
+			t.Fatalf("%s: %s", test.strategy, err)
+		}
+		doc, err := xliffFromReader(out)
+		if err != nil {
+			t.Fatalf("%s: %s", test.strategy, err)
+		}
+		if len(doc.File) != 1 {
+			t.Fatalf("%s: expected the files to be combined, got %d", test.strategy, len(doc.File))
+		}
+
+		var ids []string
+		targets := map[string]string{}
+		for _, unit := range doc.File[0].Body.Units() {
+			ids = append(ids, unit.ID)
+			targets[unit.ID] = unitTarget(unit)
+		}
+		if got := strings.Join(ids, ","); got != "greeting,bye,old,new" {
+			t.Errorf("%s: expected units greeting,bye,old,new, got %s", test.strategy, got)
+		}
+		if targets["bye"] != test.bye {
+			t.Errorf("%s: expected %q, got %q", test.strategy, test.bye, targets["bye"])
+		}
+	}
+
+	m := &mergeConv{aFile: "testdata/diff/a.xlf", bFile: "testdata/diff/b.xlf", strategy: MERGE_FAIL}
+	if err := m.Convert(bytes.NewBuffer(nil)); err == nil {
+		t.Errorf("expected %s to fail", MERGE_FAIL)
+	}
+
+	// the same unit in all inputs is no conflict
+	m = &mergeConv{aFile: "testdata/diff/a.xlf", bFile: "testdata/diff/a.xlf", inFiles: []string{"testdata/diff/a.xlf"}, strategy: MERGE_FAIL}
+	if err := m.Convert(bytes.NewBuffer(nil)); err != nil {
+		t.Errorf("expected no conflicts, got %s", err)
+	}
+}
+
+// the report names the input a unit was taken from, with units added
+// by the inputs before
+func TestMergeOrigin(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cFile := filepath.Join(dir, "c.xlf")
+	err = ioutil.WriteFile(cFile, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="app" source-language="en" target-language="de" datatype="plaintext">
+    <body>
+      <trans-unit id="bye">
+        <source>Bye</source>
+        <target state="translated">Ciao</target>
+      </trans-unit>
+      <trans-unit id="new">
+        <source>New</source>
+        <target state="translated">Neuer</target>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := bytes.NewBuffer(nil)
+	log.SetOutput(report)
+	defer log.SetOutput(os.Stderr)
+
+	m := &mergeConv{aFile: "testdata/diff/a.xlf", bFile: "testdata/diff/b.xlf", inFiles: []string{cFile}, strategy: MERGE_PREFER_B}
+	if err = m.Convert(bytes.NewBuffer(nil)); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{
+		`"bye": testdata/diff/b.xlf: "Auf Wiedersehen\nund bis bald" (final), ` + cFile,
+		`"new": testdata/diff/b.xlf: "Neu" (translated), ` + cFile,
+	} {
+		if !strings.Contains(report.String(), part) {
+			t.Errorf("expected %q in the report:\n%s", part, report)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/tealeg/xlsx"
+)
+
+// OpenDocument Spreadsheets (.ods) are read into and written from the
+// same *xlsx.File the xlsx converters work on. only the content of the
+// cells (as text) and the bold style of a cell are taken into account,
+// formulas, number formats etc. are not.
+
+const (
+	ODS_MIMETYPE = "application/vnd.oasis.opendocument.spreadsheet"
+
+	ODS_NS_OFFICE = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	ODS_NS_TABLE  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	ODS_NS_TEXT   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	ODS_NS_STYLE  = "urn:oasis:names:tc:opendocument:xmlns:style:1.0"
+	ODS_NS_FO     = "urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"
+)
+
+// isODS reports if fileName is an OpenDocument Spreadsheet
+func isODS(fileName string) bool {
+	r, err := zip.OpenReader(fileName)
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name == "mimetype" {
+			data, err := readZipFile(f)
+			return err == nil && strings.TrimSpace(string(data)) == ODS_MIMETYPE
+		}
+	}
+	return false
+}
+
+// openSpreadsheet opens a .xlsx or a .ods
+func openSpreadsheet(fileName string) (*xlsx.File, error) {
+	if isODS(fileName) {
+		return odsOpenFile(fileName)
+	}
+	return xlsx.OpenFile(fileName)
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+func odsOpenFile(fileName string) (*xlsx.File, error) {
+
+	r, err := zip.OpenReader(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.Name == "content.xml" {
+			data, err := readZipFile(f)
+			if err != nil {
+				return nil, err
+			}
+			return odsRead(bytes.NewReader(data))
+		}
+	}
+	return nil, fmt.Errorf("no content.xml in %s", fileName)
+}
+
+// odsRead reads the content.xml of an .ods. repeated empty rows and
+// cells at the end of a table (LibreOffice fills the whole sheet with
+// them) are dropped.
+func odsRead(r io.Reader) (*xlsx.File, error) {
+
+	var (
+		file  = xlsx.NewFile()
+		dec   = xml.NewDecoder(r)
+		sheet *xlsx.Sheet
+		row   *xlsx.Row
+
+		rowRepeat   int
+		emptyRows   int // pending empty rows
+		emptyCells  int // pending empty cells of the current row
+		cellRepeat  int
+		text        *bytes.Buffer // text of the current cell, nil outside cells
+		paragraphs  int
+		inParagraph bool
+		annotations int
+	)
+
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case annotations > 0:
+				if t.Name.Space == ODS_NS_OFFICE && t.Name.Local == "annotation" {
+					annotations++
+				}
+			case t.Name.Space == ODS_NS_OFFICE && t.Name.Local == "annotation":
+				annotations++
+			case t.Name.Space == ODS_NS_TABLE && t.Name.Local == "table":
+				name := odsAttr(t, ODS_NS_TABLE, "name")
+				sheet = &xlsx.Sheet{Name: name, File: file, Selected: len(file.Sheets) == 0}
+				file.Sheets = append(file.Sheets, sheet)
+				file.Sheet[name] = sheet
+				emptyRows = 0
+			case t.Name.Space == ODS_NS_TABLE && t.Name.Local == "table-row" && sheet != nil:
+				rowRepeat = odsRepeat(t, "number-rows-repeated")
+				row = &xlsx.Row{Sheet: sheet}
+				emptyCells = 0
+			case t.Name.Space == ODS_NS_TABLE && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell") && row != nil:
+				cellRepeat = odsRepeat(t, "number-columns-repeated")
+				text, paragraphs = bytes.NewBuffer(nil), 0
+			case text == nil:
+			case t.Name.Space == ODS_NS_TEXT && t.Name.Local == "p":
+				if paragraphs > 0 {
+					text.WriteString("\n")
+				}
+				paragraphs++
+				inParagraph = true
+			case t.Name.Space == ODS_NS_TEXT && t.Name.Local == "s":
+				n := 1
+				if c := odsAttr(t, ODS_NS_TEXT, "c"); c != "" {
+					n, _ = strconv.Atoi(c)
+				}
+				text.WriteString(strings.Repeat(" ", n))
+			case t.Name.Space == ODS_NS_TEXT && t.Name.Local == "tab":
+				text.WriteString("\t")
+			case t.Name.Space == ODS_NS_TEXT && t.Name.Local == "line-break":
+				text.WriteString("\n")
+			}
+
+		case xml.CharData:
+			if text != nil && annotations == 0 && inParagraph {
+				text.Write(t)
+			}
+
+		case xml.EndElement:
+			switch {
+			case annotations > 0:
+				if t.Name.Space == ODS_NS_OFFICE && t.Name.Local == "annotation" {
+					annotations--
+				}
+			case t.Name.Space == ODS_NS_TEXT && t.Name.Local == "p":
+				inParagraph = false
+			case t.Name.Space == ODS_NS_TABLE && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell") && text != nil:
+				if text.Len() == 0 {
+					emptyCells += cellRepeat
+				} else {
+					for ; emptyCells > 0; emptyCells-- {
+						row.AddCell()
+					}
+					for i := 0; i < cellRepeat; i++ {
+						row.AddCell().SetString(text.String())
+					}
+				}
+				text = nil
+			case t.Name.Space == ODS_NS_TABLE && t.Name.Local == "table-row" && row != nil:
+				if len(row.Cells) == 0 {
+					emptyRows += rowRepeat
+				} else {
+					for ; emptyRows > 0; emptyRows-- {
+						sheet.AddRow()
+					}
+					for i := 0; i < rowRepeat; i++ {
+						odsCopyRow(sheet.AddRow(), row)
+					}
+				}
+				row = nil
+			case t.Name.Space == ODS_NS_TABLE && t.Name.Local == "table":
+				sheet = nil
+			}
+		}
+	}
+
+	return file, nil
+}
+
+func odsCopyRow(to, from *xlsx.Row) {
+	for _, cell := range from.Cells {
+		to.AddCell().SetString(cell.Value)
+	}
+}
+
+func odsAttr(start xml.StartElement, space, local string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func odsRepeat(start xml.StartElement, local string) int {
+	if n, err := strconv.Atoi(odsAttr(start, ODS_NS_TABLE, local)); err == nil && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// odsWrite writes file as .ods
+func odsWrite(w io.Writer, file *xlsx.File) error {
+
+	zw := zip.NewWriter(w)
+
+	// the mimetype has to be the first entry, uncompressed
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	io.WriteString(mw, ODS_MIMETYPE)
+
+	mw, err = zw.Create("META-INF/manifest.xml")
+	if err != nil {
+		return err
+	}
+	io.WriteString(mw, xml.Header+`<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+ <manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="`+ODS_MIMETYPE+`"/>
+ <manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>
+`)
+
+	mw, err = zw.Create("content.xml")
+	if err != nil {
+		return err
+	}
+	if _, err = mw.Write(odsContent(file)); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func odsContent(file *xlsx.File) []byte {
+
+	buf := bytes.NewBufferString(xml.Header)
+	buf.WriteString(`<office:document-content` +
+		` xmlns:office="` + ODS_NS_OFFICE + `"` +
+		` xmlns:table="` + ODS_NS_TABLE + `"` +
+		` xmlns:text="` + ODS_NS_TEXT + `"` +
+		` xmlns:style="` + ODS_NS_STYLE + `"` +
+		` xmlns:fo="` + ODS_NS_FO + `"` +
+		` office:version="1.2">` + "\n")
+	buf.WriteString(`<office:automatic-styles>` +
+		`<style:style style:name="bold" style:family="table-cell">` +
+		`<style:text-properties fo:font-weight="bold"/>` +
+		`</style:style>` +
+		`</office:automatic-styles>` + "\n")
+	buf.WriteString("<office:body><office:spreadsheet>\n")
+
+	for _, sheet := range file.Sheets {
+		buf.WriteString(`<table:table table:name="` + escapedAttr(sheet.Name) + `">` + "\n")
+		for _, row := range sheet.Rows {
+			buf.WriteString("<table:table-row>")
+			for _, cell := range row.Cells {
+				value := cell.String()
+				if value == "" {
+					buf.WriteString("<table:table-cell/>")
+					continue
+				}
+				buf.WriteString("<table:table-cell")
+				if cell.GetStyle().Font.Bold {
+					buf.WriteString(` table:style-name="bold"`)
+				}
+				buf.WriteString(` office:value-type="string">`)
+				for _, p := range strings.Split(value, "\n") {
+					buf.WriteString("<text:p>" + odsText(p) + "</text:p>")
+				}
+				buf.WriteString("</table:table-cell>")
+			}
+			if len(row.Cells) == 0 {
+				buf.WriteString("<table:table-cell/>")
+			}
+			buf.WriteString("</table:table-row>\n")
+		}
+		if len(sheet.Rows) == 0 {
+			buf.WriteString("<table:table-row><table:table-cell/></table:table-row>\n")
+		}
+		buf.WriteString("</table:table>\n")
+	}
+
+	buf.WriteString("</office:spreadsheet></office:body>\n</office:document-content>\n")
+	return buf.Bytes()
+}
+
+// odsText escapes a paragraph. consecutive spaces (and leading ones)
+// need <text:s/> in ODF, they would be collapsed otherwise.
+func odsText(p string) string {
+
+	buf := bytes.NewBuffer(nil)
+	spaces := 0
+	flush := func(end bool) {
+		if spaces > 0 && buf.Len() > 0 && !end {
+			buf.WriteString(" ")
+			spaces--
+		}
+		if spaces > 0 {
+			fmt.Fprintf(buf, `<text:s text:c="%d"/>`, spaces)
+		}
+		spaces = 0
+	}
+
+	for _, r := range p {
+		switch r {
+		case ' ':
+			spaces++
+			continue
+		case '\t':
+			flush(false)
+			buf.WriteString("<text:tab/>")
+			continue
+		}
+		flush(false)
+		escapeText(buf, string(r))
+	}
+	flush(true)
+	return buf.String()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+func TestODSRoundTrip(t *testing.T) {
+
+	file := xlsx.NewFile()
+	sheet, _ := file.AddSheet("app")
+	values := [][]string{
+		{"key", "note", "source", "de"},
+		{},
+		{"a", "", "  two  spaces", "tab\there"},
+		{"b", "x & y", "line 1\nline 2", "<b>"},
+	}
+	for r, row := range values {
+		for c, value := range row {
+			sheet.Cell(r, c).SetString(value)
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := odsWrite(buf, file); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr.File[0].Name != "mimetype" || zr.File[0].Method != zip.Store {
+		t.Errorf("mimetype must be the first, uncompressed entry")
+	}
+	var content []byte
+	for _, f := range zr.File {
+		if f.Name == "content.xml" {
+			content, _ = readZipFile(f)
+		}
+	}
+
+	read, err := odsRead(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Sheets) != 1 || read.Sheets[0].Name != "app" {
+		t.Fatalf("unexpected sheets: %v", read.Sheets)
+	}
+	for r, row := range values {
+		for c, value := range row {
+			if got := read.Sheets[0].Cell(r, c).String(); got != value {
+				t.Errorf("cell %d,%d: expected %q, got %q", r, c, value, got)
+			}
+		}
+	}
+}
+
+// LibreOffice fills a sheet with repeated empty rows and cells
+func TestODSReadRepeated(t *testing.T) {
+
+	content := `<office:document-content xmlns:office="` + ODS_NS_OFFICE + `" xmlns:table="` + ODS_NS_TABLE + `" xmlns:text="` + ODS_NS_TEXT + `">
+<office:body><office:spreadsheet><table:table table:name="Sheet1">
+<table:table-row><table:table-cell table:number-columns-repeated="2" office:value-type="string"><text:p>k</text:p></table:table-cell><table:table-cell table:number-columns-repeated="1020"/></table:table-row>
+<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+<table:table-row><table:table-cell/><table:table-cell office:value-type="float" office:value="3"><text:p>3<office:annotation><text:p>comment</text:p></office:annotation></text:p></table:table-cell></table:table-row>
+<table:table-row table:number-rows-repeated="1048572"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table></office:spreadsheet></office:body></office:document-content>`
+
+	file, err := odsRead(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := file.Sheets[0].Rows
+	if len(rows) != 4 || len(rows[0].Cells) != 2 || len(rows[3].Cells) != 2 {
+		t.Fatalf("unexpected size: %d rows", len(rows))
+	}
+	if rows[0].Cells[1].String() != "k" || rows[3].Cells[1].String() != "3" {
+		t.Errorf("unexpected cells: %q %q", rows[0].Cells[1].String(), rows[3].Cells[1].String())
+	}
+}
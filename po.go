@@ -0,0 +1,417 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// poEntry is a message of a gettext PO (or POT) file:
+//
+//	# translator comments
+//	#. extracted comments
+//	#: references
+//	#, flags
+//	#| msgid "previous msgid"
+//	msgctxt "context"
+//	msgid "untranslated"
+//	msgstr "translated"
+type poEntry struct {
+	Comments     []string
+	Extracted    []string
+	References   []string
+	Flags        []string
+	PrevCtxt     string
+	PrevID       string
+	PrevIDPlural string
+	Ctxt         string
+	ID           string
+	IDPlural     string
+	Str          []string // msgstr or msgstr[n] of plural entries
+}
+
+// isHeader reports if e is the header entry (msgid "") of a PO
+func (e *poEntry) isHeader() bool {
+	return e.ID == "" && e.Ctxt == ""
+}
+
+func (e *poEntry) hasFlag(flag string) bool {
+	for _, f := range e.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// readPO reads all entries of a PO. obsolete entries (#~) are skipped.
+func readPO(r io.Reader) ([]*poEntry, error) {
+
+	var (
+		entries []*poEntry
+		entry   = new(poEntry)
+		last    *string // the string continuation lines are added to
+		hasID   bool
+		lineNo  int
+	)
+
+	next := func() {
+		if hasID {
+			entries = append(entries, entry)
+		}
+		entry, last, hasID = new(poEntry), nil, false
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		// a comment after the msgstr starts the next entry
+		if hasID && len(entry.Str) > 0 && strings.HasPrefix(line, "#") {
+			next()
+		}
+
+		var err error
+		switch {
+		case line == "":
+			next()
+		case strings.HasPrefix(line, "#~"):
+			if !hasID {
+				entry = new(poEntry) // comments belong to the obsolete entry
+			}
+			last = nil
+		case strings.HasPrefix(line, "#|"):
+			prev := strings.TrimSpace(line[2:])
+			if strings.HasPrefix(prev, `"`) && last != nil {
+				var s string
+				if s, err = poUnquote(prev); err == nil {
+					*last += s
+				}
+				break
+			}
+			last, err = entry.keyword(prev, true)
+		case strings.HasPrefix(line, "#."):
+			entry.Extracted = append(entry.Extracted, poComment(line[2:]))
+		case strings.HasPrefix(line, "#:"):
+			entry.References = append(entry.References, strings.Fields(line[2:])...)
+		case strings.HasPrefix(line, "#,"):
+			for _, flag := range strings.Split(line[2:], ",") {
+				if flag = strings.TrimSpace(flag); flag != "" {
+					entry.Flags = append(entry.Flags, flag)
+				}
+			}
+		case strings.HasPrefix(line, "#"):
+			entry.Comments = append(entry.Comments, poComment(line[1:]))
+		case strings.HasPrefix(line, `"`):
+			if last == nil {
+				return nil, fmt.Errorf("line %d: unexpected string", lineNo)
+			}
+			var s string
+			if s, err = poUnquote(line); err == nil {
+				*last += s
+			}
+		default:
+			if hasID && len(entry.Str) > 0 && !strings.HasPrefix(line, "msgstr") {
+				next()
+			}
+			last, err = entry.keyword(line, false)
+			hasID = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	next()
+
+	return entries, nil
+}
+
+// keyword parses a line like `msgid "text"` and returns the field the
+// string was stored in. prev is set for the "#|" lines.
+func (e *poEntry) keyword(line string, prev bool) (*string, error) {
+
+	i := strings.IndexAny(line, " \t")
+	if i < 0 {
+		return nil, fmt.Errorf("missing string in %q", line)
+	}
+	keyword, value := line[:i], strings.TrimSpace(line[i:])
+
+	s, err := poUnquote(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var field *string
+	switch {
+	case prev && keyword == "msgctxt":
+		field = &e.PrevCtxt
+	case prev && keyword == "msgid":
+		field = &e.PrevID
+	case prev && keyword == "msgid_plural":
+		field = &e.PrevIDPlural
+	case prev:
+		return nil, fmt.Errorf("unsupported keyword: %q", keyword)
+	case keyword == "msgctxt":
+		field = &e.Ctxt
+	case keyword == "msgid":
+		field = &e.ID
+	case keyword == "msgid_plural":
+		field = &e.IDPlural
+	case keyword == "msgstr":
+		e.Str = append(e.Str, "")
+		field = &e.Str[len(e.Str)-1]
+	case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]"):
+		n, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid plural index in %q", keyword)
+		}
+		for len(e.Str) <= n {
+			e.Str = append(e.Str, "")
+		}
+		field = &e.Str[n]
+	default:
+		return nil, fmt.Errorf("unsupported keyword: %q", keyword)
+	}
+
+	*field += s
+	return field, nil
+}
+
+func poComment(comment string) string {
+	return strings.TrimPrefix(comment, " ")
+}
+
+func poUnquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("invalid string: %s", s)
+	}
+	return strconv.Unquote(s)
+}
+
+func poQuote(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// writeTo writes e in the layout of the gettext tools
+func (e *poEntry) writeTo(buf *bytes.Buffer) {
+
+	for _, c := range e.Comments {
+		buf.WriteString(strings.TrimRight("# "+c, " ") + "\n")
+	}
+	for _, c := range e.Extracted {
+		buf.WriteString("#. " + c + "\n")
+	}
+	if len(e.References) > 0 {
+		buf.WriteString("#: " + strings.Join(e.References, " ") + "\n")
+	}
+	if len(e.Flags) > 0 {
+		buf.WriteString("#, " + strings.Join(e.Flags, ", ") + "\n")
+	}
+	if e.PrevCtxt != "" {
+		writePOString(buf, "#| ", "msgctxt", e.PrevCtxt)
+	}
+	if e.PrevID != "" {
+		writePOString(buf, "#| ", "msgid", e.PrevID)
+	}
+	if e.PrevIDPlural != "" {
+		writePOString(buf, "#| ", "msgid_plural", e.PrevIDPlural)
+	}
+
+	if e.Ctxt != "" {
+		writePOString(buf, "", "msgctxt", e.Ctxt)
+	}
+	writePOString(buf, "", "msgid", e.ID)
+	if e.IDPlural == "" {
+		str := ""
+		if len(e.Str) > 0 {
+			str = e.Str[0]
+		}
+		writePOString(buf, "", "msgstr", str)
+		return
+	}
+	writePOString(buf, "", "msgid_plural", e.IDPlural)
+	for i, str := range e.Str {
+		writePOString(buf, "", fmt.Sprintf("msgstr[%d]", i), str)
+	}
+}
+
+// writePOString writes a keyword and its string. strings with inner
+// newlines are split into one line per line of text.
+func writePOString(buf *bytes.Buffer, prefix, keyword, s string) {
+
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		buf.WriteString(prefix + keyword + " " + poQuote(s) + "\n")
+		return
+	}
+
+	buf.WriteString(prefix + keyword + ` ""` + "\n")
+	for _, line := range lines {
+		buf.WriteString(prefix + poQuote(line) + "\n")
+	}
+}
+
+// poHeaderField returns the value of a field ("Language: de") of the
+// header entry
+func poHeaderField(header, name string) string {
+	for _, line := range strings.Split(header, "\n") {
+		if i := strings.IndexByte(line, ':'); i > 0 && strings.EqualFold(line[:i], name) {
+			return strings.TrimSpace(line[i+1:])
+		}
+	}
+	return ""
+}
+
+// setPOHeaderField sets the value of a field of the header entry, the
+// field is appended if it does not exist
+func setPOHeaderField(header, name, value string) string {
+	lines := strings.SplitAfter(header, "\n")
+	for i, line := range lines {
+		if j := strings.IndexByte(line, ':'); j > 0 && strings.EqualFold(line[:j], name) {
+			lines[i] = line[:j] + ": " + value + "\n"
+			return strings.Join(lines, "")
+		}
+	}
+	if header != "" && !strings.HasSuffix(header, "\n") {
+		header += "\n"
+	}
+	return header + name + ": " + value + "\n"
+}
+
+// a PO is represented in XLIFF similar to the "XLIFF 1.2 Representation
+// Guide for Gettext PO": the header entry becomes a trans-unit with
+// restype="x-gettext-domain-header", plural entries become a <group
+// restype="x-gettext-plurals"> with one trans-unit per plural form.
+// msgctxt is kept in resname, translator comments in the <note>,
+// references in <context-group name="po-reference">, everything else in
+// <context-group name="po-entry">.
+const (
+	PO_HEADER_RESTYPE  = "x-gettext-domain-header"
+	PO_PLURALS_RESTYPE = "x-gettext-plurals"
+	PO_ENTRY_GROUP     = "po-entry"
+	PO_REFERENCE_GROUP = "po-reference"
+)
+
+// metaTo stores comments, references, flags and previous msgids of e
+// in unit
+func (e *poEntry) metaTo(unit *xliffTransUnit) {
+
+	unit.Note = strings.Join(e.Comments, "\n")
+
+	if len(e.Extracted) > 0 {
+		unit.addContext(PO_ENTRY_GROUP, "information", "x-po-autocomment", strings.Join(e.Extracted, "\n"))
+	}
+	var flags []string
+	for _, flag := range e.Flags {
+		if flag != "fuzzy" {
+			flags = append(flags, flag)
+		}
+	}
+	if len(flags) > 0 {
+		unit.addContext(PO_ENTRY_GROUP, "information", "x-po-flags", strings.Join(flags, ", "))
+	}
+	for _, prev := range []struct{ ctxType, value string }{
+		{"x-po-previous-msgctxt", e.PrevCtxt},
+		{"x-po-previous-msgid", e.PrevID},
+		{"x-po-previous-msgid_plural", e.PrevIDPlural},
+	} {
+		if prev.value != "" {
+			unit.addContext(PO_ENTRY_GROUP, "information", prev.ctxType, prev.value)
+		}
+	}
+
+	for _, ref := range e.References {
+		cg := xliffContextGroup{Name: PO_REFERENCE_GROUP, Purpose: "location"}
+		file, line := ref, ""
+		if i := strings.LastIndexByte(ref, ':'); i > 0 {
+			if _, err := strconv.Atoi(ref[i+1:]); err == nil {
+				file, line = ref[:i], ref[i+1:]
+			}
+		}
+		cg.Context = append(cg.Context, xliffContext{Type: "sourcefile", Inner: file})
+		if line != "" {
+			cg.Context = append(cg.Context, xliffContext{Type: "linenumber", Inner: line})
+		}
+		unit.ContextGroup = append(unit.ContextGroup, cg)
+	}
+}
+
+// metaFrom takes comments, references, flags and previous msgids of e
+// from unit. any context-group with purpose="location" is a reference.
+func (e *poEntry) metaFrom(unit *xliffTransUnit) {
+
+	if unit.Note != "" {
+		e.Comments = strings.Split(unit.Note, "\n")
+	}
+	if extracted := unit.context(PO_ENTRY_GROUP, "x-po-autocomment"); extracted != "" {
+		e.Extracted = strings.Split(extracted, "\n")
+	}
+	for _, flag := range strings.Split(unit.context(PO_ENTRY_GROUP, "x-po-flags"), ",") {
+		if flag = strings.TrimSpace(flag); flag != "" && !e.hasFlag(flag) {
+			e.Flags = append(e.Flags, flag)
+		}
+	}
+	e.PrevCtxt = unit.context(PO_ENTRY_GROUP, "x-po-previous-msgctxt")
+	e.PrevID = unit.context(PO_ENTRY_GROUP, "x-po-previous-msgid")
+	e.PrevIDPlural = unit.context(PO_ENTRY_GROUP, "x-po-previous-msgid_plural")
+
+	for _, cg := range unit.ContextGroup {
+		if cg.Purpose != "location" {
+			continue
+		}
+		file, line := "", ""
+		for _, ctx := range cg.Context {
+			switch ctx.Type {
+			case "sourcefile":
+				file = ctx.Inner
+			case "linenumber":
+				line = ctx.Inner
+			}
+		}
+		if file == "" {
+			continue
+		}
+		if line != "" {
+			file += ":" + line
+		}
+		e.References = append(e.References, file)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// a PO converted to XLIFF and back is the same PO
+func TestPORoundTrip(t *testing.T) {
+
+	in := "testdata/po/app.po"
+	orig, err := ioutil.ReadFile(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xlf := bytes.NewBuffer(nil)
+	if err = (&fromPO{inFile: in, sourceLang: "en"}).Convert(xlf); err != nil {
+		t.Fatal(err)
+	}
+	tmp, err := ioutil.TempFile("", "xliffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Write(xlf.Bytes())
+	tmp.Close()
+
+	po := bytes.NewBuffer(nil)
+	if err = (&toPO{inFile: tmp.Name(), inline: INLINE_PLAIN}).Convert(po); err != nil {
+		t.Fatal(err)
+	}
+	if po.String() != string(orig) {
+		t.Errorf("round-trip changed the PO:\n%s\nXLIFF:\n%s", po, xlf)
+	}
+
+	pot := bytes.NewBuffer(nil)
+	if err = (&toPO{inFile: tmp.Name(), inline: INLINE_PLAIN, pot: true}).Convert(pot); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{`"Language: \n"`, "msgid \"Hello %s!\"\nmsgstr \"\"\n", "msgstr[1] \"\"\n"} {
+		if !strings.Contains(pot.String(), part) {
+			t.Errorf("expected %q in POT:\n%s", part, pot)
+		}
+	}
+	if strings.Contains(pot.String(), "fuzzy") {
+		t.Errorf("unexpected fuzzy flag in POT:\n%s", pot)
+	}
+}
+
+// a header without msgstr is an empty one
+func TestFromPOHeaderWithoutMsgstr(t *testing.T) {
+
+	tmp, err := ioutil.TempFile("", "xliffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.WriteString("msgid \"\"\n\nmsgid \"a\"\nmsgstr \"b\"\n")
+	tmp.Close()
+
+	xlf := bytes.NewBuffer(nil)
+	if err = (&fromPO{inFile: tmp.Name(), sourceLang: "en"}).Convert(xlf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(xlf.String(), `preserve">a</source>`) {
+		t.Errorf("expected the unit of \"a\":\n%s", xlf)
+	}
+}
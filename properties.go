@@ -0,0 +1,331 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Java .properties are read as java.util.Properties does: logical lines
+// continued by a trailing backslash, "key = value", "key: value" or "key
+// value", escapes (\t, \n, \uXXXX, ...) in keys and values. the comment
+// lines (# or !) right before an entry become its note.
+//
+// ResourceBundles of Java 9 and later are UTF-8, older ones ISO-8859-1
+// with \uXXXX escapes for everything else. with -encoding auto a file is
+// taken as UTF-8 if it is valid UTF-8, as Java does.
+//
+// messages which are passed through MessageFormat need apostrophes
+// doubled:
+//
+//	l'utilisateur {0} -> l''utilisateur {0}
+//
+// -apostrophes decides for which messages: "args" for the ones holding an
+// argument ({0}, ...), as Spring does by default, "all" or "none".
+// from-properties turns them back into single ones for the same messages,
+// so translators see the plain text.
+const (
+	PROPERTIES_AUTO   = "auto"
+	PROPERTIES_UTF8   = "utf-8"
+	PROPERTIES_LATIN1 = "iso-8859-1"
+
+	APOSTROPHES_ARGS = "args"
+	APOSTROPHES_ALL  = "all"
+	APOSTROPHES_NONE = "none"
+)
+
+// propEntry is an entry of a .properties
+type propEntry struct {
+	Key     string
+	Value   string
+	Comment string
+}
+
+func isValidPropertiesEncoding(encoding string) bool {
+	return encoding == PROPERTIES_AUTO || encoding == PROPERTIES_UTF8 || encoding == PROPERTIES_LATIN1
+}
+
+func isValidApostrophes(apostrophes string) bool {
+	return apostrophes == APOSTROPHES_ARGS || apostrophes == APOSTROPHES_ALL || apostrophes == APOSTROPHES_NONE
+}
+
+// readProperties reads the entries of a .properties in order
+func readProperties(data []byte, encoding string) ([]propEntry, error) {
+
+	text := string(data)
+	if encoding == PROPERTIES_LATIN1 || (encoding == PROPERTIES_AUTO && !utf8.Valid(data)) {
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		text = string(runes)
+	}
+	text = strings.TrimPrefix(text, "\ufeff")
+
+	var entries []propEntry
+	var comment []string
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	for n := 0; n < len(lines); n++ {
+		line := strings.TrimLeft(lines[n], " \t\f")
+		if line == "" {
+			comment = nil
+			continue
+		}
+		if line[0] == '#' || line[0] == '!' {
+			comment = append(comment, unescapeUnicode(strings.TrimPrefix(line[1:], " ")))
+			continue
+		}
+
+		// a logical line is continued as long as a natural line ends
+		// in an odd number of backslashes
+		first := n + 1
+		for continued(line) && n+1 < len(lines) {
+			n++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[n], " \t\f")
+		}
+		if continued(line) {
+			line = line[:len(line)-1]
+		}
+
+		key, value := splitProperty(line)
+		k, err := unescapeProperty(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", first, err)
+		}
+		v, err := unescapeProperty(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", first, err)
+		}
+		entries = append(entries, propEntry{Key: k, Value: v, Comment: strings.Join(comment, "\n")})
+		comment = nil
+	}
+	return entries, nil
+}
+
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits a logical line at the first unescaped =, : or
+// whitespace
+func splitProperty(line string) (key, value string) {
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			break
+		}
+	}
+	if i >= len(line) {
+		return line, ""
+	}
+	value = strings.TrimLeft(line[i:], " \t\f")
+	if value != "" && (value[0] == '=' || value[0] == ':') {
+		value = strings.TrimLeft(value[1:], " \t\f")
+	}
+	return line[:i], value
+}
+
+func unescapeProperty(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed \\uxxxx encoding")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\uxxxx encoding")
+			}
+			// surrogate pairs are written as two escapes
+			if r >= 0xd800 && r < 0xdc00 && i+11 <= len(s) && s[i+5:i+7] == "\\u" {
+				low, err := strconv.ParseUint(s[i+7:i+11], 16, 32)
+				if err == nil && low >= 0xdc00 && low < 0xe000 {
+					r = 0x10000 + (r-0xd800)<<10 + (low - 0xdc00)
+					i += 6
+				}
+			}
+			buf.WriteRune(rune(r))
+			i += 4
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String(), nil
+}
+
+// writeProperties writes entries as .properties. ISO-8859-1 is written
+// as ASCII, everything else escaped as \uXXXX, which is read correctly as
+// UTF-8 as well.
+func writeProperties(w io.Writer, entries []propEntry, encoding string) error {
+
+	ascii := encoding != PROPERTIES_UTF8
+	var buf bytes.Buffer
+	for i, e := range entries {
+		if e.Comment != "" {
+			if i > 0 {
+				buf.WriteString("\n")
+			}
+			for _, line := range strings.Split(e.Comment, "\n") {
+				buf.WriteString(strings.TrimRight("# "+escapePropertyComment(line, ascii), " ") + "\n")
+			}
+		}
+		buf.WriteString(escapeProperty(e.Key, true, ascii) + "=" + escapeProperty(e.Value, false, ascii) + "\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// escapeProperty escapes a key or value as Properties.store does
+func escapeProperty(s string, key, ascii bool) string {
+	var buf bytes.Buffer
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r == ' ' && (key || i == 0):
+			buf.WriteString(`\ `)
+		case key && strings.ContainsRune("=:#!", r):
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r < 0x20 || (ascii && r > 0x7e):
+			buf.WriteString(escapeUnicode(r))
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
+
+// comments are not unescaped by Java, but are written with \uXXXX
+// escapes by writeProperties
+var unicodeEscape = regexp.MustCompile(`\\u[0-9a-fA-F]{4}`)
+
+func unescapeUnicode(s string) string {
+	return unicodeEscape.ReplaceAllStringFunc(s, func(escape string) string {
+		r, _ := strconv.ParseUint(escape[2:], 16, 32)
+		return string(rune(r))
+	})
+}
+
+func escapePropertyComment(s string, ascii bool) string {
+	if !ascii {
+		return s
+	}
+	var buf bytes.Buffer
+	for _, r := range s {
+		if r > 0x7e {
+			buf.WriteString(escapeUnicode(r))
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func escapeUnicode(r rune) string {
+	if r > 0xffff {
+		r -= 0x10000
+		return fmt.Sprintf("\\u%04X\\u%04X", 0xd800+(r>>10), 0xdc00+(r&0x3ff))
+	}
+	return fmt.Sprintf("\\u%04X", r)
+}
+
+var messageFormatArg = regexp.MustCompile(`\{\s*\d+\s*[,}]`)
+
+// usesMessageFormat tells whether a message goes through MessageFormat
+func usesMessageFormat(message, apostrophes string) bool {
+	switch apostrophes {
+	case APOSTROPHES_ALL:
+		return true
+	case APOSTROPHES_ARGS:
+		return messageFormatArg.MatchString(message)
+	}
+	return false
+}
+
+// quoted literals of MessageFormat, as in "Type '{0}' literally"
+var messageFormatQuoted = regexp.MustCompile(`'\{[^']*\}'|'[{}]'`)
+
+// doubleApostrophes and singleApostrophes add and remove the quoting of
+// apostrophes for MessageFormat. the apostrophes quoting a literal {...}
+// are left as they are.
+func doubleApostrophes(message, apostrophes string) string {
+	if !usesMessageFormat(message, apostrophes) {
+		return message
+	}
+	var buf bytes.Buffer
+	last := 0
+	for _, m := range messageFormatQuoted.FindAllStringIndex(message, -1) {
+		buf.WriteString(strings.Replace(message[last:m[0]], "'", "''", -1))
+		buf.WriteString(message[m[0]:m[1]])
+		last = m[1]
+	}
+	buf.WriteString(strings.Replace(message[last:], "'", "''", -1))
+	return buf.String()
+}
+
+func singleApostrophes(message, apostrophes string) string {
+	if !usesMessageFormat(message, apostrophes) {
+		return message
+	}
+	return strings.Replace(message, "''", "'", -1)
+}
+
+// propertiesLang returns the language of a ResourceBundle by its name
+// (messages_pt_BR.properties -> pt-BR), "" for the base bundle
+func propertiesLang(fileName string) string {
+	name := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if p := parts[i]; (len(p) == 2 || len(p) == 3) && strings.ToLower(p) == p {
+			return strings.Join(parts[i:], "-")
+		}
+	}
+	return ""
+}
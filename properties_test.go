@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadProperties(t *testing.T) {
+
+	data, err := ioutil.ReadFile("testdata/properties/messages.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := readProperties(data, PROPERTIES_AUTO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []propEntry{
+		{"app.title", "Shop", "title of the start page"},
+		{"welcome", "Welcome, {0}!", "shown after login"},
+		{"user name=x", "User name", ""},
+		{"cart.empty", "Your cart is empty, isn't it?", ""},
+		{"remove.confirm", "Remove {0} from the user''s cart?", ""},
+		{"long.text", "This is a long text spread over lines", ""},
+		{"tabs", "a\tb\nc", ""},
+		{" leading", "  space", ""},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected\n%q\ngot\n%q", expected, entries)
+	}
+
+	// ISO-8859-1, taken as such as it is not valid UTF-8
+	data, err = ioutil.ReadFile("testdata/properties/messages_fr.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, err = readProperties(data, PROPERTIES_AUTO); err != nil {
+		t.Fatal(err)
+	}
+	if value := entries[2].Value; value != "Votre panier est vide, n'est-ce pas\u00a0?" {
+		t.Errorf("expected the ISO-8859-1 to be decoded, got %q", value)
+	}
+
+	if _, err = readProperties([]byte("key=\\u00g1\n"), PROPERTIES_AUTO); err == nil {
+		t.Errorf("expected an error for a malformed \\u escape")
+	}
+}
+
+func TestWriteProperties(t *testing.T) {
+
+	entries := []propEntry{
+		{"key:with spaces", " leading space", "Grüße\nsecond line"},
+		{"emoji", "\U0001F600 ä", ""},
+	}
+	for _, encoding := range []string{PROPERTIES_LATIN1, PROPERTIES_UTF8} {
+		buf := bytes.NewBuffer(nil)
+		if err := writeProperties(buf, entries, encoding); err != nil {
+			t.Fatal(err)
+		}
+		if encoding == PROPERTIES_LATIN1 && buf.String() != "# Gr\\u00FC\\u00DFe\n# second line\nkey\\:with\\ spaces=\\ leading space\nemoji=\\uD83D\\uDE00 \\u00E4\n" {
+			t.Errorf("unexpected %s:\n%s", encoding, buf)
+		}
+		read, err := readProperties(buf.Bytes(), PROPERTIES_AUTO)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read, entries) {
+			t.Errorf("%s: expected\n%q\ngot\n%q", encoding, entries, read)
+		}
+	}
+}
+
+func TestApostrophes(t *testing.T) {
+	for _, c := range []struct{ apostrophes, message, expected string }{
+		{APOSTROPHES_ARGS, "l'utilisateur {0}", "l''utilisateur {0}"},
+		{APOSTROPHES_ARGS, "n'est-ce pas", "n'est-ce pas"},
+		{APOSTROPHES_ARGS, "{1,number}'s", "{1,number}''s"},
+		{APOSTROPHES_ALL, "n'est-ce pas", "n''est-ce pas"},
+		{APOSTROPHES_NONE, "l'utilisateur {0}", "l'utilisateur {0}"},
+		{APOSTROPHES_ARGS, "Type '{0}' literally for {1}", "Type '{0}' literally for {1}"},
+		{APOSTROPHES_ARGS, "It's '{' for {1}, l'{0}", "It''s '{' for {1}, l''{0}"},
+	} {
+		doubled := doubleApostrophes(c.message, c.apostrophes)
+		if doubled != c.expected {
+			t.Errorf("%s: %q: expected %q, got %q", c.apostrophes, c.message, c.expected, doubled)
+		}
+		if single := singleApostrophes(doubled, c.apostrophes); single != c.message {
+			t.Errorf("%s: %q: expected %q back, got %q", c.apostrophes, doubled, c.message, single)
+		}
+	}
+}
+
+func TestPropertiesRoundTrip(t *testing.T) {
+
+	fp := &fromProperties{
+		inFile:      "testdata/properties/messages.properties",
+		targetFile:  "testdata/properties/messages_de.properties",
+		sourceLang:  "en",
+		targetLang:  propertiesLang("messages_de.properties"),
+		encoding:    PROPERTIES_AUTO,
+		apostrophes: APOSTROPHES_ARGS,
+	}
+	out := bytes.NewBuffer(nil)
+	if err := fp.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "messages-de.xlf")
+	if err = ioutil.WriteFile(xlf, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tp := &toProperties{inFile: xlf, encoding: PROPERTIES_LATIN1, apostrophes: APOSTROPHES_ARGS}
+	written := bytes.NewBuffer(nil)
+	if err = tp.Convert(written); err != nil {
+		t.Fatal(err)
+	}
+	original, err := ioutil.ReadFile("testdata/properties/messages_de.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.String() != string(original) {
+		t.Errorf("expected\n%s\ngot\n%s", original, written)
+	}
+
+	if lang := propertiesLang("app_messages_pt_BR.properties"); lang != "pt-BR" {
+		t.Errorf("expected pt-BR, got %q", lang)
+	}
+}
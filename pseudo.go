@@ -0,0 +1,257 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// pseudoConv pseudo-localizes a XLIFF: like copy it copies the sources
+// to the targets, but the letters are replaced by accented ones, the
+// texts are expanded and put into brackets, so that hard coded strings,
+// truncated texts and broken encodings stand out in the UI.
+// placeholders (printf, ICU, {{angular}}), ICU syntax, HTML tags and
+// inline elements are left untouched.
+type pseudoConv struct {
+	inFile     string
+	targetLang string
+	expand     int
+	brackets   bool
+	rtl        bool
+	format     string
+	pretty     bool
+	inline     string
+	version    string
+}
+
+const (
+	PSEUDO_XLIFF = "xliff"
+	PSEUDO_JSON  = "json"
+
+	PSEUDO_RLO = "\u202e" // right-to-left override
+	PSEUDO_PDF = "\u202c" // pop directional formatting
+)
+
+var pseudoAccents = map[rune]rune{}
+
+func init() {
+	registeredConverters["pseudo"] = new(pseudoConv)
+
+	plain := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	accented := []rune("åƀçđéƒĝĥîĵķļɱñöþǫŕšţûṽŵẋýžÅƁÇĐÉƑĜĤÎĴĶĻṀÑÖÞǪŔŠŢÛṼŴẊÝŽ")
+	for i, r := range plain {
+		pseudoAccents[r] = accented[i]
+	}
+}
+
+func (p *pseudoConv) Description() string {
+	return "Pseudo-localizes a XLIFF (accented, expanded targets)"
+}
+
+func (p *pseudoConv) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" pseudo", flag.ExitOnError)
+	fs.StringVar(&p.inFile, "in", "", "infile")
+	fs.StringVar(&p.targetLang, "target-lang", "", "target language (default: \"qps-ploc\", with -rtl \"qps-plocm\")")
+	fs.IntVar(&p.expand, "expand", 30, "expand the texts by this many percent")
+	fs.BoolVar(&p.brackets, "brackets", true, "put the texts into [brackets]")
+	fs.BoolVar(&p.rtl, "rtl", false, "mirror the texts (right-to-left)")
+	fs.StringVar(&p.format, "format", PSEUDO_XLIFF, "output format (xliff, json)")
+	fs.BoolVar(&p.pretty, "pretty", false, "pretty print the resulting json")
+	fs.StringVar(&p.inline, "inline", INLINE_PLAIN, "render inline elements in json as plain, placeholder or xml")
+	xliffVersionFlag(fs, &p.version)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch p.format {
+	case PSEUDO_XLIFF, PSEUDO_JSON:
+	default:
+		return fmt.Errorf("unsupported 'format': %q", p.format)
+	}
+	if p.expand < 0 {
+		return fmt.Errorf("unsupported 'expand': %d", p.expand)
+	}
+	if !isValidInline(p.inline) {
+		return fmt.Errorf("unsupported 'inline': %q", p.inline)
+	}
+	if p.targetLang == "" {
+		p.targetLang = "qps-ploc"
+		if p.rtl {
+			p.targetLang = "qps-plocm"
+		}
+	}
+	return nil
+}
+
+func (p *pseudoConv) Prepare() error {
+	return nil
+}
+
+func (p *pseudoConv) Convert(w io.Writer) error {
+
+	var doc, err = xliffFromFile(p.inFile)
+	if err != nil {
+		return err
+	}
+
+	keyVals := map[string]string{}
+	for i := range doc.File {
+
+		doc.File[i].TargetLang = p.targetLang
+		for _, unit := range doc.File[i].Body.Units() {
+			if attrValue(unit.Attrs, "translate") == "no" {
+				continue
+			}
+			copySource(unit)
+			p.pseudoTarget(unit.Target)
+
+			if _, exist := keyVals[unit.ID]; exist {
+				log.Printf("warning: double entry for key %q", unit.ID)
+			}
+			keyVals[unit.ID] = unit.Target.Text(p.inline)
+		}
+	}
+
+	if p.format == PSEUDO_JSON {
+		out, err := marshalKeyValues(keyVals, p.pretty, "")
+		if err == nil {
+			_, err = w.Write(out)
+		}
+		return err
+	}
+
+	if err = doc.SetVersion(p.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
+
+// pseudoTarget pseudo-localizes a target which was copied from the source
+func (p *pseudoConv) pseudoTarget(target *xliffTarget) {
+
+	content := (*xliffSource)(target).content()
+	length := utf8.RuneCountInString(content.TextOnly())
+	content = content.mapText(p.text)
+
+	padding := strings.Repeat("~", (length*p.expand+99)/100)
+	if p.brackets {
+		content = append(xliffContent{{Text: "["}}, content...)
+		padding += "]"
+	}
+	content = content.appendText(padding)
+
+	target.Lang = p.targetLang
+	target.Content = content
+	target.Inner = content.Plain()
+}
+
+// placeholders and markup which are kept as they are: printf, HTML tags
+// and entities
+var pseudoKeepRx = regexp.MustCompile(`^(?:` + qaPrintfRx.String() + `|</?[A-Za-z][^<>]*>|&#?\w+;)`)
+
+// text pseudo-localizes s
+func (p *pseudoConv) text(s string) string {
+
+	buf := bytes.NewBuffer(nil)
+	run := bytes.NewBuffer(nil) // letters to be mirrored
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if p.rtl {
+			buf.WriteString(PSEUDO_RLO + run.String() + PSEUDO_PDF)
+		} else {
+			buf.Write(run.Bytes())
+		}
+		run.Reset()
+	}
+
+	for len(s) > 0 {
+		if s[0] == '{' {
+			if end := matchingBrace(s); end > 0 {
+				flush()
+				buf.WriteString(p.icu(s[:end+1]))
+				s = s[end+1:]
+				continue
+			}
+		}
+		if loc := pseudoKeepRx.FindStringIndex(s); loc != nil {
+			flush()
+			buf.WriteString(s[:loc[1]])
+			s = s[loc[1]:]
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s)
+		if accented, ok := pseudoAccents[r]; ok {
+			r = accented
+		}
+		run.WriteRune(r)
+		s = s[n:]
+	}
+	flush()
+
+	return buf.String()
+}
+
+// icu pseudo-localizes an argument in braces: the messages of plural and
+// select are pseudo-localized, anything else is kept as it is
+func (p *pseudoConv) icu(arg string) string {
+
+	parts := strings.SplitN(arg[1:len(arg)-1], ",", 3)
+	if len(parts) < 3 {
+		return arg // {name}, {name, number}, {{name}}
+	}
+	switch strings.TrimSpace(parts[1]) {
+	case "plural", "select", "selectordinal":
+	default:
+		return arg
+	}
+
+	buf := bytes.NewBufferString("{" + parts[0] + "," + parts[1] + ",")
+	rest := parts[2]
+	for len(rest) > 0 {
+		if rest[0] == '{' {
+			if end := matchingBrace(rest); end > 0 {
+				buf.WriteString("{" + p.text(rest[1:end]) + "}")
+				rest = rest[end+1:]
+				continue
+			}
+		}
+		buf.WriteByte(rest[0])
+		rest = rest[1:]
+	}
+	buf.WriteString("}")
+
+	return buf.String()
+}
+
+// matchingBrace returns the index of the brace closing the one s starts
+// with, -1 if there is none
+func matchingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestPseudoText(t *testing.T) {
+
+	p := &pseudoConv{}
+	tests := []struct{ in, out string }{
+		{"Hello {name}!", "Ĥéļļö {name}!"},
+		{"%1$s of %d <b>files</b> &amp; more", "%1$s öƒ %d <b>ƒîļéš</b> &amp; ɱöŕé"},
+		{"{count, plural, one {# item} other {# items in {folder}}}", "{count, plural, one {# îţéɱ} other {# îţéɱš îñ {folder}}}"},
+		{"Updated {{minutes}} ago", "Ûþđåţéđ {{minutes}} åĝö"},
+		{"unbalanced { brace", "ûñƀåļåñçéđ { ƀŕåçé"},
+	}
+	for _, test := range tests {
+		if out := p.text(test.in); out != test.out {
+			t.Errorf("%q: expected %q, got %q", test.in, test.out, out)
+		}
+	}
+
+	p.rtl = true
+	if out, expected := p.text("Hi {name}"), "\u202eĤî \u202c{name}"; out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+
+	target := &xliffTarget{Inner: "Save"}
+	p = &pseudoConv{expand: 50, brackets: true, targetLang: "qps-ploc"}
+	p.pseudoTarget(target)
+	if target.Inner != "[Šåṽé~~]" || target.Lang != "qps-ploc" {
+		t.Errorf("expected %q (qps-ploc), got %q (%s)", "[Šåṽé~~]", target.Inner, target.Lang)
+	}
+}
@@ -0,0 +1,594 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// qaConv checks the translations of a XLIFF for common mistakes. each
+// check can be enabled or disabled and has a severity; issues of the
+// severity given by -fail-on (or above) make xliffer exit with 1.
+type qaConv struct {
+	inFile   string
+	enable   string
+	disable  string
+	severity string
+	format   string
+	failOn   string
+	inline   string
+
+	checks []qaCheck
+}
+
+const (
+	QA_ERROR   = "error"
+	QA_WARNING = "warning"
+	QA_NONE    = "none"
+
+	QA_TEXT  = "text"
+	QA_JSON  = "json"
+	QA_JUNIT = "junit"
+)
+
+// qaCheck looks at the source and the target of a unit. target is nil
+// for units without <target>.
+type qaCheck struct {
+	name     string
+	severity string
+	check    func(source string, target *string) []string
+}
+
+// qaIssue is a problem found by a check
+type qaIssue struct {
+	File     string `json:"file,omitempty"`
+	ID       string `json:"id"`
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// the checks, in the order they are run
+var qaChecks = []qaCheck{
+	{"empty", QA_WARNING, qaEmpty},
+	{"untranslated", QA_WARNING, qaUntranslated},
+	{"placeholders", QA_ERROR, qaPlaceholders},
+	{"tags", QA_ERROR, qaTags},
+	{"whitespace", QA_WARNING, qaWhitespace},
+	{"double-space", QA_WARNING, qaDoubleSpace},
+	{"punctuation", QA_WARNING, qaPunctuation},
+}
+
+func init() {
+	registeredConverters["qa"] = new(qaConv)
+}
+
+func (qa *qaConv) Description() string {
+	return "Checks the translations of a XLIFF for mistakes"
+}
+
+func (qa *qaConv) ParseArgs(base string, args []string) error {
+	var names []string
+	for _, c := range qaChecks {
+		names = append(names, c.name)
+	}
+
+	var fs = flag.NewFlagSet(base+" qa", flag.ExitOnError)
+	fs.StringVar(&qa.inFile, "in", "", "infile")
+	fs.StringVar(&qa.enable, "checks", "", "checks to run, comma separated (default: all of "+strings.Join(names, ",")+")")
+	fs.StringVar(&qa.disable, "disable", "", "checks not to run, comma separated")
+	fs.StringVar(&qa.severity, "severity", "", "change the severity of checks, eg. \"empty=error,punctuation=none\"")
+	fs.StringVar(&qa.format, "format", QA_TEXT, "report format (text, json, junit)")
+	fs.StringVar(&qa.failOn, "fail-on", QA_ERROR, "exit with 1 on issues of this severity or above (error, warning, none)")
+	fs.StringVar(&qa.inline, "inline", INLINE_PLACEHOLDER, "check inline elements as plain, placeholder or xml")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch qa.format {
+	case QA_TEXT, QA_JSON, QA_JUNIT:
+	default:
+		return fmt.Errorf("unsupported 'format': %q", qa.format)
+	}
+	if !isValidSeverity(qa.failOn) {
+		return fmt.Errorf("unsupported 'fail-on': %q", qa.failOn)
+	}
+	if !isValidInline(qa.inline) {
+		return fmt.Errorf("unsupported 'inline': %q", qa.inline)
+	}
+	return nil
+}
+
+func (qa *qaConv) Prepare() error {
+
+	enabled := map[string]bool{}
+	for _, name := range qaList(qa.enable) {
+		if findQACheck(name) == nil {
+			return fmt.Errorf("unknown check %q", name)
+		}
+		enabled[name] = true
+	}
+	for _, name := range qaList(qa.disable) {
+		if findQACheck(name) == nil {
+			return fmt.Errorf("unknown check %q", name)
+		}
+	}
+
+	severities := map[string]string{}
+	for _, pair := range qaList(qa.severity) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || findQACheck(kv[0]) == nil || !isValidSeverity(kv[1]) {
+			return fmt.Errorf("unsupported 'severity': %q", pair)
+		}
+		severities[kv[0]] = kv[1]
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range qaList(qa.disable) {
+		disabled[name] = true
+	}
+
+	qa.checks = nil
+	for _, c := range qaChecks {
+		if (len(enabled) > 0 && !enabled[c.name]) || disabled[c.name] {
+			continue
+		}
+		if severity, ok := severities[c.name]; ok {
+			c.severity = severity
+		}
+		if c.severity != QA_NONE {
+			qa.checks = append(qa.checks, c)
+		}
+	}
+	return nil
+}
+
+func (qa *qaConv) Convert(w io.Writer) error {
+
+	doc, err := xliffFromFile(qa.inFile)
+	if err != nil {
+		return err
+	}
+
+	var (
+		issues []qaIssue
+		units  []qaIssue // all units checked, for the junit report
+	)
+	for _, file := range doc.File {
+		file.Body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+			if attrValue(unit.Attrs, "translate") == "no" {
+				return
+			}
+			units = append(units, qaIssue{File: file.Original, ID: unit.ID})
+			issues = append(issues, qa.check(file.Original, unit)...)
+		})
+	}
+
+	buf := bytes.NewBuffer(nil)
+	switch qa.format {
+	case QA_JSON:
+		if issues == nil {
+			issues = []qaIssue{}
+		}
+		out, err := json.MarshalIndent(issues, "", "\t")
+		if err != nil {
+			return err
+		}
+		buf.Write(out)
+		buf.WriteString("\n")
+	case QA_JUNIT:
+		if err = qa.writeJUnit(buf, units, issues); err != nil {
+			return err
+		}
+	default:
+		qa.writeText(buf, issues)
+	}
+
+	if _, err = w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		if qa.fails(issue) {
+			return errIssues
+		}
+	}
+	return nil
+}
+
+// check runs the enabled checks on unit
+func (qa *qaConv) check(file string, unit *xliffTransUnit) []qaIssue {
+
+	source := unit.Source.Text(qa.inline)
+	var target *string
+	if unit.Target != nil {
+		text := unit.Target.Text(qa.inline)
+		target = &text
+	}
+
+	var issues []qaIssue
+	for _, c := range qa.checks {
+		for _, msg := range c.check(source, target) {
+			issues = append(issues, qaIssue{file, unit.ID, c.name, c.severity, msg})
+		}
+	}
+	return issues
+}
+
+func (qa *qaConv) writeText(buf *bytes.Buffer, issues []qaIssue) {
+
+	count := map[string]int{}
+	for _, issue := range issues {
+		count[issue.Severity]++
+		fmt.Fprintf(buf, "%-7s %s", issue.Severity, issue.ID)
+		if issue.File != "" {
+			fmt.Fprintf(buf, " (%s)", issue.File)
+		}
+		fmt.Fprintf(buf, ": [%s] %s\n", issue.Check, issue.Message)
+	}
+
+	if len(issues) == 0 {
+		buf.WriteString("no issues\n")
+		return
+	}
+	fmt.Fprintf(buf, "%d issues: %d errors, %d warnings\n", len(issues), count[QA_ERROR], count[QA_WARNING])
+}
+
+// the parts of a JUnit report qa writes
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes a testcase per unit. issues of the -fail-on severity
+// (or above) are failures, the others are written to system-out.
+func (qa *qaConv) writeJUnit(buf *bytes.Buffer, units []qaIssue, issues []qaIssue) error {
+
+	suite := junitSuite{Name: "xliffer qa", Tests: len(units)}
+	cases := map[qaIssue]*junitCase{}
+	suite.Cases = make([]junitCase, len(units))
+	for i, unit := range units {
+		suite.Cases[i] = junitCase{ClassName: unit.File, Name: unit.ID}
+		cases[unit] = &suite.Cases[i]
+	}
+
+	for _, issue := range issues {
+		c := cases[qaIssue{File: issue.File, ID: issue.ID}]
+		if !qa.fails(issue) {
+			c.SystemOut += issue.Severity + ": " + issue.Check + ": " + issue.Message + "\n"
+			continue
+		}
+		if c.Failure == nil {
+			c.Failure = &junitFailure{Message: issue.Message, Type: issue.Check}
+			suite.Failures++
+		}
+		c.Failure.Text += issue.Check + ": " + issue.Message + "\n"
+	}
+
+	out, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	buf.WriteString(xml.Header)
+	buf.Write(out)
+	buf.WriteString("\n")
+	return nil
+}
+
+// fails reports if issue is of the -fail-on severity or above
+func (qa *qaConv) fails(issue qaIssue) bool {
+	return qa.failOn != QA_NONE && severityRank(issue.Severity) >= severityRank(qa.failOn)
+}
+
+func qaList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func findQACheck(name string) *qaCheck {
+	for i := range qaChecks {
+		if qaChecks[i].name == name {
+			return &qaChecks[i]
+		}
+	}
+	return nil
+}
+
+func isValidSeverity(severity string) bool {
+	switch severity {
+	case QA_ERROR, QA_WARNING, QA_NONE:
+		return true
+	}
+	return false
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case QA_ERROR:
+		return 2
+	case QA_WARNING:
+		return 1
+	}
+	return 0
+}
+
+// the checks. they return one message per issue.
+
+func qaEmpty(source string, target *string) []string {
+	if strings.TrimSpace(source) != "" && (target == nil || strings.TrimSpace(*target) == "") {
+		return []string{"target is empty"}
+	}
+	return nil
+}
+
+func qaUntranslated(source string, target *string) []string {
+	if target != nil && *target == source && strings.IndexFunc(source, unicode.IsLetter) >= 0 {
+		return []string{"target is the same as source"}
+	}
+	return nil
+}
+
+var (
+	// the name of an ICU argument ({name}, {count, plural, ...}) or of a
+	// placeholder of INLINE_PLACEHOLDER
+	qaICUArgRx = regexp.MustCompile(`^[\w.-]+$`)
+	// printf, including positional arguments (%1$s) and %@ of Objective-C
+	qaPrintfRx = regexp.MustCompile(`%%|%(\d+\$)?[-+ #0]*(\d+|\*)?(\.(\d+|\*))?(hh|h|ll|l|L|q|j|z|t)?[diouxXeEfFgGaAcspn@]`)
+	// HTML tags and the paired codes of INLINE_PLACEHOLDER
+	qaTagRx = regexp.MustCompile(`<(/?)([A-Za-z0-9][\w.:-]*)[^<>]*?(/?)>`)
+)
+
+func qaPlaceholders(source string, target *string) []string {
+	if target == nil || *target == "" {
+		return nil
+	}
+	sourceCount, targetCount := map[string]int{}, map[string]int{}
+	qaICUArgs(source, sourceCount)
+	qaICUArgs(*target, targetCount)
+	for _, m := range qaPrintfRx.FindAllString(source, -1) {
+		if m != "%%" {
+			sourceCount[m]++
+		}
+	}
+	for _, m := range qaPrintfRx.FindAllString(*target, -1) {
+		if m != "%%" {
+			targetCount[m]++
+		}
+	}
+	return qaCompareCounts("placeholder", sourceCount, targetCount)
+}
+
+// qaICUArgs counts the ICU arguments of s. the cases of plural and
+// select arguments are text, the arguments used in there are counted
+// once however many cases use them: a translation may have other cases.
+func qaICUArgs(s string, count map[string]int) {
+	nested := map[string]int{}
+	qaICUScan(s, count, nested)
+	for name := range nested {
+		if count[name] == 0 {
+			count[name] = 1
+		}
+	}
+}
+
+func qaICUScan(s string, count, nested map[string]int) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '{' {
+			continue
+		}
+		end := matchingBrace(s[i:])
+		if end < 0 {
+			return
+		}
+		arg := s[i+1 : i+end]
+		i += end
+
+		parts := strings.SplitN(arg, ",", 3)
+		name := strings.TrimSpace(parts[0])
+		if !qaICUArgRx.MatchString(name) {
+			qaICUScan(arg, count, nested) // {{.Name}} of Go templates
+			continue
+		}
+		count["{"+name+"}"]++
+		if len(parts) < 3 {
+			continue
+		}
+		switch strings.TrimSpace(parts[1]) {
+		case "plural", "select", "selectordinal":
+			cases := parts[2]
+			for j := 0; j < len(cases); j++ {
+				if cases[j] != '{' {
+					continue
+				}
+				caseEnd := matchingBrace(cases[j:])
+				if caseEnd < 0 {
+					break
+				}
+				qaICUScan(cases[j+1:j+caseEnd], nested, nested)
+				j += caseEnd
+			}
+		}
+	}
+}
+
+func qaTags(source string, target *string) []string {
+	if target == nil || *target == "" {
+		return nil
+	}
+	var msgs []string
+
+	// tags which are not balanced in the source already (like the
+	// "<Ctrl>" of a shortcut) are no markup, probably
+	sourceCount, sourceUnbalanced := qaScanTags(source)
+	targetCount, targetUnbalanced := qaScanTags(*target)
+	for _, msg := range targetUnbalanced {
+		if !qaContains(sourceUnbalanced, msg) {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	return append(msgs, qaCompareCounts("tag", sourceCount, targetCount)...)
+}
+
+// qaScanTags counts the tags of s and reports the ones which are not
+// balanced
+func qaScanTags(s string) (map[string]int, []string) {
+	var (
+		count = map[string]int{}
+		msgs  []string
+		open  []string
+	)
+	for _, m := range qaTagRx.FindAllStringSubmatch(s, -1) {
+		count["<"+m[1]+m[2]+m[3]+">"]++
+		switch {
+		case m[3] == "/":
+		case m[1] == "":
+			open = append(open, m[2])
+		case len(open) > 0 && open[len(open)-1] == m[2]:
+			open = open[:len(open)-1]
+		default:
+			msgs = append(msgs, fmt.Sprintf("closing tag </%s> without opening tag", m[2]))
+		}
+	}
+	for _, name := range open {
+		msgs = append(msgs, fmt.Sprintf("tag <%s> is not closed", name))
+	}
+	return count, msgs
+}
+
+func qaContains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// qaCompareCounts reports the items which are missing in or added to
+// the target
+func qaCompareCounts(what string, sourceCount, targetCount map[string]int) []string {
+	var missing, added []string
+	for item, n := range sourceCount {
+		if targetCount[item] < n {
+			missing = append(missing, fmt.Sprintf("%s %s is missing in target", what, item))
+		}
+	}
+	for item, n := range targetCount {
+		if sourceCount[item] < n {
+			added = append(added, fmt.Sprintf("%s %s is not part of source", what, item))
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(added)
+	return append(missing, added...)
+}
+
+func qaWhitespace(source string, target *string) []string {
+	if target == nil || strings.TrimSpace(*target) == "" {
+		return nil
+	}
+	var msgs []string
+	if qaLeading(source) != qaLeading(*target) {
+		msgs = append(msgs, fmt.Sprintf("leading whitespace differs: %q vs. %q", qaLeading(source), qaLeading(*target)))
+	}
+	if qaTrailing(source) != qaTrailing(*target) {
+		msgs = append(msgs, fmt.Sprintf("trailing whitespace differs: %q vs. %q", qaTrailing(source), qaTrailing(*target)))
+	}
+	return msgs
+}
+
+func qaLeading(s string) string {
+	return s[:len(s)-len(strings.TrimLeftFunc(s, unicode.IsSpace))]
+}
+
+func qaTrailing(s string) string {
+	return s[len(strings.TrimRightFunc(s, unicode.IsSpace)):]
+}
+
+func qaDoubleSpace(source string, target *string) []string {
+	if target == nil {
+		return nil
+	}
+	text := strings.TrimSpace(*target)
+	if strings.Contains(text, "  ") && !strings.Contains(strings.TrimSpace(source), "  ") {
+		return []string{"target contains doubled spaces"}
+	}
+	return nil
+}
+
+// full width punctuation (CJK) counts the same as its ASCII counterpart
+var qaPunctuationMap = map[rune]rune{
+	'.': '.', '。': '.', '．': '.',
+	'!': '!', '！': '!',
+	'?': '?', '？': '?',
+	':': ':', '：': ':',
+	';': ';', '；': ';',
+	',': ',', '，': ',', '、': ',',
+	'…': '…',
+}
+
+func qaPunctuation(source string, target *string) []string {
+	if target == nil || strings.TrimSpace(*target) == "" {
+		return nil
+	}
+	s, t := qaLastPunctuation(source), qaLastPunctuation(*target)
+	if s == t {
+		return nil
+	}
+	describe := func(r rune) string {
+		if r == 0 {
+			return "none"
+		}
+		return fmt.Sprintf("%q", r)
+	}
+	return []string{fmt.Sprintf("ends with %s, source with %s", describe(t), describe(s))}
+}
+
+// qaLastPunctuation returns the punctuation s ends with, 0 if none. "..."
+// is taken as "…".
+func qaLastPunctuation(s string) rune {
+	s = strings.TrimRightFunc(s, unicode.IsSpace)
+	if strings.HasSuffix(s, "...") {
+		return '…'
+	}
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return qaPunctuationMap[r]
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestQA(t *testing.T) {
+
+	qa := new(qaConv)
+	if err := qa.ParseArgs("xliffer", []string{"-in", "testdata/qa/app.xlf", "-severity", "empty=error", "-disable", "punctuation"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := qa.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.NewBuffer(nil)
+	if err := qa.Convert(out); err != errIssues {
+		t.Fatalf("expected errIssues, got %v", err)
+	}
+
+	expected := `error   placeholders (app): [placeholders] placeholder %2$d is missing in target
+error   placeholders (app): [placeholders] placeholder {user} is missing in target
+error   placeholders (app): [placeholders] placeholder {users} is not part of source
+error   tags (app): [tags] tag <a> is not closed
+error   tags (app): [tags] tag </a> is missing in target
+warning whitespace (app): [whitespace] trailing whitespace differs: " " vs. ""
+warning spaces (app): [double-space] target contains doubled spaces
+warning same (app): [untranslated] target is the same as source
+error   empty (app): [empty] target is empty
+9 issues: 6 errors, 3 warnings
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	// warnings only do not fail
+	qa.enable, qa.disable, qa.severity = "whitespace,double-space", "", ""
+	if err := qa.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if err := qa.Convert(bytes.NewBuffer(nil)); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if qa.severity = "tags=fatal"; qa.Prepare() == nil {
+		t.Errorf("expected an unsupported severity to be refused")
+	}
+}
+
+func TestQAPlaceholdersICU(t *testing.T) {
+	for _, c := range []struct {
+		source, target string
+		issues         int
+	}{
+		{"{gender, select, male{he} female{she} other{they}}", "{gender, select, male{er} female{sie} other{sie}}", 0},
+		{"{count, plural, one{1 file} other{{count} files}}", "{count, plural, one{1 plik} few{{count} pliki} many{{count} plików} other{{count} pliku}}", 0},
+		{"{name} has {count, plural, one{a cat} other{{count} cats}}", "{count, plural, one{eine Katze} other{{count} Katzen}}", 1},
+		{"{gender, select, male{{name} is} other{{name} are}}", "{gender, select, male{er ist} other{sie sind}}", 1},
+		{"Hello {{.Name}}", "Hallo {{.Nom}}", 2},
+	} {
+		target := c.target
+		if issues := qaPlaceholders(c.source, &target); len(issues) != c.issues {
+			t.Errorf("%q -> %q: expected %d issues, got %v", c.source, c.target, c.issues, issues)
+		}
+	}
+}
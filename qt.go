@@ -0,0 +1,305 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// a Qt Linguist .ts holds the messages of an application by context (the
+// class they are used in), with their source and translation:
+//
+//	<TS version="2.1" language="de_DE" sourcelanguage="en">
+//	<context>
+//	    <name>MainWindow</name>
+//	    <message numerus="yes">
+//	        <location filename="../src/mainwindow.cpp" line="80"/>
+//	        <source>%n file(s)</source>
+//	        <translation type="unfinished">
+//	            <numerusform>%n Datei</numerusform>
+//	            <numerusform>%n Dateien</numerusform>
+//	        </translation>
+//	    </message>
+//	</context>
+//	</TS>
+//
+// a context becomes a <group restype="x-qt-context">, a message a
+// trans-unit with id "<context>:<source>" ("|<comment>" added if the
+// message has a disambiguating comment), or the id of the message for
+// id based translations, kept in resname as well. a numerus message
+// becomes a <group restype="x-qt-numerus"> holding a unit per form
+// ("<id>[0]", "<id>[1]", ...). as for PO, the translator comment goes
+// into the note, locations into <context-group name="qt-location">,
+// comment and extracomment into <context-group name="qt-message">.
+//
+// a translation of type "unfinished" is a target which needs a review
+// (or none at all, if empty), "vanished" and "obsolete" are kept as the
+// states "x-qt-vanished" and "x-qt-obsolete".
+const (
+	QT_CONTEXT_RESTYPE = "x-qt-context"
+	QT_NUMERUS_RESTYPE = "x-qt-numerus"
+	QT_MESSAGE_GROUP   = "qt-message"
+	QT_LOCATION_GROUP  = "qt-location"
+)
+
+type qtTS struct {
+	XMLName        xml.Name    `xml:"TS"`
+	Version        string      `xml:"version,attr"`
+	Language       string      `xml:"language,attr"`
+	SourceLanguage string      `xml:"sourcelanguage,attr"`
+	Contexts       []qtContext `xml:"context"`
+}
+
+type qtContext struct {
+	Name     string      `xml:"name"`
+	Messages []qtMessage `xml:"message"`
+}
+
+type qtMessage struct {
+	ID                string        `xml:"id,attr"`
+	Numerus           string        `xml:"numerus,attr"`
+	Locations         []qtLocation  `xml:"location"`
+	Source            string        `xml:"source"`
+	Comment           string        `xml:"comment"`
+	ExtraComment      string        `xml:"extracomment"`
+	TranslatorComment string        `xml:"translatorcomment"`
+	Translation       qtTranslation `xml:"translation"`
+}
+
+type qtLocation struct {
+	Filename string `xml:"filename,attr"`
+	Line     string `xml:"line,attr"`
+}
+
+type qtTranslation struct {
+	Type  string   `xml:"type,attr"`
+	Text  string   `xml:",chardata"`
+	Forms []string `xml:"numerusform"`
+}
+
+func qtFromFile(fileName string) (*qtTS, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	ts := new(qtTS)
+	if err = xml.Unmarshal(data, ts); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+
+	// lupdate writes locations relative to the previous one within a
+	// context: the file left out if it is the same, the line as +n/-n
+	// from the previous line of that file
+	for i := range ts.Contexts {
+		file, lines := "", map[string]int{}
+		for j := range ts.Contexts[i].Messages {
+			locations := ts.Contexts[i].Messages[j].Locations
+			for k := range locations {
+				loc := &locations[k]
+				if loc.Filename == "" {
+					loc.Filename = file
+				}
+				file = loc.Filename
+				n, err := strconv.Atoi(strings.TrimPrefix(loc.Line, "+"))
+				if err != nil {
+					continue
+				}
+				if strings.HasPrefix(loc.Line, "+") || strings.HasPrefix(loc.Line, "-") {
+					n += lines[file]
+				}
+				lines[file] = n
+				loc.Line = strconv.Itoa(n)
+			}
+		}
+	}
+	return ts, nil
+}
+
+// unitID returns the id of the unit of a message
+func (msg *qtMessage) unitID(context string) string {
+	if msg.ID != "" {
+		return msg.ID
+	}
+	id := context + ":" + msg.Source
+	if msg.Comment != "" {
+		id += "|" + msg.Comment
+	}
+	return id
+}
+
+func (msg *qtMessage) isNumerus() bool {
+	return msg.Numerus == "yes"
+}
+
+// metaTo stores comments and locations of msg in unit
+func (msg *qtMessage) metaTo(unit *xliffTransUnit) {
+
+	unit.Note = msg.TranslatorComment
+	if msg.ID != "" {
+		setAttr(&unit.Attrs, "resname", msg.ID)
+	}
+	if msg.Comment != "" {
+		unit.addContext(QT_MESSAGE_GROUP, "information", "x-qt-comment", msg.Comment)
+	}
+	if msg.ExtraComment != "" {
+		unit.addContext(QT_MESSAGE_GROUP, "information", "x-qt-extracomment", msg.ExtraComment)
+	}
+	for _, loc := range msg.Locations {
+		cg := xliffContextGroup{Name: QT_LOCATION_GROUP, Purpose: "location"}
+		cg.Context = append(cg.Context, xliffContext{Type: "sourcefile", Inner: loc.Filename})
+		if loc.Line != "" {
+			cg.Context = append(cg.Context, xliffContext{Type: "linenumber", Inner: loc.Line})
+		}
+		unit.ContextGroup = append(unit.ContextGroup, cg)
+	}
+}
+
+// metaFrom takes comments and locations of msg from unit. any
+// context-group with purpose="location" is a location.
+func (msg *qtMessage) metaFrom(unit *xliffTransUnit) {
+
+	msg.TranslatorComment = unit.Note
+	msg.ID = attrValue(unit.Attrs, "resname")
+	msg.Comment = unit.context(QT_MESSAGE_GROUP, "x-qt-comment")
+	msg.ExtraComment = unit.context(QT_MESSAGE_GROUP, "x-qt-extracomment")
+	for _, cg := range unit.ContextGroup {
+		if cg.Purpose != "location" {
+			continue
+		}
+		var loc qtLocation
+		for _, ctx := range cg.Context {
+			switch ctx.Type {
+			case "sourcefile":
+				loc.Filename = ctx.Inner
+			case "linenumber":
+				loc.Line = ctx.Inner
+			}
+		}
+		if loc.Filename != "" {
+			msg.Locations = append(msg.Locations, loc)
+		}
+	}
+}
+
+// xliffState returns the state of a target for the type of a translation,
+// qtType the other way around
+func (tr *qtTranslation) xliffState() string {
+	switch tr.Type {
+	case "":
+		return "translated"
+	case "unfinished":
+		return STATE_NEEDS_REVIEW
+	}
+	return "x-qt-" + tr.Type
+}
+
+func qtType(state string) string {
+	switch {
+	case strings.HasPrefix(state, "x-qt-"):
+		return strings.TrimPrefix(state, "x-qt-")
+	case state == "" || state == "new" || strings.HasPrefix(state, "needs-"):
+		return "unfinished"
+	}
+	return ""
+}
+
+// qtNumerusForms returns how many numerus forms Qt expects for lang
+func qtNumerusForms(lang string) int {
+	switch strings.SplitN(normLang(lang), "-", 2)[0] {
+	case "ja", "ko", "zh", "vi", "th", "id", "ms", "tr":
+		return 1
+	case "pl", "ru", "uk", "be", "cs", "sk", "hr", "sr", "bs", "lt", "lv", "ro":
+		return 3
+	case "sl", "cy":
+		return 4
+	case "ga":
+		return 5
+	case "ar":
+		return 6
+	}
+	return 2
+}
+
+// writeTS writes ts the way lupdate does
+func writeTS(w io.Writer, ts *qtTS) error {
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<!DOCTYPE TS>\n")
+	buf.WriteString("<TS version=\"" + qtEscape(ts.Version) + "\"")
+	if ts.Language != "" {
+		buf.WriteString(" language=\"" + qtEscape(ts.Language) + "\"")
+	}
+	if ts.SourceLanguage != "" {
+		buf.WriteString(" sourcelanguage=\"" + qtEscape(ts.SourceLanguage) + "\"")
+	}
+	buf.WriteString(">\n")
+
+	for _, ctx := range ts.Contexts {
+		buf.WriteString("<context>\n    <name>" + qtEscape(ctx.Name) + "</name>\n")
+		for _, msg := range ctx.Messages {
+			buf.WriteString("    <message")
+			if msg.ID != "" {
+				buf.WriteString(" id=\"" + qtEscape(msg.ID) + "\"")
+			}
+			if msg.isNumerus() {
+				buf.WriteString(" numerus=\"yes\"")
+			}
+			buf.WriteString(">\n")
+			for _, loc := range msg.Locations {
+				buf.WriteString("        <location filename=\"" + qtEscape(loc.Filename) + "\"")
+				if loc.Line != "" {
+					buf.WriteString(" line=\"" + qtEscape(loc.Line) + "\"")
+				}
+				buf.WriteString("/>\n")
+			}
+			buf.WriteString("        <source>" + qtEscape(msg.Source) + "</source>\n")
+			for _, c := range []struct{ name, text string }{
+				{"comment", msg.Comment},
+				{"extracomment", msg.ExtraComment},
+				{"translatorcomment", msg.TranslatorComment},
+			} {
+				if c.text != "" {
+					buf.WriteString("        <" + c.name + ">" + qtEscape(c.text) + "</" + c.name + ">\n")
+				}
+			}
+
+			buf.WriteString("        <translation")
+			if msg.Translation.Type != "" {
+				buf.WriteString(" type=\"" + msg.Translation.Type + "\"")
+			}
+			switch {
+			case msg.isNumerus():
+				buf.WriteString(">\n")
+				for _, form := range msg.Translation.Forms {
+					buf.WriteString("            <numerusform>" + qtEscape(form) + "</numerusform>\n")
+				}
+				buf.WriteString("        </translation>\n")
+			default:
+				buf.WriteString(">" + qtEscape(msg.Translation.Text) + "</translation>\n")
+			}
+			buf.WriteString("    </message>\n")
+		}
+		buf.WriteString("</context>\n")
+	}
+	buf.WriteString("</TS>\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// qtEscape escapes text as lupdate does, quotes included
+func qtEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;", "'", "&apos;").Replace(s)
+}
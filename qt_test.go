@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQtTSRoundTrip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "qt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "de.xlf")
+
+	doc := convertXliff(t, &fromQtTS{inFile: "testdata/qt/app_de.ts"}, xlf)
+	if file := doc.File[0]; file.SourceLang != "en" || file.TargetLang != "de-DE" {
+		t.Errorf("expected en -> de-DE, got %s -> %s", file.SourceLang, file.TargetLang)
+	}
+	checkUnits(t, &doc.File[0].Body, qtDescribeUnit, map[string]string{
+		"MainWindow:&File":         "&amp;Datei [translated]",
+		"MainWindow:Open|verb":     "Öffnen [needs-review-translation]",
+		"MainWindow:Save \"%1\"?":  " []",
+		"MainWindow:Old entry":     "Alter Eintrag [x-qt-vanished]",
+		"MainWindow:%n file(s)[0]": "%n Datei [translated]",
+		"MainWindow:%n file(s)[1]": "%n Dateien [translated]",
+		"dialog_ok":                "OK [translated]",
+	})
+
+	checkConverted(t, &toQtTS{inFile: xlf}, "testdata/qt/app_de.ts")
+}
+
+func qtDescribeUnit(unit *xliffTransUnit) string {
+	return unitTarget(unit) + " [" + unitState(unit) + "]"
+}
+
+func TestQtTSRelativeLocations(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "qt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.ts")
+	data := `<TS version="2.1" language="pl">
+<context>
+    <name>A</name>
+    <message numerus="yes">
+        <location filename="a.cpp" line="10"/>
+        <location line="+5"/>
+        <source>%n file(s)</source>
+        <translation type="unfinished">
+            <numerusform>%n plik</numerusform>
+        </translation>
+    </message>
+    <message>
+        <location filename="b.cpp" line="+3"/>
+        <location filename="a.cpp" line="-2"/>
+        <source>B</source>
+        <translation type="obsolete">b</translation>
+    </message>
+</context>
+</TS>
+`
+	if err = ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := qtFromFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locations []string
+	for _, msg := range ts.Contexts[0].Messages {
+		for _, loc := range msg.Locations {
+			locations = append(locations, loc.Filename+":"+loc.Line)
+		}
+	}
+	if s := strings.Join(locations, " "); s != "a.cpp:10 a.cpp:15 b.cpp:3 a.cpp:13" {
+		t.Errorf("unexpected locations %s", s)
+	}
+
+	doc := convertXliff(t, &fromQtTS{inFile: name}, filepath.Join(dir, "pl.xlf"))
+	// polish has three forms
+	checkUnits(t, &doc.File[0].Body, qtDescribeUnit, map[string]string{
+		"A:%n file(s)[0]": "%n plik [needs-review-translation]",
+		"A:%n file(s)[1]": " []",
+		"A:%n file(s)[2]": " []",
+		"A:B":             "b [x-qt-obsolete]",
+	})
+}
@@ -0,0 +1,190 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// a .resx of .NET holds the resources of an assembly, strings and others
+// (images, ...), after a header describing the format:
+//
+//	<root>
+//	  <resheader name="resmimetype">
+//	    <value>text/microsoft-resx</value>
+//	  </resheader>
+//	  ...
+//	  <data name="Greeting" xml:space="preserve">
+//	    <value>Hello</value>
+//	    <comment>shown on the start page</comment>
+//	  </data>
+//	  <data name="Logo" type="System.Drawing.Bitmap, System.Drawing" mimetype="...">
+//	    <value>...</value>
+//	  </data>
+//	</root>
+//
+// a string becomes a trans-unit with the name as id and the comment as
+// note. resources with a type or mimetype are not strings, they are
+// skipped. the satellite assemblies of the languages are built from
+// Resources.<lang>.resx, which are written from the neutral .resx: its
+// header and other resources as they are, the strings translated.
+type resxData struct {
+	Name     string `xml:"name,attr"`
+	Type     string `xml:"type,attr"`
+	MimeType string `xml:"mimetype,attr"`
+	Value    string `xml:"value"`
+	Comment  string `xml:"comment"`
+}
+
+type resxDoc struct {
+	XMLName xml.Name   `xml:"root"`
+	Data    []resxData `xml:"data"`
+
+	raw *xmlNode // the document as read
+}
+
+func (data *resxData) isString() bool {
+	return data.Type == "" && data.MimeType == ""
+}
+
+func resxFromFile(fileName string) (*resxDoc, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	doc := new(resxDoc)
+	if err = xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	if doc.raw, err = parseXMLNodes(data); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	return doc, nil
+}
+
+// resxLang returns the language of a .resx by its name
+// (Resources.de-DE.resx -> de-DE), "" for the neutral one
+func resxLang(fileName string) string {
+	name := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	if ext := filepath.Ext(name); ext != "" {
+		return ext[1:]
+	}
+	return ""
+}
+
+// resxSatellite returns the name of the .resx of lang next to the
+// neutral one
+func resxSatellite(fileName, lang string) string {
+	return strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName)) + "." + lang + ".resx"
+}
+
+// write writes the document with the values of its strings replaced by
+// values. strings which are not in values are left out unless keep is
+// set.
+func (doc *resxDoc) write(w io.Writer, values map[string]string, keep bool) error {
+
+	var root *xmlNode
+	for _, node := range doc.raw.children {
+		if node.isElement() {
+			root = node
+		}
+	}
+	if root == nil {
+		return fmt.Errorf("missing <root>")
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, node := range doc.raw.children {
+		if node != root {
+			node.writeTo(buf)
+			continue
+		}
+
+		buf.Write(root.raw)
+		var pending []*xmlNode // the space before the next element
+		i := 0
+		for _, child := range root.children {
+			if !child.isElement() || child.local() != "data" {
+				if child.isSpace() {
+					pending = append(pending, child)
+					continue
+				}
+				writeNodes(buf, pending)
+				pending = nil
+				child.writeTo(buf)
+				continue
+			}
+
+			data := &doc.Data[i]
+			i++
+			value, ok := values[data.Name]
+			if !data.isString() || (keep && !ok) {
+				writeNodes(buf, pending)
+				pending = nil
+				child.writeTo(buf)
+				continue
+			}
+			if !ok {
+				pending = nil
+				continue
+			}
+
+			writeNodes(buf, pending)
+			pending = nil
+			writeResxData(buf, child, value)
+		}
+		writeNodes(buf, pending)
+		buf.Write(root.rawEnd)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeNodes(buf *bytes.Buffer, nodes []*xmlNode) {
+	for _, node := range nodes {
+		node.writeTo(buf)
+	}
+}
+
+// writeResxData writes a <data> with the content of its <value> replaced
+func writeResxData(buf *bytes.Buffer, data *xmlNode, value string) {
+	if data.selfClosing() {
+		buf.Write(bytes.TrimRight(bytes.TrimSuffix(data.raw, []byte("/>")), " \t\r\n"))
+		buf.WriteString("><value>" + escapedText(value) + "</value></data>")
+		return
+	}
+
+	buf.Write(data.raw)
+	written := false
+	for _, child := range data.children {
+		if !child.isElement() || child.local() != "value" || written {
+			child.writeTo(buf)
+			continue
+		}
+		written = true
+		if child.selfClosing() {
+			buf.WriteString("<value>" + escapedText(value) + "</value>")
+			continue
+		}
+		buf.Write(child.raw)
+		buf.WriteString(escapedText(value))
+		buf.Write(child.rawEnd)
+	}
+	if !written {
+		buf.WriteString("<value>" + escapedText(value) + "</value>")
+	}
+	buf.Write(data.rawEnd)
+}
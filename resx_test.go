@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResxRoundTrip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "resx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "de.xlf")
+
+	doc := convertXliff(t, &fromResx{
+		inFile:     "testdata/resx/Resources.resx",
+		targetFile: "testdata/resx/Resources.de.resx",
+		sourceLang: "en",
+		targetLang: resxLang("testdata/resx/Resources.de.resx"),
+	}, xlf)
+	checkUnits(t, &doc.File[0].Body, describeUnit, map[string]string{
+		"AppTitle":  "Notes & more -> Notizen &amp; mehr # Title of the main window",
+		"Greeting":  "Hello {0}! -> Hallo {0}! # ",
+		"Multiline": "First line\nSecond line ->  # ",
+	})
+
+	for _, source := range []bool{false, true} {
+		name := "Resources.de.resx"
+		if source {
+			name = "Resources.resx"
+		}
+		tr := &toResx{template: "testdata/resx/Resources.resx", inFiles: []string{xlf}, outDir: dir, source: source}
+		if err = tr.Convert(ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		checkFile(t, filepath.Join(dir, name), filepath.Join("testdata/resx", name))
+	}
+}
+
+func TestResxLang(t *testing.T) {
+	for name, lang := range map[string]string{
+		"Resources.resx":                 "",
+		"Resources.de.resx":              "de",
+		"Strings/Resources.zh-Hant.resx": "zh-Hant",
+	} {
+		if got := resxLang(name); got != lang {
+			t.Errorf("%s: expected %q, got %q", name, lang, got)
+		}
+	}
+}
+
+// every input is written to the resources of its language
+func TestResxInputs(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "resx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var inFiles []string
+	for _, lang := range []string{"de", "fr"} {
+		xlf := filepath.Join(dir, lang+".xlf")
+		convertXliff(t, &fromResx{
+			inFile:     "testdata/resx/Resources.resx",
+			targetFile: "testdata/resx/Resources.de.resx",
+			sourceLang: "en",
+			targetLang: lang,
+		}, xlf)
+		inFiles = append(inFiles, xlf)
+	}
+	tr := &toResx{template: "testdata/resx/Resources.resx", inFiles: inFiles, outDir: dir}
+	if err = tr.Convert(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Resources.de.resx", "Resources.fr.resx"} {
+		checkFile(t, filepath.Join(dir, name), "testdata/resx/Resources.de.resx")
+	}
+}
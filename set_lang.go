@@ -9,7 +9,6 @@
 package main
 
 import (
-	"encoding/xml"
 	"flag"
 	"io"
 )
@@ -20,6 +19,7 @@ type setLang struct {
 	inFile     string
 	sourceLang string
 	targetLang string
+	version    string
 }
 
 const _KEEP = "keep"
@@ -35,6 +35,7 @@ func (s *setLang) Description() string {
 func (s *setLang) ParseArgs(base string, args []string) error {
 	var fs = flag.NewFlagSet(base+" set-lang", flag.ExitOnError)
 	fs.StringVar(&s.inFile, "in", "", "infile")
+	xliffVersionFlag(fs, &s.version)
 	fs.StringVar(&s.targetLang, "target", _KEEP, "target language")
 	fs.StringVar(&s.sourceLang, "source", _KEEP, "source language")
 	return fs.Parse(args)
@@ -54,23 +55,21 @@ func (s *setLang) Convert(w io.Writer) error {
 	for i := range doc.File {
 
 		setOrKeep(&doc.File[i].SourceLang, s.sourceLang)
-		setOrKeep(&doc.File[i].SourceLang, s.sourceLang)
+		setOrKeep(&doc.File[i].TargetLang, s.targetLang)
 
-		for j := range doc.File[i].Body.TransUnit {
-			setOrKeep(&doc.File[i].Body.TransUnit[j].Source.Lang, s.sourceLang)
-			setOrKeep(&doc.File[i].Body.TransUnit[j].Target.Lang, s.targetLang)
+		for _, unit := range doc.File[i].Body.Units() {
+			setOrKeep(&unit.Source.Lang, s.sourceLang)
+			if unit.Target != nil {
+				setOrKeep(&unit.Target.Lang, s.targetLang)
+			}
 		}
 	}
 
-	var out []byte
-	if out, err = xml.MarshalIndent(doc, "", "  "); err != nil {
+	if err = doc.SetVersion(s.version); err != nil {
 		return err
 	}
 
-	io.WriteString(w, xml.Header)
-	w.Write(out)
-
-	return nil
+	return writeXliff(w, doc, "  ")
 }
 
 func setOrKeep(to *string, from string) {
@@ -0,0 +1,263 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// statsConv counts the units and words of XLIFF files and how much of
+// them is translated, per file and per target language
+type statsConv struct {
+	inFiles []string
+	format  string
+}
+
+const (
+	STATS_TABLE = "table"
+	STATS_CSV   = "csv"
+	STATS_JSON  = "json"
+)
+
+// statsRow holds the counts of a file or a language. units which are not
+// to be translated are left out.
+type statsRow struct {
+	File         string  `json:"file,omitempty"`
+	TargetLang   string  `json:"target-lang"`
+	Units        int     `json:"units"`
+	Words        int     `json:"words"`
+	Chars        int     `json:"chars"`
+	Translated   int     `json:"translated"`
+	NeedsReview  int     `json:"needs-review"`
+	Untranslated int     `json:"untranslated"`
+	Repetitions  int     `json:"repetitions"`
+	Complete     float64 `json:"complete"` // percent of the words translated
+
+	translatedWords int
+	sources         map[string]bool // to find repetitions, nil for the total
+}
+
+type statsReport struct {
+	Files     []*statsRow `json:"files"`
+	Languages []*statsRow `json:"languages"`
+	Total     *statsRow   `json:"total"`
+}
+
+func init() {
+	registeredConverters["stats"] = new(statsConv)
+}
+
+func (s *statsConv) Description() string {
+	return "Counts units and words of XLIFF files and their progress"
+}
+
+func (s *statsConv) ParseArgs(base string, args []string) error {
+	var inFile string
+	var fs = flag.NewFlagSet(base+" stats", flag.ExitOnError)
+	fs.StringVar(&inFile, "in", "", "infile (more files can follow as arguments)")
+	fs.StringVar(&s.format, "format", STATS_TABLE, "output format (table, csv, json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch s.format {
+	case STATS_TABLE, STATS_CSV, STATS_JSON:
+	default:
+		return fmt.Errorf("unsupported 'format': %q", s.format)
+	}
+	if inFile != "" {
+		s.inFiles = append(s.inFiles, inFile)
+	}
+	s.inFiles = append(s.inFiles, fs.Args()...)
+	if len(s.inFiles) == 0 {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (s *statsConv) Prepare() error {
+	return nil
+}
+
+func (s *statsConv) Convert(w io.Writer) error {
+
+	// repetitions are counted per target language only, the total sums
+	// them up
+	report := &statsReport{Total: &statsRow{}}
+	langs := map[string]*statsRow{}
+
+	for _, name := range s.inFiles {
+		doc, err := xliffFromFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+
+		// a row per target language of the file
+		rows := map[string]*statsRow{}
+		for _, file := range doc.File {
+			row := rows[file.TargetLang]
+			if row == nil {
+				row = newStatsRow(name, file.TargetLang)
+				rows[file.TargetLang] = row
+				report.Files = append(report.Files, row)
+			}
+			lang := langs[normLang(file.TargetLang)]
+			if lang == nil {
+				lang = newStatsRow("", file.TargetLang)
+				langs[normLang(file.TargetLang)] = lang
+				report.Languages = append(report.Languages, lang)
+			}
+
+			for _, unit := range file.Body.Units() {
+				if attrValue(unit.Attrs, "translate") == "no" {
+					continue
+				}
+				row.add(unit)
+				lang.add(unit)
+				report.Total.add(unit)
+			}
+		}
+	}
+
+	for _, lang := range report.Languages {
+		report.Total.Repetitions += lang.Repetitions
+	}
+	sort.SliceStable(report.Languages, func(i, j int) bool {
+		return report.Languages[i].TargetLang < report.Languages[j].TargetLang
+	})
+	for _, row := range append(append(report.Files, report.Languages...), report.Total) {
+		row.Complete = percent(row.translatedWords, row.Words)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	switch s.format {
+	case STATS_JSON:
+		out, err := json.MarshalIndent(report, "", "\t")
+		if err != nil {
+			return err
+		}
+		buf.Write(out)
+		buf.WriteString("\n")
+	case STATS_CSV:
+		if err := report.writeCSV(buf); err != nil {
+			return err
+		}
+	default:
+		report.writeTable(buf)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func newStatsRow(file, targetLang string) *statsRow {
+	return &statsRow{File: file, TargetLang: targetLang, sources: map[string]bool{}}
+}
+
+// add counts unit. a unit is untranslated if its target is missing,
+// empty or marked as new or needing a translation; it needs a review if
+// its state is any other needs-*.
+func (row *statsRow) add(unit *xliffTransUnit) {
+
+	source := unit.Source.Text(INLINE_TEXT_ONLY)
+	words := countWords(source)
+	row.Units++
+	row.Words += words
+	row.Chars += utf8.RuneCountInString(source)
+
+	if row.sources != nil {
+		if row.sources[source] {
+			row.Repetitions++
+		}
+		row.sources[source] = true
+	}
+
+	state := ""
+	if unit.Target != nil {
+		state = unit.Target.State
+	}
+	switch {
+	case unit.Target == nil || strings.TrimSpace(unit.Target.Inner) == "",
+		state == "new", state == "needs-translation":
+		row.Untranslated++
+	case strings.HasPrefix(state, "needs-"):
+		row.NeedsReview++
+	default:
+		row.Translated++
+		row.translatedWords += words
+	}
+}
+
+// name returns the file or the language a row is about
+func (row *statsRow) name() string {
+	switch {
+	case row.File != "":
+		return row.File
+	case row.TargetLang == "":
+		return "(no target-language)"
+	}
+	return row.TargetLang
+}
+
+var statsColumns = []string{"units", "words", "chars", "translated", "needs-review", "untranslated", "repetitions", "complete"}
+
+func (row *statsRow) columns() []string {
+	return []string{
+		fmt.Sprint(row.Units), fmt.Sprint(row.Words), fmt.Sprint(row.Chars),
+		fmt.Sprint(row.Translated), fmt.Sprint(row.NeedsReview), fmt.Sprint(row.Untranslated),
+		fmt.Sprint(row.Repetitions), fmt.Sprintf("%.1f", row.Complete),
+	}
+}
+
+// writeTable writes the files, then the languages and the total, each
+// block under its own heading
+func (report *statsReport) writeTable(buf *bytes.Buffer) {
+
+	tw := tabwriter.NewWriter(buf, 0, 8, 2, ' ', 0)
+	line := func(cells ...string) {
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	line(append([]string{"file", "target-lang"}, statsColumns...)...)
+	for _, row := range report.Files {
+		line(append([]string{row.File, row.TargetLang}, row.columns()...)...)
+	}
+	line()
+	line(append([]string{"", "target-lang"}, statsColumns...)...)
+	for _, row := range report.Languages {
+		line(append([]string{"", row.name()}, row.columns()...)...)
+	}
+	line(append([]string{"", "total"}, report.Total.columns()...)...)
+	tw.Flush()
+}
+
+// writeCSV writes a line per file, language and the total, the first
+// column telling which of them
+func (report *statsReport) writeCSV(buf *bytes.Buffer) error {
+
+	cw := csv.NewWriter(buf)
+	cw.Write(append([]string{"scope", "name", "target-lang"}, statsColumns...))
+	for _, row := range report.Files {
+		cw.Write(append([]string{"file", row.File, row.TargetLang}, row.columns()...))
+	}
+	for _, row := range report.Languages {
+		cw.Write(append([]string{"language", row.name(), row.TargetLang}, row.columns()...))
+	}
+	cw.Write(append([]string{"total", "total", ""}, report.Total.columns()...))
+	cw.Flush()
+	return cw.Error()
+}
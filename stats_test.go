@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+
+	s := &statsConv{inFiles: []string{"testdata/tmx/app-de.xlf", "testdata/tmx/app-fr.xlf", "testdata/tm/translated.xlf"}, format: STATS_JSON}
+	out := bytes.NewBuffer(nil)
+	if err := s.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	var report statsReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Files) != 3 || len(report.Languages) != 2 {
+		t.Fatalf("expected 3 files and 2 languages, got %d and %d", len(report.Files), len(report.Languages))
+	}
+
+	// app-de.xlf: "quit" needs a translation
+	if f := report.Files[0]; f.Units != 3 || f.Words != 5 || f.Translated != 2 || f.Untranslated != 1 || f.Complete != 80 {
+		t.Errorf("unexpected counts for app-de.xlf: %+v", *f)
+	}
+	// the draft of translated.xlf needs a review
+	de := report.Languages[0]
+	if de.TargetLang != "de" || de.Units != 5 || de.NeedsReview != 1 || de.Translated != 3 {
+		t.Errorf("unexpected counts for de: %+v", *de)
+	}
+	// "Hello" and "Quit" of app-fr.xlf are in app-de.xlf as well, but
+	// translated into another language: no repetitions
+	if report.Total.Units != 7 || report.Total.Repetitions != 0 || report.Languages[1].Repetitions != 0 {
+		t.Errorf("unexpected total: %+v", *report.Total)
+	}
+}
@@ -9,7 +9,6 @@
 package main
 
 import (
-	"encoding/xml"
 	"flag"
 	"io"
 )
@@ -18,7 +17,8 @@ import (
 // usefull when one has to work with .xliff files coming from sources not
 // savy in using their xliff-editors correctly.
 type swapSourceTarget struct {
-	inFile string
+	inFile  string
+	version string
 }
 
 func init() {
@@ -32,6 +32,7 @@ func (s *swapSourceTarget) Description() string {
 func (s *swapSourceTarget) ParseArgs(base string, args []string) error {
 	var fs = flag.NewFlagSet(base+" swap-source-target", flag.ExitOnError)
 	fs.StringVar(&s.inFile, "in", "", "infile")
+	xliffVersionFlag(fs, &s.version)
 	return fs.Parse(args)
 }
 
@@ -48,27 +49,27 @@ func (s *swapSourceTarget) Convert(w io.Writer) error {
 
 	for i := range doc.File {
 		doc.File[i].TargetLang = ""
-		for j := range doc.File[i].Body.TransUnit {
-			src := doc.File[i].Body.TransUnit[j].Source.Inner
-			target := doc.File[i].Body.TransUnit[j].Target.Inner
+		for _, unit := range doc.File[i].Body.Units() {
+			if unit.Target == nil {
+				unit.Target = new(xliffTarget)
+			}
+			src := unit.Source.Inner
+			target := unit.Target.Inner
 
-			sname := doc.File[i].Body.TransUnit[j].Source.XMLName
-			tname := doc.File[i].Body.TransUnit[j].Target.XMLName
+			sname := unit.Source.XMLName
+			tname := unit.Target.XMLName
 
-			doc.File[i].Body.TransUnit[j].Source.Inner = target
-			doc.File[i].Body.TransUnit[j].Target.Inner = src
-			doc.File[i].Body.TransUnit[j].Source.XMLName = sname
-			doc.File[i].Body.TransUnit[j].Target.XMLName = tname
+			unit.Source.Inner = target
+			unit.Target.Inner = src
+			unit.Source.Content, unit.Target.Content = unit.Target.Content, unit.Source.Content
+			unit.Source.XMLName = sname
+			unit.Target.XMLName = tname
 		}
 	}
 
-	var out []byte
-	if out, err = xml.MarshalIndent(doc, "", "  "); err != nil {
+	if err = doc.SetVersion(s.version); err != nil {
 		return err
 	}
 
-	io.WriteString(w, xml.Header)
-	w.Write(out)
-
-	return nil
+	return writeXliff(w, doc, "  ")
 }
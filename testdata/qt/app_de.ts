@@ -0,0 +1,46 @@
+<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE TS>
+<TS version="2.1" language="de_DE" sourcelanguage="en">
+<context>
+    <name>MainWindow</name>
+    <message>
+        <location filename="../src/mainwindow.cpp" line="42"/>
+        <location filename="../src/mainwindow.ui" line="12"/>
+        <source>&amp;File</source>
+        <translation>&amp;Datei</translation>
+    </message>
+    <message>
+        <location filename="../src/mainwindow.cpp" line="50"/>
+        <source>Open</source>
+        <comment>verb</comment>
+        <extracomment>menu entry opening a file</extracomment>
+        <translation type="unfinished">Öffnen</translation>
+    </message>
+    <message>
+        <location filename="../src/mainwindow.cpp" line="51"/>
+        <source>Save &quot;%1&quot;?</source>
+        <translatorcomment>keep the quotes</translatorcomment>
+        <translation type="unfinished"></translation>
+    </message>
+    <message>
+        <source>Old entry</source>
+        <translation type="vanished">Alter Eintrag</translation>
+    </message>
+    <message numerus="yes">
+        <location filename="../src/mainwindow.cpp" line="80"/>
+        <source>%n file(s)</source>
+        <translation>
+            <numerusform>%n Datei</numerusform>
+            <numerusform>%n Dateien</numerusform>
+        </translation>
+    </message>
+</context>
+<context>
+    <name>Dialog</name>
+    <message id="dialog_ok">
+        <location filename="../src/dialog.cpp" line="10"/>
+        <source>OK</source>
+        <translation>OK</translation>
+    </message>
+</context>
+</TS>
@@ -0,0 +1,352 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// the translation memory is a JSON file holding source/target pairs per
+// language pair. texts are kept as XML, so inline elements survive:
+//
+//	[
+//		{
+//			"source-lang": "en",
+//			"target-lang": "de",
+//			"source": "Hello <x id=\"1\"/>",
+//			"target": "Hallo <x id=\"1\"/>",
+//			"origin": "app-de.xlf"
+//		}
+//	]
+//
+// a source is kept once per language pair, the translation imported last
+// wins.
+
+type tmEntry struct {
+	SourceLang string `json:"source-lang"`
+	TargetLang string `json:"target-lang"`
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+	Origin     string `json:"origin,omitempty"`
+
+	plain []rune // plain text of Source, set by fuzzy
+}
+
+type transMemory struct {
+	entries map[string]*tmEntry // by tmKey
+}
+
+// tmImport adds the translations of XLIFF and TMX files to a translation
+// memory
+type tmImport struct {
+	tmFile  string
+	inFiles []string
+	review  bool
+}
+
+func init() {
+	registeredConverters["tm-import"] = new(tmImport)
+}
+
+func (ti *tmImport) Description() string {
+	return "Imports XLIFF and TMX files into a translation memory"
+}
+
+func (ti *tmImport) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" tm-import", flag.ExitOnError)
+	fs.StringVar(&ti.tmFile, "tm", "", "translation memory to update (created if missing)")
+	fs.BoolVar(&ti.review, "needs-review", false, "import translations which need a review as well")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ti.inFiles = fs.Args()
+	if ti.tmFile == "" {
+		return fmt.Errorf("missing -tm")
+	}
+	return nil
+}
+
+func (ti *tmImport) Prepare() error {
+	return nil
+}
+
+// Convert updates the translation memory in place and writes a summary
+func (ti *tmImport) Convert(w io.Writer) error {
+
+	tm, err := loadTM(ti.tmFile)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range ti.inFiles {
+		var added, updated int
+		if isTMX(name) {
+			doc, err := tmxFromFile(name)
+			if err != nil {
+				return fmt.Errorf("%s: %s", name, err)
+			}
+			added, updated = tm.importTMX(doc, filepath.Base(name))
+		} else {
+			doc, err := xliffFromFile(name)
+			if err != nil {
+				return fmt.Errorf("%s: %s", name, err)
+			}
+			added, updated = tm.importXliff(doc, filepath.Base(name), ti.review)
+		}
+		fmt.Fprintf(w, "%s: %d added, %d updated\n", name, added, updated)
+	}
+
+	if err = tm.save(ti.tmFile); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s: %d entries\n", ti.tmFile, len(tm.entries))
+	return nil
+}
+
+func tmKey(sourceLang, targetLang, source string) string {
+	return normLang(sourceLang) + "\n" + normLang(targetLang) + "\n" + source
+}
+
+// loadTM reads a translation memory, a missing file is an empty one
+func loadTM(fileName string) (*transMemory, error) {
+
+	tm := &transMemory{entries: map[string]*tmEntry{}}
+	data, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return tm, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []tmEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	for _, e := range entries {
+		tm.add(e)
+	}
+	return tm, nil
+}
+
+// save writes the translation memory, sorted by language pair and source
+func (tm *transMemory) save(fileName string) error {
+
+	keys := make([]string, 0, len(tm.entries))
+	for key := range tm.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]*tmEntry, len(keys))
+	for i, key := range keys {
+		entries[i] = tm.entries[key]
+	}
+
+	// inline elements are kept readable, without \u003c
+	buf := bytes.NewBuffer(nil)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(entries); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, buf.Bytes(), 0644)
+}
+
+// add adds e, replacing the entry for the same source. it reports if e
+// was added (or replaced an entry) and if it was new.
+func (tm *transMemory) add(e tmEntry) (changed, isNew bool) {
+	key := tmKey(e.SourceLang, e.TargetLang, e.Source)
+	old, exists := tm.entries[key]
+	if exists && old.Target == e.Target {
+		return false, false
+	}
+	tm.entries[key] = &e
+	return true, !exists
+}
+
+// importXliff adds the translated units of doc. translations which need
+// a review are left out unless review is set.
+func (tm *transMemory) importXliff(doc *xliffDoc, origin string, review bool) (added, updated int) {
+
+	for _, file := range doc.File {
+		file.Body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+			if unit.Target == nil || strings.TrimSpace(unit.Target.Inner) == "" {
+				return
+			}
+			state := unit.Target.State
+			if state == "new" || (strings.HasPrefix(state, "needs-") && !(review && strings.HasPrefix(state, "needs-review-"))) {
+				return
+			}
+			targetLang := file.TargetLang
+			if unit.Target.Lang != "" {
+				targetLang = unit.Target.Lang
+			}
+			changed, isNew := tm.add(tmEntry{
+				SourceLang: file.SourceLang,
+				TargetLang: targetLang,
+				Source:     unit.Source.Text(INLINE_XML),
+				Target:     unit.Target.Text(INLINE_XML),
+				Origin:     origin,
+			})
+			switch {
+			case isNew:
+				added++
+			case changed:
+				updated++
+			}
+		})
+	}
+	return added, updated
+}
+
+// importTMX adds the translation units of doc, one entry per target
+// language
+func (tm *transMemory) importTMX(doc *tmxDoc, origin string) (added, updated int) {
+
+	for i := range doc.TU {
+		tu := &doc.TU[i]
+		srcLang := tu.srcLang(doc)
+		if srcLang == TMX_ALL_LANGS && len(tu.TUV) > 0 {
+			srcLang = tu.TUV[0].Lang
+		}
+		source := tu.variant(srcLang)
+		if source == nil || strings.TrimSpace(source.Seg.Inner) == "" {
+			continue
+		}
+		for j := range tu.TUV {
+			target := &tu.TUV[j]
+			if target == source || strings.TrimSpace(target.Seg.Inner) == "" {
+				continue
+			}
+			changed, isNew := tm.add(tmEntry{
+				SourceLang: source.Lang,
+				TargetLang: target.Lang,
+				Source:     source.content().XML(),
+				Target:     target.content().XML(),
+				Origin:     origin,
+			})
+			switch {
+			case isNew:
+				added++
+			case changed:
+				updated++
+			}
+		}
+	}
+	return added, updated
+}
+
+// exact returns the entry for source, nil if there is none
+func (tm *transMemory) exact(sourceLang, targetLang, source string) *tmEntry {
+	return tm.entries[tmKey(sourceLang, targetLang, source)]
+}
+
+// tmMatch is an entry similar to the text looked up
+type tmMatch struct {
+	entry      *tmEntry
+	similarity int // percent
+}
+
+// fuzzy returns up to max entries whose plain text is at least threshold
+// percent similar to plain, best first
+func (tm *transMemory) fuzzy(sourceLang, targetLang string, plain string, threshold, max int) []tmMatch {
+
+	text := []rune(plain)
+	var matches []tmMatch
+	for _, e := range tm.entries {
+		if !sameLang(e.SourceLang, sourceLang) || !sameLang(e.TargetLang, targetLang) {
+			continue
+		}
+		if e.plain == nil {
+			content, err := parseContent(e.Source)
+			if err != nil {
+				continue
+			}
+			e.plain = []rune(content.TextOnly())
+		}
+		other := e.plain
+
+		// the similarity can't exceed the ratio of the lengths
+		short, long := len(text), len(other)
+		if short > long {
+			short, long = long, short
+		}
+		if long == 0 || short*100/long < threshold {
+			continue
+		}
+		if s := similarity(text, other); s >= threshold {
+			matches = append(matches, tmMatch{e, s})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].similarity != matches[j].similarity {
+			return matches[i].similarity > matches[j].similarity
+		}
+		return matches[i].entry.Source < matches[j].entry.Source
+	})
+	if len(matches) > max {
+		matches = matches[:max]
+	}
+	return matches
+}
+
+// similarity returns how similar a and b are in percent, based on their
+// Levenshtein distance
+func similarity(a, b []rune) int {
+	long := len(a)
+	if len(b) > long {
+		long = len(b)
+	}
+	if long == 0 {
+		return 100
+	}
+	return (long - levenshtein(a, b)) * 100 / long
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLeverage(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "tm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tmFile := filepath.Join(dir, "tm.json")
+
+	ti := &tmImport{tmFile: tmFile, inFiles: []string{"testdata/tm/memory.tmx", "testdata/tm/translated.xlf"}}
+	out := bytes.NewBuffer(nil)
+	if err = ti.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), ": 4 entries\n") {
+		t.Errorf("expected 4 entries (the draft left out), got\n%s", out)
+	}
+
+	l := &leverage{inFile: "testdata/tm/app.xlf", tmFile: tmFile, threshold: 75, suggestions: 3}
+	out.Reset()
+	if err = l.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := xliffFromReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"save":     "translated: Änderungen speichern?",
+		"delete":   "needs-review-translation: Alle Dateien löschen",
+		"greeting": `translated: Hallo <x id="1"/>`,
+		"quit":     "translated: Beenden",
+		"new":      ": ",
+	}
+	for _, unit := range doc.File[0].Body.Units() {
+		if got := unitState(unit) + ": " + unitTarget(unit); got != expected[unit.ID] {
+			t.Errorf("%s: expected %q, got %q", unit.ID, expected[unit.ID], got)
+		}
+		if unit.ID == "delete" && (len(unit.AltTrans) != 1 || unit.AltTrans[0].MatchQuality != "80") {
+			t.Errorf("expected an <alt-trans> with match-quality 80, got %+v", unit.AltTrans)
+		}
+	}
+
+	l.reportOnly = true
+	out.Reset()
+	if err = l.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	report := `de: 5 units, 11 words
+  translated      1 units       1 words   9.1%
+  exact           2 units       3 words  27.3%
+  fuzzy           1 units       4 words  36.4%
+  new             1 units       3 words  27.3%
+`
+	if out.String() != report {
+		t.Errorf("expected\n%s\ngot\n%s", report, out)
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	tests := map[string]int{
+		"":                           0,
+		"Don't delete the e-mail!":   4,
+		"Updated {minutes} ago":      3,
+		"すべてのトラッキング":                 10,
+		"Logo of 'Tour of Heroes' 2": 6,
+	}
+	for text, n := range tests {
+		if got := countWords(text); got != n {
+			t.Errorf("%q: expected %d words, got %d", text, n, got)
+		}
+	}
+}
+
+func TestImportTMXCodes(t *testing.T) {
+
+	doc, err := readTMX(strings.NewReader(`<tmx version="1.4"><header srclang="en"/><body>
+<tu><tuv xml:lang="en"><seg><bpt i="1" type="bold">&lt;b&gt;</bpt>Save<ept i="1">&lt;/b&gt;</ept></seg></tuv>
+<tuv xml:lang="de"><seg><bpt i="1" type="bold">&lt;b&gt;</bpt>Speichern<ept i="1">&lt;/b&gt;</ept></seg></tuv></tu>
+</body></tmx>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := &transMemory{entries: map[string]*tmEntry{}}
+	if added, _ := tm.importTMX(doc, "codes.tmx"); added != 1 {
+		t.Fatalf("expected 1 entry, got %d", added)
+	}
+
+	// the codes are stored as XLIFF, as the ones of an imported XLIFF
+	e := tm.exact("en", "de", `<bpt id="1" ctype="bold">&lt;b&gt;</bpt>Save<ept id="1">&lt;/b&gt;</ept>`)
+	if e == nil {
+		t.Fatalf("expected the source with XLIFF codes, got %v", tm.entries)
+	}
+	if e.Target != `<bpt id="1" ctype="bold">&lt;b&gt;</bpt>Speichern<ept id="1">&lt;/b&gt;</ept>` {
+		t.Errorf("unexpected target %q", e.Target)
+	}
+}
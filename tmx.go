@@ -0,0 +1,268 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TMX (Translation Memory eXchange) holds translation units made of one
+// variant (<tuv>) per language:
+//
+//	<tmx version="1.4">
+//	  <header srclang="en" .../>
+//	  <body>
+//	    <tu>
+//	      <tuv xml:lang="en"><seg>Hello</seg></tuv>
+//	      <tuv xml:lang="de"><seg>Hallo</seg></tuv>
+//	    </tu>
+//	  </body>
+//	</tmx>
+//
+// the content of a <seg> is read like the one of a XLIFF <source>. the
+// inline codes of TMX look alike the ones of XLIFF 1.2 but differ in
+// their attributes, tmxToXliff and xliffToTMX map between them:
+//
+//	TMX                  XLIFF
+//	<bpt i="1" type>     <bpt id="1" ctype>
+//	<ept i="1">          <ept id="1">
+//	<ph x="1" type>      <ph id="1" ctype>, <x id="1" ctype/>
+//	<it x="1" pos>       <it id="1" pos>
+//	<hi x="1" type>      <g id="1" ctype>, <mrk mtype>
+//	<ut>                 <ph>
+//	<bpt/>...<ept/>      <g>, <bx/> <ex/>
+
+const TMX_ALL_LANGS = "*all*"
+
+type tmxDoc struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Version string    `xml:"version,attr"`
+	Header  tmxHeader `xml:"header"`
+	TU      []tmxTU   `xml:"body>tu"`
+}
+
+type tmxHeader struct {
+	SrcLang  string `xml:"srclang,attr"`
+	DataType string `xml:"datatype,attr"`
+}
+
+type tmxTU struct {
+	TUID    string   `xml:"tuid,attr,omitempty"`
+	SrcLang string   `xml:"srclang,attr,omitempty"`
+	TUV     []tmxTUV `xml:"tuv"`
+}
+
+type tmxTUV struct {
+	Lang string      `xml:"lang,attr"`
+	Seg  xliffSource `xml:"seg"`
+}
+
+// isTMX reports if fileName is a TMX, judged by its extension
+func isTMX(fileName string) bool {
+	return strings.HasSuffix(strings.ToLower(fileName), ".tmx")
+}
+
+func tmxFromFile(fileName string) (*tmxDoc, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readTMX(f)
+}
+
+func readTMX(r io.Reader) (*tmxDoc, error) {
+	doc := new(tmxDoc)
+	if err := xml.NewDecoder(r).Decode(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// srcLang returns the source language of tu, TMX_ALL_LANGS if any
+// variant might be the source
+func (tu *tmxTU) srcLang(doc *tmxDoc) string {
+	if tu.SrcLang != "" {
+		return tu.SrcLang
+	}
+	if doc.Header.SrcLang != "" {
+		return doc.Header.SrcLang
+	}
+	return TMX_ALL_LANGS
+}
+
+// variant returns the <tuv> of tu in lang, nil if there is none
+func (tu *tmxTU) variant(lang string) *tmxTUV {
+	for i := range tu.TUV {
+		if sameLang(tu.TUV[i].Lang, lang) {
+			return &tu.TUV[i]
+		}
+	}
+	return nil
+}
+
+// content returns the content of the segment of tuv, its inline codes
+// mapped to XLIFF
+func (tuv *tmxTUV) content() xliffContent {
+	return tmxToXliff(tuv.Seg.content(), map[string]int{})
+}
+
+// tmxToXliff maps the inline codes of TMX to XLIFF 1.2. codes without
+// number are numbered per element by auto, so that they still match
+// between the variants of a tu (as long as their order does).
+func tmxToXliff(content xliffContent, auto map[string]int) xliffContent {
+
+	mapped := make(xliffContent, 0, len(content))
+	for _, inline := range content {
+		if inline.Name == "" {
+			mapped = mapped.appendText(inline.Text)
+			continue
+		}
+
+		id := inline.attr("i")
+		if id == "" {
+			id = inline.attr("x")
+		}
+		if id == "" {
+			auto[inline.Name]++
+			id = fmt.Sprintf("%s%d", inline.Name, auto[inline.Name])
+		}
+		attrs := []xml.Attr{tmxAttr("id", id)}
+
+		name, code := inline.Name, inline.Content
+		switch inline.Name {
+		case "bpt", "ph":
+			attrs = appendAttr(attrs, "ctype", inline.attr("type"))
+		case "ept":
+		case "it":
+			pos := "open"
+			if inline.attr("pos") == "end" {
+				pos = "close"
+			}
+			attrs = appendAttr(attrs, "pos", pos)
+		case "hi":
+			name, code = "g", tmxToXliff(inline.Content, auto)
+			attrs = appendAttr(attrs, "ctype", inline.attr("type"))
+		case "ut":
+			name = "ph"
+		default:
+			continue // unknown elements are dropped, their text is lost
+		}
+		mapped = append(mapped, xliffInline{Name: name, Attrs: attrs, Content: code})
+	}
+	return mapped
+}
+
+// tmxIDs numbers the ids of XLIFF codes for TMX, which expects integers
+type tmxIDs map[string]int
+
+func (ids tmxIDs) get(id string) string {
+	n, ok := ids[id]
+	if !ok {
+		n = len(ids) + 1
+		ids[id] = n
+	}
+	return strconv.Itoa(n)
+}
+
+// xliffToTMX maps the inline elements of XLIFF 1.2 to the codes of TMX.
+// source and target of a unit share ids, so that their codes match.
+func xliffToTMX(content xliffContent, ids tmxIDs) xliffContent {
+
+	mapped := make(xliffContent, 0, len(content))
+	for _, inline := range content {
+		if inline.Name == "" {
+			mapped = mapped.appendText(inline.Text)
+			continue
+		}
+
+		id := inline.attr("id")
+		pair := id
+		if rid := inline.attr("rid"); rid != "" {
+			pair = rid
+		}
+		ctype := inline.attr("ctype")
+
+		switch inline.Name {
+		case "bpt":
+			mapped = append(mapped, xliffInline{Name: "bpt", Attrs: appendAttr([]xml.Attr{tmxAttr("i", ids.get(pair))}, "type", ctype), Content: inline.Content})
+		case "ept":
+			mapped = append(mapped, xliffInline{Name: "ept", Attrs: []xml.Attr{tmxAttr("i", ids.get(pair))}, Content: inline.Content})
+		case "bx":
+			mapped = append(mapped, xliffInline{Name: "bpt", Attrs: appendAttr([]xml.Attr{tmxAttr("i", ids.get(pair))}, "type", ctype)})
+		case "ex":
+			mapped = append(mapped, xliffInline{Name: "ept", Attrs: []xml.Attr{tmxAttr("i", ids.get(pair))}})
+		case "g":
+			i := tmxAttr("i", ids.get(id))
+			mapped = append(mapped, xliffInline{Name: "bpt", Attrs: appendAttr([]xml.Attr{i}, "type", ctype)})
+			mapped = append(mapped, xliffToTMX(inline.Content, ids)...)
+			mapped = append(mapped, xliffInline{Name: "ept", Attrs: []xml.Attr{i}})
+		case "x", "ph":
+			mapped = append(mapped, xliffInline{Name: "ph", Attrs: appendAttr([]xml.Attr{tmxAttr("x", ids.get(id))}, "type", ctype), Content: inline.Content})
+		case "it":
+			pos := "begin"
+			if inline.attr("pos") == "close" {
+				pos = "end"
+			}
+			mapped = append(mapped, xliffInline{Name: "it", Attrs: []xml.Attr{tmxAttr("pos", pos), tmxAttr("x", ids.get(id))}, Content: inline.Content})
+		case "mrk":
+			mapped = append(mapped, xliffInline{Name: "hi", Attrs: appendAttr(nil, "type", inline.attr("mtype")), Content: xliffToTMX(inline.Content, ids)})
+		default:
+			mapped = append(mapped, inline)
+		}
+	}
+	return mapped
+}
+
+func tmxAttr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+// appendAttr appends an attribute, unless value is empty
+func appendAttr(attrs []xml.Attr, name, value string) []xml.Attr {
+	if value == "" {
+		return attrs
+	}
+	return append(attrs, tmxAttr(name, value))
+}
+
+// writeTMX writes doc as TMX 1.4. the segments are written as rendered
+// by INLINE_XML.
+func writeTMX(w io.Writer, doc *tmxDoc) error {
+
+	buf := bytes.NewBufferString(xml.Header)
+	buf.WriteString(`<tmx version="1.4">` + "\n")
+	fmt.Fprintf(buf, `  <header creationtool="xliffer" creationtoolversion="%s" segtype="sentence" o-tmf="xliff" adminlang="en" srclang="%s" datatype="%s"/>`+"\n",
+		escapedAttr(Version), escapedAttr(doc.Header.SrcLang), escapedAttr(doc.Header.DataType))
+	buf.WriteString("  <body>\n")
+	for _, tu := range doc.TU {
+		buf.WriteString("    <tu")
+		if tu.TUID != "" {
+			buf.WriteString(` tuid="` + escapedAttr(tu.TUID) + `"`)
+		}
+		if tu.SrcLang != "" {
+			buf.WriteString(` srclang="` + escapedAttr(tu.SrcLang) + `"`)
+		}
+		buf.WriteString(">\n")
+		for _, tuv := range tu.TUV {
+			fmt.Fprintf(buf, `      <tuv xml:lang="%s"><seg>%s</seg></tuv>`+"\n", escapedAttr(tuv.Lang), tuv.Seg.Text(INLINE_XML))
+		}
+		buf.WriteString("    </tu>\n")
+	}
+	buf.WriteString("  </body>\n</tmx>\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
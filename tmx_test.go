@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTMXRoundTrip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tmxFile := filepath.Join(dir, "app.tmx")
+
+	tt := &toTMX{inFiles: []string{"testdata/tmx/app-de.xlf", "testdata/tmx/app-fr.xlf"}}
+	out := bytes.NewBuffer(nil)
+	if err = tt.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(tmxFile, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmx, err := readTMX(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "quit" needs a translation in German, so it is French only
+	langs := map[string]int{"greeting": 3, "terms": 2, "quit": 2}
+	if len(tmx.TU) != len(langs) {
+		t.Fatalf("expected %d tus, got %d", len(langs), len(tmx.TU))
+	}
+	for _, tu := range tmx.TU {
+		if len(tu.TUV) != langs[tu.TUID] {
+			t.Errorf("%s: expected %d variants, got %d", tu.TUID, langs[tu.TUID], len(tu.TUV))
+		}
+	}
+	if got := tmx.TU[1].TUV[1].Seg.Text(INLINE_XML); got != `Die <bpt i="1" type="x-a"/>Bedingungen<ept i="1"/> akzeptieren` {
+		t.Errorf("unexpected TMX codes: %s", got)
+	}
+
+	ft := &fromTMX{inFile: tmxFile}
+	if err = ft.Convert(out); err == nil {
+		t.Error("expected an error for the missing -target-lang")
+	}
+
+	expected := map[string]string{
+		"greeting": `translated: Bonjour <ph id="1" ctype="x-name"/> !`,
+		"terms":    ": ",
+		"quit":     "translated: Quitter",
+	}
+	ft.targetLang = "fr"
+	out.Reset()
+	if err = ft.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := xliffFromReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, unit := range doc.File[0].Body.Units() {
+		if got := unitState(unit) + ": " + unitTarget(unit); got != expected[unit.ID] {
+			t.Errorf("%s: expected %q, got %q", unit.ID, expected[unit.ID], got)
+		}
+	}
+}
+
+func TestFromTMXMerge(t *testing.T) {
+
+	ft := &fromTMX{inFile: "testdata/tmx/vendor.tmx", mergeFile: "testdata/tmx/todo.xlf"}
+	out := bytes.NewBuffer(nil)
+	if err := ft.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := xliffFromReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the codes are the ones of the XLIFF, "close" differs in its codes
+	expected := map[string]string{
+		"warning": `translated: Das ist <bpt id="b" ctype="bold">&lt;b&gt;</bpt>wichtig<ept id="b">&lt;/b&gt;</ept>`,
+		"pages":   `translated: Seite <x id="current"/> von <x id="count"/>`,
+		"close":   ": ",
+		"open":    "translated: Öffnen",
+	}
+	for _, unit := range doc.File[0].Body.Units() {
+		if got := unitState(unit) + ": " + unitTarget(unit); got != expected[unit.ID] {
+			t.Errorf("%s: expected %q, got %q", unit.ID, expected[unit.ID], got)
+		}
+	}
+}
+
+func TestToTMXSameLang(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlfFile := filepath.Join(dir, "en.xlf")
+	err = ioutil.WriteFile(xlfFile, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="app" source-language="en" target-language="EN" datatype="plaintext">
+    <body>
+      <trans-unit id="quit">
+        <source>Quit</source>
+        <target state="translated">Exit</target>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := &toTMX{inFiles: []string{"testdata/tmx/app-fr.xlf", xlfFile}}
+	out := bytes.NewBuffer(nil)
+	if err = tt.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	tmx, err := readTMX(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tu := range tmx.TU {
+		if source := tu.variant("en"); source != nil && source.Seg.Text(INLINE_PLAIN) == "Exit" {
+			t.Errorf("%s: source replaced by the target of EN", tu.TUID)
+		}
+	}
+}
+
+func TestFromTMXMergeNoFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlfFile := filepath.Join(dir, "empty.xlf")
+	err = ioutil.WriteFile(xlfFile, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+</xliff>
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ft := &fromTMX{inFile: "testdata/tmx/vendor.tmx", mergeFile: xlfFile}
+	if err = ft.Convert(bytes.NewBuffer(nil)); err != nil {
+		t.Fatal(err)
+	}
+}
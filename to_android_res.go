@@ -0,0 +1,243 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// toAndroid writes the translations of XLIFF files as Android string
+// resources, a values-<lang>/strings.xml per target language. units
+// which are not translated are left out, Android falls back to the
+// default resources for them.
+type toAndroid struct {
+	inFiles []string
+	outDir  string
+	source  bool
+}
+
+func init() {
+	registeredConverters["to-android"] = new(toAndroid)
+}
+
+func (ta *toAndroid) Description() string {
+	return "Converts XLIFF files to Android strings.xml"
+}
+
+func (ta *toAndroid) ParseArgs(base string, args []string) error {
+	var inFile string
+	var fs = flag.NewFlagSet(base+" to-android", flag.ExitOnError)
+	fs.StringVar(&inFile, "in", "", "infile (more files can follow as arguments)")
+	fs.StringVar(&ta.outDir, "out", "", "res directory to write values-<lang>/strings.xml to (default: write a single language to stdout)")
+	fs.BoolVar(&ta.source, "source", false, "write the sources as values/strings.xml as well")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if inFile != "" {
+		ta.inFiles = append(ta.inFiles, inFile)
+	}
+	ta.inFiles = append(ta.inFiles, fs.Args()...)
+	if len(ta.inFiles) == 0 {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (ta *toAndroid) Prepare() error {
+	return nil
+}
+
+// androidValues are the resources of a values directory
+type androidValues struct {
+	dir       string
+	resources []*androidResource
+	byName    map[string]*androidResource
+	partial   map[string]bool // string arrays lacking translations
+}
+
+func (ta *toAndroid) Convert(w io.Writer) error {
+
+	var values []*androidValues
+	byDir := map[string]*androidValues{}
+	get := func(dir string) *androidValues {
+		if v := byDir[dir]; v != nil {
+			return v
+		}
+		v := &androidValues{dir: dir, byName: map[string]*androidResource{}, partial: map[string]bool{}}
+		byDir[dir] = v
+		values = append(values, v)
+		return v
+	}
+
+	for _, name := range ta.inFiles {
+		doc, err := xliffFromFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		for _, file := range doc.File {
+			if ta.source {
+				ta.add(get("values"), &file, true)
+			}
+			if file.TargetLang == "" {
+				return fmt.Errorf("%s: missing target-language", name)
+			}
+			ta.add(get("values-"+androidQualifier(file.TargetLang)), &file, false)
+		}
+	}
+
+	// an array replaces the one of the default resources as a whole,
+	// it can't be translated in parts
+	for _, v := range values {
+		v.dropPartial()
+	}
+
+	if ta.outDir == "" {
+		if len(values) != 1 {
+			return fmt.Errorf("%d values directories to write, missing -out", len(values))
+		}
+		return writeAndroid(w, values[0].resources)
+	}
+
+	for _, v := range values {
+		buf := bytes.NewBuffer(nil)
+		if err := writeAndroid(buf, v.resources); err != nil {
+			return err
+		}
+		dir := filepath.Join(ta.outDir, v.dir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		fileName := filepath.Join(dir, "strings.xml")
+		if err := ioutil.WriteFile(fileName, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s: %d resources\n", fileName, len(v.resources))
+	}
+	return nil
+}
+
+// add adds the units of file to v, the sources if source is set
+func (ta *toAndroid) add(v *androidValues, file *xliffFile, source bool) {
+
+	file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+
+		translatable := attrValue(unit.Attrs, "translate") != "no"
+		var group *xliffGroup
+		if n := len(groups); n > 0 {
+			switch attrValue(groups[n-1].Attrs, "restype") {
+			case ANDROID_PLURALS_RESTYPE, ANDROID_ARRAY_RESTYPE:
+				group = groups[n-1]
+				translatable = translatable && attrValue(group.Attrs, "translate") != "no"
+			}
+		}
+
+		content := unit.Source.content()
+		if source && isTargetForm(unit) {
+			return
+		}
+		if !source {
+			if !translatable {
+				return
+			}
+			if unit.Target == nil || strings.TrimSpace(unit.Target.Inner) == "" {
+				if group != nil && attrValue(group.Attrs, "restype") == ANDROID_ARRAY_RESTYPE {
+					v.partial[androidName(group.ID)] = true
+				}
+				return
+			}
+			content = (*xliffSource)(unit.Target).content()
+		}
+		item := androidItem{Content: content, CDATA: attrValue(unit.Attrs, "restype") == ANDROID_CDATA_RESTYPE}
+		item.Reference = !translatable && strings.HasPrefix(content.Plain(), "@")
+
+		if group == nil {
+			res := &androidResource{Kind: ANDROID_STRING, Name: androidName(unit.ID), Translatable: translatable, Comment: unit.Note}
+			res.Items = []androidItem{item}
+			v.addResource(res)
+			return
+		}
+
+		res := v.byName[androidName(group.ID)]
+		if res == nil {
+			res = &androidResource{Kind: ANDROID_ARRAY, Name: androidName(group.ID), Translatable: translatable}
+			if attrValue(group.Attrs, "restype") == ANDROID_PLURALS_RESTYPE {
+				res.Kind = ANDROID_PLURALS
+			}
+			if len(group.Note) > 0 {
+				res.Comment = group.Note[0]
+			}
+			v.addResource(res)
+		}
+		if res.Kind == ANDROID_PLURALS {
+			item.Quantity = attrValue(unit.Attrs, "resname")
+		}
+		for i := range group.TransUnit {
+			if &group.TransUnit[i] == unit {
+				res.setItem(i, item)
+			}
+		}
+	})
+}
+
+// setItem sets the i-th item of an array or the item of the quantity of
+// plurals, replacing the one of a file added before
+func (res *androidResource) setItem(i int, item androidItem) {
+	for j := range res.Items {
+		if res.Kind == ANDROID_PLURALS && res.Items[j].Quantity == item.Quantity || res.Kind != ANDROID_PLURALS && j == i {
+			res.Items[j] = item
+			return
+		}
+	}
+	res.Items = append(res.Items, item)
+}
+
+// addResource adds res, replacing a resource of the same name
+func (v *androidValues) addResource(res *androidResource) {
+	if old := v.byName[res.Name]; old != nil {
+		*old = *res
+		return
+	}
+	v.byName[res.Name] = res
+	v.resources = append(v.resources, res)
+}
+
+func (v *androidValues) dropPartial() {
+	resources := v.resources[:0]
+	for _, res := range v.resources {
+		if v.partial[res.Name] {
+			log.Printf("warning: %s: string-array %q is not translated completely, left out", v.dir, res.Name)
+			continue
+		}
+		resources = append(resources, res)
+	}
+	v.resources = resources
+}
+
+// androidName turns id into the name of a resource, which is made of
+// letters, digits, "_" and "."
+func androidName(id string) string {
+	name := []rune(id)
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.') {
+			name[i] = '_'
+		}
+	}
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		return "_" + string(name)
+	}
+	return string(name)
+}
@@ -0,0 +1,100 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+)
+
+// toARB writes the translations of a XLIFF as the ARB of the target
+// language, @@locale set from the target-language. units which are not
+// translated are left out, Flutter falls back to the template for them.
+type toARB struct {
+	inFile string
+	source bool
+}
+
+func init() {
+	registeredConverters["to-arb"] = new(toARB)
+}
+
+func (ta *toARB) Description() string {
+	return "Converts XLIFF to Flutter ARB"
+}
+
+func (ta *toARB) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" to-arb", flag.ExitOnError)
+	fs.StringVar(&ta.inFile, "in", "", "infile")
+	fs.BoolVar(&ta.source, "source", false, "write the sources (the template ARB) instead of the targets")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if ta.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (ta *toARB) Prepare() error {
+	return nil
+}
+
+func (ta *toARB) Convert(w io.Writer) error {
+
+	doc, err := xliffFromFile(ta.inFile)
+	if err != nil {
+		return err
+	}
+
+	lang := ""
+	var messages []arbMessage
+	keys := map[string]bool{}
+	for _, file := range doc.File {
+		fileLang := file.TargetLang
+		if ta.source {
+			fileLang = file.SourceLang
+		}
+		if fileLang == "" {
+			return fmt.Errorf("%s: missing target-language", ta.inFile)
+		}
+		if lang != "" && !sameLang(lang, fileLang) {
+			return fmt.Errorf("%s: more than one language: %s, %s", ta.inFile, lang, fileLang)
+		}
+		lang = fileLang
+
+		file.Body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+			value := unit.Source.Text(INLINE_PLAIN)
+			if !ta.source {
+				if unit.Target == nil || attrValue(unit.Attrs, "translate") == "no" {
+					return
+				}
+				value = unit.Target.Text(INLINE_PLAIN)
+			}
+			if value == "" {
+				return
+			}
+			if keys[unit.ID] {
+				log.Printf("warning: double entry for key %q", unit.ID)
+				return
+			}
+			keys[unit.ID] = true
+			messages = append(messages, arbMessage{
+				Key:         unit.ID,
+				Value:       value,
+				Description: unit.Note,
+				Metadata:    arbMetadata(unit),
+			})
+		})
+	}
+
+	return writeARB(w, langLocale(lang), messages)
+}
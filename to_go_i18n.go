@@ -0,0 +1,103 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// toGoI18n writes the translations of a XLIFF as go-i18n message file.
+// units which are not translated are left out; with the hash kept from a
+// translate.<lang>.toml the result can be given to "goi18n merge".
+type toGoI18n struct {
+	inFile string
+	source bool
+	format string
+}
+
+func init() {
+	registeredConverters["to-go-i18n"] = new(toGoI18n)
+}
+
+func (tg *toGoI18n) Description() string {
+	return "Converts XLIFF to a go-i18n message file (TOML, JSON, YAML)"
+}
+
+func (tg *toGoI18n) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" to-go-i18n", flag.ExitOnError)
+	fs.StringVar(&tg.inFile, "in", "", "infile")
+	fs.BoolVar(&tg.source, "source", false, "write the sources (active.<source-lang>.toml) instead of the targets")
+	fs.StringVar(&tg.format, "format", GO_I18N_TOML, "format to write: toml, json or yaml")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if tg.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	if !isValidGoI18nFormat(tg.format) {
+		return fmt.Errorf("unsupported 'format': %q", tg.format)
+	}
+	return nil
+}
+
+func (tg *toGoI18n) Prepare() error {
+	return nil
+}
+
+func (tg *toGoI18n) Convert(w io.Writer) error {
+
+	doc, err := xliffFromFile(tg.inFile)
+	if err != nil {
+		return err
+	}
+
+	var messages []goI18nMessage
+	index := map[string]int{}
+	for _, file := range doc.File {
+		file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+			value := unit.Source.Text(INLINE_PLAIN)
+			if tg.source {
+				if isTargetForm(unit) {
+					return
+				}
+			} else {
+				if unit.Target == nil || attrValue(unit.Attrs, "translate") == "no" {
+					return
+				}
+				value = unit.Target.Text(INLINE_PLAIN)
+			}
+			if value == "" {
+				return
+			}
+
+			id, form := unit.ID, "other"
+			if n := len(groups); n > 0 && attrValue(groups[n-1].Attrs, "restype") == GO_I18N_PLURAL_RESTYPE {
+				id = groups[n-1].ID
+				form = strings.TrimPrefix(unit.ID, id+".")
+			}
+			i, exists := index[id]
+			if !exists {
+				i = len(messages)
+				index[id] = i
+				msg := goI18nMessage{ID: id, Forms: map[string]string{}}
+				msg.metaFrom(unit)
+				if tg.source {
+					msg.Hash = ""
+				}
+				messages = append(messages, msg)
+			}
+			messages[i].Forms[form] = value
+		})
+	}
+
+	return writeGoI18n(w, messages, tg.format)
+}
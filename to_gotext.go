@@ -0,0 +1,139 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// toGotext writes a XLIFF as messages.gotext.json of the target language,
+// to be read by "gotext generate". units without translation are written
+// with an empty one, as gotext does.
+type toGotext struct {
+	inFile string
+}
+
+func init() {
+	registeredConverters["to-gotext"] = new(toGotext)
+}
+
+func (tg *toGotext) Description() string {
+	return "Converts XLIFF to gotext messages (messages.gotext.json)"
+}
+
+func (tg *toGotext) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" to-gotext", flag.ExitOnError)
+	fs.StringVar(&tg.inFile, "in", "", "infile")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if tg.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (tg *toGotext) Prepare() error {
+	return nil
+}
+
+func (tg *toGotext) Convert(w io.Writer) error {
+
+	doc, err := xliffFromFile(tg.inFile)
+	if err != nil {
+		return err
+	}
+
+	messages := &gotextMessages{Messages: []gotextMessage{}}
+	for _, file := range doc.File {
+		if messages.Language == "" {
+			messages.Language = file.TargetLang
+		}
+
+		var source, translation *gotextText
+		var sel *xliffGroup
+		flush := func() {
+			if sel == nil {
+				return
+			}
+			// a message without cases but the ones of the target
+			// language is a plain text
+			if len(source.Cases) == 1 && source.Cases[0].Key == "other" {
+				source = &gotextText{Msg: source.Cases[0].Msg}
+			}
+			msg := &messages.Messages[len(messages.Messages)-1]
+			msg.Message = source.json()
+			msg.Translation = jsonString("")
+			if translation.isSelect() {
+				msg.Translation = translation.json()
+			}
+			sel = nil
+		}
+
+		file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+
+			text, state := "", ""
+			if unit.Target != nil && attrValue(unit.Attrs, "translate") != "no" {
+				text, state = unit.Target.Text(INLINE_PLAIN), unit.Target.State
+			}
+			fuzzy := text != "" && strings.HasPrefix(state, "needs-")
+
+			var group *xliffGroup
+			if n := len(groups); n > 0 && attrValue(groups[n-1].Attrs, "restype") == GOTEXT_SELECT_RESTYPE {
+				group = groups[n-1]
+			}
+			if group != nil && group == sel {
+				key := gotextCaseKey(group, unit)
+				if !isTargetForm(unit) {
+					source.Cases = append(source.Cases, gotextCase{key, unit.Source.Text(INLINE_PLAIN)})
+				}
+				if text != "" {
+					translation.Cases = append(translation.Cases, gotextCase{key, text})
+				}
+				msg := &messages.Messages[len(messages.Messages)-1]
+				msg.Fuzzy = msg.Fuzzy || fuzzy
+				return
+			}
+			flush()
+
+			msg := gotextMessage{Fuzzy: fuzzy}
+			msg.metaFrom(unit)
+			if group == nil {
+				msg.Message = jsonString(unit.Source.Text(INLINE_PLAIN))
+				msg.Translation = jsonString(text)
+				messages.Messages = append(messages.Messages, msg)
+				return
+			}
+
+			msg.ID = jsonString(group.ID)
+			if ids := unit.context(GOTEXT_CONTEXT_GROUP, GOTEXT_IDS); ids != "" {
+				msg.ID = []byte(ids)
+			}
+			sel = group
+			source = &gotextText{
+				Feature: unit.context(GOTEXT_CONTEXT_GROUP, GOTEXT_FEATURE),
+				Arg:     unit.context(GOTEXT_CONTEXT_GROUP, GOTEXT_ARG),
+			}
+			if !isTargetForm(unit) {
+				source.Cases = append(source.Cases, gotextCase{gotextCaseKey(group, unit), unit.Source.Text(INLINE_PLAIN)})
+			}
+			translation = &gotextText{Feature: source.Feature, Arg: source.Arg}
+			if text != "" {
+				translation.Cases = append(translation.Cases, gotextCase{gotextCaseKey(group, unit), text})
+			}
+			messages.Messages = append(messages.Messages, msg)
+		})
+		flush()
+	}
+
+	return writeGotext(w, messages)
+}
@@ -0,0 +1,234 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// toIOSStrings writes the translations of XLIFF files as .strings and
+// .stringsdict files into a <lang>.lproj per target language. the name of
+// a file is taken from the original of its <file>; units which are not
+// translated are left out, iOS falls back to the development language
+// for them.
+type toIOSStrings struct {
+	inFiles []string
+	outDir  string
+	source  bool
+	utf16   bool
+}
+
+func init() {
+	registeredConverters["to-ios-strings"] = new(toIOSStrings)
+}
+
+func (ti *toIOSStrings) Description() string {
+	return "Converts XLIFF files to iOS .strings and .stringsdict"
+}
+
+func (ti *toIOSStrings) ParseArgs(base string, args []string) error {
+	var inFile string
+	var fs = flag.NewFlagSet(base+" to-ios-strings", flag.ExitOnError)
+	fs.StringVar(&inFile, "in", "", "infile (more files can follow as arguments)")
+	fs.StringVar(&ti.outDir, "out", "", "directory to write <lang>.lproj/* to (default: write a single file to stdout)")
+	fs.BoolVar(&ti.source, "source", false, "write the sources into the .lproj of the source language as well")
+	fs.BoolVar(&ti.utf16, "utf16", false, "write .strings in UTF-16 instead of UTF-8")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if inFile != "" {
+		ti.inFiles = append(ti.inFiles, inFile)
+	}
+	ti.inFiles = append(ti.inFiles, fs.Args()...)
+	if len(ti.inFiles) == 0 {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (ti *toIOSStrings) Prepare() error {
+	return nil
+}
+
+// iosFile is a .strings or .stringsdict to be written
+type iosFile struct {
+	path    string // <lang>.lproj/<name>
+	entries []iosEntry
+	plurals []iosPlural
+	keys    map[string]int // index in entries or plurals
+}
+
+func (ti *toIOSStrings) Convert(w io.Writer) error {
+
+	var files []*iosFile
+	byPath := map[string]*iosFile{}
+	get := func(lang, original string) *iosFile {
+		name := filepath.Base(original)
+		if !isStringsdict(name) && !strings.HasSuffix(name, ".strings") {
+			name = "Localizable.strings"
+		}
+		path := filepath.Join(iosLproj(lang), name)
+		if f := byPath[path]; f != nil {
+			return f
+		}
+		f := &iosFile{path: path, keys: map[string]int{}}
+		byPath[path] = f
+		files = append(files, f)
+		return f
+	}
+
+	for _, name := range ti.inFiles {
+		doc, err := xliffFromFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		for _, file := range doc.File {
+			if ti.source {
+				ti.add(get(file.SourceLang, file.Original), &file, true)
+			}
+			if file.TargetLang == "" {
+				return fmt.Errorf("%s: missing target-language", name)
+			}
+			ti.add(get(file.TargetLang, file.Original), &file, false)
+		}
+	}
+
+	if ti.outDir == "" {
+		if len(files) != 1 {
+			return fmt.Errorf("%d files to write, missing -out", len(files))
+		}
+		return ti.write(w, files[0])
+	}
+
+	for _, f := range files {
+		buf := bytes.NewBuffer(nil)
+		if err := ti.write(buf, f); err != nil {
+			return err
+		}
+		fileName := filepath.Join(ti.outDir, f.path)
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(fileName, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s: %d entries\n", fileName, len(f.entries)+len(f.plurals))
+	}
+	return nil
+}
+
+func (ti *toIOSStrings) write(w io.Writer, f *iosFile) error {
+	if isStringsdict(f.path) {
+		return writeStringsdict(w, f.plurals)
+	}
+	return writeIOSStrings(w, f.entries, ti.utf16)
+}
+
+// add adds the units of file to f, the sources if source is set. the
+// units of a .stringsdict group go into plurals, the others into entries.
+func (ti *toIOSStrings) add(f *iosFile, file *xliffFile, source bool) {
+
+	text := func(unit *xliffTransUnit) string {
+		if source {
+			if isTargetForm(unit) {
+				return ""
+			}
+			return unit.Source.Text(INLINE_PLAIN)
+		}
+		if unit.Target == nil || attrValue(unit.Attrs, "translate") == "no" {
+			return ""
+		}
+		return unit.Target.Text(INLINE_PLAIN)
+	}
+
+	file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+
+		n := len(groups)
+		if n == 0 || attrValue(groups[n-1].Attrs, "restype") != IOS_STRINGSDICT_RESTYPE {
+			value := text(unit)
+			if value == "" {
+				return
+			}
+			e := iosEntry{Key: unit.ID, Value: value, Comment: unit.Note}
+			if i, exists := f.keys[e.Key]; exists {
+				f.entries[i] = e
+				return
+			}
+			f.keys[e.Key] = len(f.entries)
+			f.entries = append(f.entries, e)
+			return
+		}
+
+		if group := groups[n-1]; unit == &group.TransUnit[0] {
+			if p := ti.plural(group, text); p != nil {
+				if i, exists := f.keys[p.Key]; exists {
+					f.plurals[i] = *p
+					return
+				}
+				f.keys[p.Key] = len(f.plurals)
+				f.plurals = append(f.plurals, *p)
+			}
+		}
+	})
+}
+
+// plural turns a .stringsdict group into an entry, nil if none of its
+// forms is translated. a format which is not translated is taken from the
+// source.
+func (ti *toIOSStrings) plural(group *xliffGroup, text func(*xliffTransUnit) string) *iosPlural {
+
+	p := &iosPlural{Key: group.ID}
+	forms := 0
+	for i := range group.TransUnit {
+		unit := &group.TransUnit[i]
+		resname := attrValue(unit.Attrs, "resname")
+		if resname == IOS_FORMAT_KEY {
+			if p.Format = text(unit); p.Format == "" {
+				p.Format = unit.Source.Text(INLINE_PLAIN)
+			}
+			continue
+		}
+
+		sep := strings.LastIndexByte(resname, ':')
+		value := text(unit)
+		if sep < 0 || value == "" {
+			continue
+		}
+		name, quantity := resname[:sep], resname[sep+1:]
+
+		var v *iosPluralVar
+		for j := range p.Vars {
+			if p.Vars[j].Name == name {
+				v = &p.Vars[j]
+			}
+		}
+		if v == nil {
+			p.Vars = append(p.Vars, iosPluralVar{
+				Name:      name,
+				ValueType: unit.context(IOS_CONTEXT_GROUP, IOS_VALUE_TYPE),
+				Forms:     map[string]string{},
+			})
+			v = &p.Vars[len(p.Vars)-1]
+		}
+		v.Forms[quantity] = value
+		forms++
+	}
+
+	if forms == 0 {
+		return nil
+	}
+	return p
+}
@@ -11,18 +11,26 @@ package main
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"regexp"
+	"sort"
+	"strings"
 )
 
 // toJSON converts the target translation to a simple key:value
 // structered JSON file
 type toJSON struct {
-	inFile   string
-	pretty   bool
-	keyMatch string
-	keyTo    string
+	inFile      string
+	pretty      bool
+	keyMatch    string
+	keyTo       string
+	groupPrefix bool
+	groupSep    string
+	inline      string
+	nested      bool
+	nestSep     string
 }
 
 func init() {
@@ -39,7 +47,18 @@ func (tj *toJSON) ParseArgs(base string, args []string) error {
 	fs.StringVar(&tj.keyMatch, "key-match", "", "translate chars in key (regexp)")
 	fs.StringVar(&tj.keyTo, "key-to", "", "chars of key gets translated to (string)")
 	fs.BoolVar(&tj.pretty, "pretty", tj.pretty, "pretty print the resulting json")
-	return fs.Parse(args)
+	fs.BoolVar(&tj.groupPrefix, "group-prefix", false, "prefix keys with the ids of the enclosing groups")
+	fs.StringVar(&tj.groupSep, "group-sep", ".", "separator between group ids and key")
+	fs.StringVar(&tj.inline, "inline", INLINE_PLAIN, "render inline elements as plain, placeholder or xml")
+	fs.BoolVar(&tj.nested, "nested", false, "write nested objects (i18next, vue-i18n) instead of flat keys")
+	fs.StringVar(&tj.nestSep, "nest-sep", ".", "separator which splits keys into nested objects")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !isValidInline(tj.inline) {
+		return fmt.Errorf("unsupported 'inline': %q", tj.inline)
+	}
+	return nil
 }
 
 func (tj *toJSON) Prepare() error {
@@ -67,29 +86,91 @@ func (tj *toJSON) Convert(w io.Writer) error {
 	var mappings = map[string]string{}
 	for _, file := range doc.File {
 
-		// note: no support for "groups" yet.
-		for _, unit := range file.Body.TransUnit {
+		file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
 
-			unitID := keyTrans(unit.ID)
+			unitID := unit.ID
+			if tj.groupPrefix {
+				unitID = strings.Join(append(groupIDs(groups), unitID), tj.groupSep)
+			}
+			unitID = keyTrans(unitID)
 
 			if _, exist := mappings[unitID]; exist {
 				log.Printf("warning: double entry for key %q", unitID)
 			}
 
-			mappings[unitID] = unit.Target.Inner
-		}
+			if unit.Target != nil {
+				mappings[unitID] = unit.Target.Text(tj.inline)
+			} else {
+				mappings[unitID] = ""
+			}
+		})
 	}
 
-	var out []byte
-	if tj.pretty {
-		out, err = json.MarshalIndent(&mappings, "", "\t")
-	} else {
-		out, err = json.Marshal(&mappings)
+	nestSep := ""
+	if tj.nested {
+		nestSep = tj.nestSep
 	}
-
+	out, err := marshalKeyValues(mappings, tj.pretty, nestSep)
 	if err == nil {
 		w.Write(out)
 	}
 
 	return err
 }
+
+// marshalKeyValues writes keyVals as JSON, the keys sorted. with a
+// nestSep the keys are split into nested objects:
+//
+//	"a.b.c": "x" -> {"a": {"b": {"c": "x"}}}
+func marshalKeyValues(keyVals map[string]string, pretty bool, nestSep string) ([]byte, error) {
+
+	var v interface{} = keyVals
+	if nestSep != "" {
+		nested, err := nestKeyValues(keyVals, nestSep)
+		if err != nil {
+			return nil, err
+		}
+		v = nested
+	}
+
+	if pretty {
+		return json.MarshalIndent(v, "", "\t")
+	}
+	return json.Marshal(v)
+}
+
+// nestKeyValues splits the keys at sep into nested objects. a key which
+// is a text and the parent of other keys at the same time ("a" and "a.b")
+// is a conflict.
+func nestKeyValues(keyVals map[string]string, sep string) (map[string]interface{}, error) {
+
+	keys := make([]string, 0, len(keyVals))
+	for key := range keyVals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	root := map[string]interface{}{}
+	for _, key := range keys {
+		parts := strings.Split(key, sep)
+		node := root
+		for i, part := range parts[:len(parts)-1] {
+			switch child := node[part].(type) {
+			case nil:
+				next := map[string]interface{}{}
+				node[part] = next
+				node = next
+			case map[string]interface{}:
+				node = child
+			default:
+				return nil, fmt.Errorf("can't nest %q: %q is a text already", key, strings.Join(parts[:i+1], sep))
+			}
+		}
+		leaf := parts[len(parts)-1]
+		if _, exists := node[leaf]; exists {
+			return nil, fmt.Errorf("can't nest %q: it holds other keys already", key)
+		}
+		node[leaf] = keyVals[key]
+	}
+	return root, nil
+}
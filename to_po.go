@@ -0,0 +1,157 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// toPO converts a XLIFF to a gettext PO. the id of a trans-unit becomes
+// the msgctxt (unless it is the source text itself), the source the
+// msgid and the target the msgstr. with -pot the targets are blanked
+// just like blank-target does, resulting in a template.
+type toPO struct {
+	inFile string
+	pot    bool
+	inline string
+}
+
+func init() {
+	registeredConverters["to-po"] = new(toPO)
+}
+
+func (tp *toPO) Description() string {
+	return "Converts XLIFF to gettext PO (or POT)"
+}
+
+func (tp *toPO) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" to-po", flag.ExitOnError)
+	fs.StringVar(&tp.inFile, "in", "", "infile")
+	fs.BoolVar(&tp.pot, "pot", false, "write a template (POT), without translations")
+	fs.StringVar(&tp.inline, "inline", INLINE_PLAIN, "render inline elements as plain, placeholder or xml")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !isValidInline(tp.inline) {
+		return fmt.Errorf("unsupported 'inline': %q", tp.inline)
+	}
+	return nil
+}
+
+func (tp *toPO) Prepare() error {
+	return nil
+}
+
+func (tp *toPO) Convert(w io.Writer) error {
+
+	var doc, err = xliffFromFile(tp.inFile)
+	if err != nil {
+		return err
+	}
+
+	header := ""
+	lang := ""
+	if len(doc.File) > 0 {
+		lang = doc.File[0].TargetLang
+	}
+	if tp.pot {
+		blankTargets(doc)
+		lang = ""
+	}
+
+	var entries []*poEntry
+	for _, file := range doc.File {
+
+		file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+
+			if attrValue(unit.Attrs, "restype") == PO_HEADER_RESTYPE {
+				if header == "" {
+					header = unit.Source.Inner
+				}
+				return
+			}
+
+			if n := len(groups); n > 0 && attrValue(groups[n-1].Attrs, "restype") == PO_PLURALS_RESTYPE {
+				if group := groups[n-1]; unit == &group.TransUnit[0] {
+					entries = append(entries, tp.pluralEntry(group))
+				}
+				return
+			}
+
+			e := &poEntry{ID: unit.Source.Text(tp.inline)}
+			e.Ctxt = poContext(attrValue(unit.Attrs, "resname"), unit.ID, e.ID)
+			e.Str = []string{tp.target(unit, e)}
+			e.metaFrom(unit)
+			entries = append(entries, e)
+		})
+	}
+
+	if header == "" {
+		header = "MIME-Version: 1.0\n"
+	}
+	header = setPOHeaderField(header, "Content-Type", "text/plain; charset=UTF-8")
+	header = setPOHeaderField(header, "Content-Transfer-Encoding", "8bit")
+	header = setPOHeaderField(header, "Language", lang)
+
+	buf := bytes.NewBuffer(nil)
+	(&poEntry{Str: []string{header}}).writeTo(buf)
+	for _, e := range entries {
+		buf.WriteString("\n")
+		e.writeTo(buf)
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// pluralEntry turns a group of plural forms into one entry
+func (tp *toPO) pluralEntry(group *xliffGroup) *poEntry {
+
+	units := group.TransUnit
+	e := &poEntry{ID: units[0].Source.Text(tp.inline)}
+	e.IDPlural = e.ID
+	if len(units) > 1 {
+		e.IDPlural = units[1].Source.Text(tp.inline)
+	}
+	e.Ctxt = poContext(group.ResName, group.ID, e.ID)
+	for i := range units {
+		e.Str = append(e.Str, tp.target(&units[i], e))
+	}
+	e.metaFrom(&units[0])
+	return e
+}
+
+// target returns the translation of unit and marks e as fuzzy if the
+// translation needs a review
+func (tp *toPO) target(unit *xliffTransUnit, e *poEntry) string {
+	if unit.Target == nil {
+		return ""
+	}
+	text := unit.Target.Text(tp.inline)
+	if text != "" && strings.HasPrefix(unit.Target.State, "needs-") && !e.hasFlag("fuzzy") {
+		e.Flags = append([]string{"fuzzy"}, e.Flags...)
+	}
+	return text
+}
+
+// poContext returns the msgctxt of an entry: the resname if there is
+// one, otherwise the id unless it is just the msgid
+func poContext(resName, id, msgid string) string {
+	if resName != "" {
+		return resName
+	}
+	if id != msgid {
+		return id
+	}
+	return ""
+}
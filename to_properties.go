@@ -0,0 +1,96 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+)
+
+// toProperties writes the translations of a XLIFF as the .properties of
+// a Java ResourceBundle. units which are not translated are left out, the
+// ResourceBundle falls back to the base bundle for them.
+type toProperties struct {
+	inFile      string
+	source      bool
+	encoding    string
+	apostrophes string
+}
+
+func init() {
+	registeredConverters["to-properties"] = new(toProperties)
+}
+
+func (tp *toProperties) Description() string {
+	return "Converts XLIFF to Java .properties"
+}
+
+func (tp *toProperties) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" to-properties", flag.ExitOnError)
+	fs.StringVar(&tp.inFile, "in", "", "infile")
+	fs.BoolVar(&tp.source, "source", false, "write the sources (the base bundle) instead of the targets")
+	fs.StringVar(&tp.encoding, "encoding", PROPERTIES_LATIN1, "encoding to write: iso-8859-1 (with \\uXXXX escapes) or utf-8")
+	fs.StringVar(&tp.apostrophes, "apostrophes", APOSTROPHES_ARGS, "double the apostrophes of MessageFormat messages: args, all or none")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if tp.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	if tp.encoding != PROPERTIES_UTF8 && tp.encoding != PROPERTIES_LATIN1 {
+		return fmt.Errorf("unsupported 'encoding': %q", tp.encoding)
+	}
+	if !isValidApostrophes(tp.apostrophes) {
+		return fmt.Errorf("unsupported 'apostrophes': %q", tp.apostrophes)
+	}
+	return nil
+}
+
+func (tp *toProperties) Prepare() error {
+	return nil
+}
+
+func (tp *toProperties) Convert(w io.Writer) error {
+
+	doc, err := xliffFromFile(tp.inFile)
+	if err != nil {
+		return err
+	}
+
+	var entries []propEntry
+	keys := map[string]bool{}
+	for _, file := range doc.File {
+		file.Body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+			value := unit.Source.Text(INLINE_PLAIN)
+			if !tp.source {
+				if unit.Target == nil || attrValue(unit.Attrs, "translate") == "no" {
+					return
+				}
+				value = unit.Target.Text(INLINE_PLAIN)
+			}
+			if value == "" {
+				return
+			}
+			if keys[unit.ID] {
+				log.Printf("warning: double entry for key %q", unit.ID)
+				return
+			}
+			keys[unit.ID] = true
+			entries = append(entries, propEntry{
+				Key:     unit.ID,
+				Value:   doubleApostrophes(value, tp.apostrophes),
+				Comment: unit.Note,
+			})
+		})
+	}
+
+	return writeProperties(w, entries, tp.encoding)
+}
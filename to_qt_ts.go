@@ -0,0 +1,113 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// toQtTS writes a XLIFF as Qt Linguist .ts, ready for lrelease. units
+// outside of a context group go into a context named after their
+// <file>.
+type toQtTS struct {
+	inFile string
+}
+
+func init() {
+	registeredConverters["to-qt-ts"] = new(toQtTS)
+}
+
+func (tq *toQtTS) Description() string {
+	return "Converts XLIFF to Qt Linguist .ts"
+}
+
+func (tq *toQtTS) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" to-qt-ts", flag.ExitOnError)
+	fs.StringVar(&tq.inFile, "in", "", "infile")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if tq.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (tq *toQtTS) Prepare() error {
+	return nil
+}
+
+func (tq *toQtTS) Convert(w io.Writer) error {
+
+	doc, err := xliffFromFile(tq.inFile)
+	if err != nil {
+		return err
+	}
+
+	ts := &qtTS{Version: "2.1"}
+	contexts := map[string]int{}
+	for _, file := range doc.File {
+		if ts.Language == "" {
+			ts.Language = langLocale(file.TargetLang)
+			ts.SourceLanguage = langLocale(file.SourceLang)
+		}
+
+		file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+
+			name, numerus := file.Original, false
+			for _, group := range groups {
+				switch attrValue(group.Attrs, "restype") {
+				case QT_CONTEXT_RESTYPE:
+					name = group.ID
+				case QT_NUMERUS_RESTYPE:
+					numerus = true
+				}
+			}
+			i, exists := contexts[name]
+			if !exists {
+				i = len(ts.Contexts)
+				contexts[name] = i
+				ts.Contexts = append(ts.Contexts, qtContext{Name: name})
+			}
+			ctx := &ts.Contexts[i]
+
+			text, state := "", ""
+			if unit.Target != nil {
+				text, state = unit.Target.Text(INLINE_PLAIN), unit.Target.State
+				if text == "" && qtType(state) == "" {
+					state = "new"
+				}
+			}
+
+			if numerus && unit != &groups[len(groups)-1].TransUnit[0] {
+				tr := &ctx.Messages[len(ctx.Messages)-1].Translation
+				tr.Forms = append(tr.Forms, text)
+				if tr.Type == "" {
+					tr.Type = qtType(state)
+				}
+				return
+			}
+
+			msg := qtMessage{Source: unit.Source.Text(INLINE_PLAIN)}
+			msg.metaFrom(unit)
+			msg.Translation.Type = qtType(state)
+			if numerus {
+				msg.Numerus = "yes"
+				msg.Translation.Forms = []string{text}
+			} else {
+				msg.Translation.Text = text
+			}
+			ctx.Messages = append(ctx.Messages, msg)
+		})
+	}
+
+	return writeTS(w, ts)
+}
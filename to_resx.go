@@ -0,0 +1,130 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// toResx writes the translations of XLIFF files as Resources.<lang>.resx
+// next to the neutral .resx they were made from (-template): its header
+// and the resources which are not strings are kept as they are, the
+// strings are translated. strings which are not translated are left out,
+// .NET falls back to the neutral resources for them.
+type toResx struct {
+	template string
+	inFiles  []string
+	outDir   string
+	source   bool
+}
+
+func init() {
+	registeredConverters["to-resx"] = new(toResx)
+}
+
+func (tr *toResx) Description() string {
+	return "Converts XLIFF files to .NET .resx"
+}
+
+func (tr *toResx) ParseArgs(base string, args []string) error {
+	var inFile string
+	var fs = flag.NewFlagSet(base+" to-resx", flag.ExitOnError)
+	fs.StringVar(&tr.template, "template", "", "the neutral .resx (Resources.resx)")
+	fs.StringVar(&inFile, "in", "", "infile (more files can follow as arguments)")
+	fs.StringVar(&tr.outDir, "out", "", "directory to write Resources.<lang>.resx to (default: write a single file to stdout)")
+	fs.BoolVar(&tr.source, "source", false, "write the sources into the neutral .resx instead")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if inFile != "" {
+		tr.inFiles = append(tr.inFiles, inFile)
+	}
+	tr.inFiles = append(tr.inFiles, fs.Args()...)
+	if tr.template == "" {
+		return fmt.Errorf("missing -template")
+	}
+	if len(tr.inFiles) == 0 {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (tr *toResx) Prepare() error {
+	return nil
+}
+
+func (tr *toResx) Convert(w io.Writer) error {
+
+	resx, err := resxFromFile(tr.template)
+	if err != nil {
+		return err
+	}
+
+	var langs []string
+	values := map[string]map[string]string{}
+	for _, name := range tr.inFiles {
+		doc, err := xliffFromFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		for _, file := range doc.File {
+			lang := file.TargetLang
+			if tr.source {
+				lang = file.SourceLang
+			}
+			if lang == "" {
+				return fmt.Errorf("%s: missing target-language", name)
+			}
+			if values[lang] == nil {
+				values[lang] = map[string]string{}
+				langs = append(langs, lang)
+			}
+			file.Body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+				if tr.source {
+					values[lang][unit.ID] = unit.Source.Text(INLINE_PLAIN)
+					return
+				}
+				if unit.Target == nil || attrValue(unit.Attrs, "translate") == "no" {
+					return
+				}
+				if value := unit.Target.Text(INLINE_PLAIN); value != "" {
+					values[lang][unit.ID] = value
+				}
+			})
+		}
+	}
+
+	if tr.outDir == "" {
+		if len(langs) != 1 {
+			return fmt.Errorf("%d languages to write, missing -out", len(langs))
+		}
+		return resx.write(w, values[langs[0]], tr.source)
+	}
+
+	for _, lang := range langs {
+		buf := bytes.NewBuffer(nil)
+		if err := resx.write(buf, values[lang], tr.source); err != nil {
+			return err
+		}
+		fileName := filepath.Join(tr.outDir, resxSatellite(tr.template, lang))
+		if tr.source {
+			fileName = filepath.Join(tr.outDir, filepath.Base(tr.template))
+		}
+		if err := ioutil.WriteFile(fileName, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s: %d strings\n", fileName, len(values[lang]))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// toTMX collects the translations of one or more XLIFF files into a TMX.
+// units with the same source (in the same source language) become one
+// translation unit holding a variant per target language.
+type toTMX struct {
+	inFiles []string
+	review  bool
+}
+
+func init() {
+	registeredConverters["to-tmx"] = new(toTMX)
+}
+
+func (tt *toTMX) Description() string {
+	return "Converts XLIFF files to TMX"
+}
+
+func (tt *toTMX) ParseArgs(base string, args []string) error {
+	var inFile string
+	var fs = flag.NewFlagSet(base+" to-tmx", flag.ExitOnError)
+	fs.StringVar(&inFile, "in", "", "infile (more files can follow as arguments)")
+	fs.BoolVar(&tt.review, "needs-review", false, "export translations which need a review as well")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if inFile != "" {
+		tt.inFiles = append(tt.inFiles, inFile)
+	}
+	tt.inFiles = append(tt.inFiles, fs.Args()...)
+	if len(tt.inFiles) == 0 {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (tt *toTMX) Prepare() error {
+	return nil
+}
+
+func (tt *toTMX) Convert(w io.Writer) error {
+
+	tmx := &tmxDoc{Version: "1.4"}
+	tus := map[string]int{} // index in tmx.TU by source language and source
+	ids := map[int]tmxIDs{} // the numbers of the codes of each tu
+	srcLangs := map[string]bool{}
+
+	for _, name := range tt.inFiles {
+		doc, err := xliffFromFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+
+		for _, file := range doc.File {
+			file.Body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+				if !tt.exported(unit) {
+					return
+				}
+				targetLang := file.TargetLang
+				if unit.Target.Lang != "" {
+					targetLang = unit.Target.Lang
+				}
+				if sameLang(targetLang, file.SourceLang) {
+					return // would replace the source variant
+				}
+
+				source := unit.Source.Text(INLINE_XML)
+				key := normLang(file.SourceLang) + "\n" + source
+				i, exists := tus[key]
+				if !exists {
+					i = len(tmx.TU)
+					tus[key] = i
+					ids[i] = tmxIDs{}
+					tmx.TU = append(tmx.TU, tmxTU{
+						TUID: unit.ID,
+						TUV:  []tmxTUV{tmxVariant(file.SourceLang, unit.Source.content(), ids[i])},
+					})
+					srcLangs[normLang(file.SourceLang)] = true
+				}
+				tu := &tmx.TU[i]
+
+				variant := tmxVariant(targetLang, (*xliffSource)(unit.Target).content(), ids[i])
+				if existing := tu.variant(targetLang); existing != nil {
+					*existing = variant // the file given last wins
+				} else {
+					tu.TUV = append(tu.TUV, variant)
+				}
+			})
+		}
+	}
+
+	// the source language goes into the header if there is just one,
+	// into the tus otherwise
+	if len(srcLangs) > 1 {
+		tmx.Header.SrcLang = TMX_ALL_LANGS
+		for i := range tmx.TU {
+			tmx.TU[i].SrcLang = tmx.TU[i].TUV[0].Lang
+		}
+	} else if len(tmx.TU) > 0 {
+		tmx.Header.SrcLang = tmx.TU[0].TUV[0].Lang
+	}
+	tmx.Header.DataType = "xml"
+
+	return writeTMX(w, tmx)
+}
+
+// exported reports if the translation of unit goes into the TMX
+func (tt *toTMX) exported(unit *xliffTransUnit) bool {
+	if unit.Target == nil || strings.TrimSpace(unit.Target.Inner) == "" {
+		return false
+	}
+	state := unit.Target.State
+	if state == "new" || strings.HasPrefix(state, "needs-") {
+		return tt.review && strings.HasPrefix(state, "needs-review-")
+	}
+	return true
+}
+
+func tmxVariant(lang string, content xliffContent, ids tmxIDs) tmxTUV {
+	content = xliffToTMX(content, ids)
+	return tmxTUV{Lang: lang, Seg: xliffSource{Content: content, Inner: content.Plain()}}
+}
@@ -0,0 +1,125 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+)
+
+// toXcstrings merges the translations of XLIFF files into a String
+// Catalog. only the stringUnits of translated units are changed,
+// everything else of the catalog (comments, extraction states, other
+// languages) is kept as it is.
+type toXcstrings struct {
+	catalog string
+	inFiles []string
+
+	cat *xcCatalog
+}
+
+func init() {
+	registeredConverters["to-xcstrings"] = new(toXcstrings)
+}
+
+func (tx *toXcstrings) Description() string {
+	return "Merges XLIFF files into an Xcode String Catalog (.xcstrings)"
+}
+
+func (tx *toXcstrings) ParseArgs(base string, args []string) error {
+	var inFile string
+	var fs = flag.NewFlagSet(base+" to-xcstrings", flag.ExitOnError)
+	fs.StringVar(&tx.catalog, "catalog", "", "the String Catalog to merge into (.xcstrings)")
+	fs.StringVar(&inFile, "in", "", "infile (more files can follow as arguments)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if inFile != "" {
+		tx.inFiles = append(tx.inFiles, inFile)
+	}
+	tx.inFiles = append(tx.inFiles, fs.Args()...)
+	if tx.catalog == "" {
+		return fmt.Errorf("missing -catalog")
+	}
+	if len(tx.inFiles) == 0 {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+// Prepare reads the catalog before -o is created, which may be the
+// catalog itself
+func (tx *toXcstrings) Prepare() error {
+	var err error
+	tx.cat, err = xcstringsFromFile(tx.catalog)
+	return err
+}
+
+func (tx *toXcstrings) Convert(w io.Writer) error {
+
+	cat := tx.cat
+	for _, name := range tx.inFiles {
+		doc, err := xliffFromFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		for i := range doc.File {
+			file := &doc.File[i]
+			if file.TargetLang == "" {
+				return fmt.Errorf("%s: missing target-language", name)
+			}
+			file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+				tx.merge(cat, file.TargetLang, groups, unit)
+			})
+		}
+	}
+
+	return writeXcstrings(w, cat)
+}
+
+// merge sets the stringUnit of a unit to its target
+func (tx *toXcstrings) merge(cat *xcCatalog, lang string, groups []*xliffGroup, unit *xliffTransUnit) {
+
+	key, path := unit.ID, ""
+	if n := len(groups); n > 0 && attrValue(groups[n-1].Attrs, "restype") == XCSTRINGS_VARIATIONS_RESTYPE {
+		key, path = groups[n-1].ID, attrValue(unit.Attrs, "resname")
+		if attrValue(groups[n-1].Attrs, "translate") == "no" {
+			return
+		}
+	}
+	if unit.Target == nil || attrValue(unit.Attrs, "translate") == "no" {
+		return
+	}
+	value := unit.Target.Text(INLINE_PLAIN)
+	if value == "" {
+		return
+	}
+
+	entry := xcChild(cat.strings(), key)
+	if entry == nil {
+		log.Printf("warning: key %q is not in the catalog", key)
+		return
+	}
+
+	get := func(obj xcObject, name string) xcObject {
+		child := xcChild(obj, name)
+		if child == nil {
+			child = xcObject{}
+			obj[name] = map[string]interface{}(child)
+		}
+		return child
+	}
+	locs := get(entry, "localizations")
+	node := xcNode(get(locs, lang), xcChild(locs, cat.sourceLang()), path)
+	stringUnit := get(node, "stringUnit")
+	stringUnit["state"] = xcState(unit.Target.State)
+	stringUnit["value"] = value
+}
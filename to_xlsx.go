@@ -23,7 +23,10 @@ import (
 //   key | note            | source | ... | <target>
 //   a.b | first entry     | hi     | ... | hey
 //   b.c | important entry | cya    | ....| bye
+//
+// "to-ods" creates a .ods instead, as does appending to a .ods.
 type toXLSX struct {
+	ods          bool
 	inFile       string
 	appendFile   string
 	appendSheet  string
@@ -39,17 +42,25 @@ type toXLSX struct {
 
 func init() {
 	registeredConverters["to-xlsx"] = new(toXLSX)
+	registeredConverters["to-ods"] = &toXLSX{ods: true}
 }
 
 func (plugin *toXLSX) Description() string {
+	if plugin.ods {
+		return "Converts XLIFF to ODS"
+	}
 	return "Converts XLIFF to XLSX"
 }
 
 func (conv *toXLSX) ParseArgs(base string, args []string) error {
-	var fs = flag.NewFlagSet(base+" to-xlsx", flag.ExitOnError)
+	name := "to-xlsx"
+	if conv.ods {
+		name = "to-ods"
+	}
+	var fs = flag.NewFlagSet(base+" "+name, flag.ExitOnError)
 	fs.StringVar(&conv.inFile, "in", "", "infile")
-	fs.StringVar(&conv.appendFile, "append", "", ".xlsx file to append to")
-	fs.StringVar(&conv.appendSheet, "sheet", "", "sheet of .xlsx to append to")
+	fs.StringVar(&conv.appendFile, "append", "", ".xlsx (or .ods) file to append to")
+	fs.StringVar(&conv.appendSheet, "sheet", "", "sheet of .xlsx (or .ods) to append to")
 	fs.IntVar(&conv.headRow, "head-row", 0, "row which holds the header")
 	fs.IntVar(&conv.keyColumn, "key-column", 0, "column holding the key / msgid")
 	fs.StringVar(&conv.keyMatch, "key-match", "", "translate chars in key (regexp)")
@@ -130,10 +141,13 @@ func (conv *toXLSX) Convert(w io.Writer) error {
 	appendix := make([]xlEntry, 0)
 
 	for _, file := range doc.File {
-		for _, unit := range file.Body.TransUnit {
+		for _, unit := range file.Body.Units() {
 
 			key := keyTrans(unit.ID)
-			entry := xlEntry{key, unit.Note, unit.Source.Inner, unit.Target.Inner}
+			entry := xlEntry{key, unit.Note, unit.Source.Inner, ""}
+			if unit.Target != nil {
+				entry.Target = unit.Target.Inner
+			}
 			row, exists := existingKeys[key]
 			if !exists {
 				appendix = append(appendix, entry)
@@ -161,6 +175,9 @@ func (conv *toXLSX) Convert(w io.Writer) error {
 		}
 	}
 
+	if conv.ods {
+		return odsWrite(w, conv.xlFile)
+	}
 	conv.xlFile.Write(w)
 
 	return err
@@ -188,7 +205,10 @@ func (conv *toXLSX) newOrAppend() (*xlsx.File, *xlsx.Sheet, error) {
 
 	} else {
 
-		f, err := xlsx.OpenFile(conv.appendFile)
+		if isODS(conv.appendFile) {
+			conv.ods = true
+		}
+		f, err := openSpreadsheet(conv.appendFile)
 		if err != nil {
 			return nil, nil, err
 		}
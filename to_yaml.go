@@ -0,0 +1,118 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// toYAML writes the translations of a XLIFF as Rails locale file, the
+// dotted ids turned into nested keys below the target language. units
+// which are not translated are left out, the I18n fallbacks of Rails
+// take care of them.
+type toYAML struct {
+	inFile string
+	source bool
+}
+
+func init() {
+	registeredConverters["to-yaml"] = new(toYAML)
+}
+
+func (ty *toYAML) Description() string {
+	return "Converts XLIFF to a Rails YAML locale file"
+}
+
+func (ty *toYAML) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" to-yaml", flag.ExitOnError)
+	fs.StringVar(&ty.inFile, "in", "", "infile")
+	fs.BoolVar(&ty.source, "source", false, "write the sources (below the source language) instead of the targets")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if ty.inFile == "" {
+		return fmt.Errorf("missing -in")
+	}
+	return nil
+}
+
+func (ty *toYAML) Prepare() error {
+	return nil
+}
+
+func (ty *toYAML) Convert(w io.Writer) error {
+
+	doc, err := xliffFromFile(ty.inFile)
+	if err != nil {
+		return err
+	}
+
+	lang := ""
+	var entries []yamlEntry
+	for _, file := range doc.File {
+		fileLang := file.TargetLang
+		if ty.source {
+			fileLang = file.SourceLang
+		}
+		if fileLang == "" {
+			return fmt.Errorf("%s: missing target-language", ty.inFile)
+		}
+		if lang != "" && !sameLang(lang, fileLang) {
+			return fmt.Errorf("%s: more than one language: %s, %s", ty.inFile, lang, fileLang)
+		}
+		lang = fileLang
+
+		// the texts of the file come first in the walk, the plural
+		// groups go before the text they were found in front of
+		type positioned struct {
+			position int
+			entry    yamlEntry
+		}
+		var fileEntries []positioned
+		texts := 0
+		file.Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+			position := 2*len(file.Body.TransUnit) + 2
+			if len(groups) == 0 {
+				position = 2*texts + 1
+				texts++
+			} else if p, err := strconv.Atoi(unit.context(YAML_CONTEXT_GROUP, YAML_POSITION)); err == nil {
+				position = 2 * p
+			}
+
+			value := unit.Source.Text(INLINE_PLAIN)
+			if ty.source {
+				if isTargetForm(unit) {
+					return
+				}
+			} else {
+				if unit.Target == nil || attrValue(unit.Attrs, "translate") == "no" {
+					return
+				}
+				value = unit.Target.Text(INLINE_PLAIN)
+			}
+			if value == "" {
+				return
+			}
+			fileEntries = append(fileEntries, positioned{position, yamlEntry{Path: strings.Split(unit.ID, "."), Value: value, Comment: unit.Note}})
+		})
+		sort.SliceStable(fileEntries, func(i, j int) bool {
+			return fileEntries[i].position < fileEntries[j].position
+		})
+		for _, e := range fileEntries {
+			entries = append(entries, e.entry)
+		}
+	}
+
+	return writeYAML(w, lang, entries)
+}
@@ -0,0 +1,57 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// xliffUpgrade converts a XLIFF between version 1.2 and 2.x. it is
+// registered twice: as "upgrade" (to 2.0) and as "downgrade" (to 1.2).
+type xliffUpgrade struct {
+	name    string
+	inFile  string
+	version string
+}
+
+func init() {
+	registeredConverters["upgrade"] = &xliffUpgrade{name: "upgrade", version: "2.0"}
+	registeredConverters["downgrade"] = &xliffUpgrade{name: "downgrade", version: "1.2"}
+}
+
+func (u *xliffUpgrade) Description() string {
+	return fmt.Sprintf("Converts a XLIFF to version %s", u.version)
+}
+
+func (u *xliffUpgrade) ParseArgs(base string, args []string) error {
+	var fs = flag.NewFlagSet(base+" "+u.name, flag.ExitOnError)
+	fs.StringVar(&u.inFile, "in", "", "infile")
+	fs.StringVar(&u.version, "to", u.version, "XLIFF version to convert to")
+	return fs.Parse(args)
+}
+
+func (u *xliffUpgrade) Prepare() error {
+	return nil
+}
+
+func (u *xliffUpgrade) Convert(w io.Writer) error {
+
+	var doc, err = xliffFromFile(u.inFile)
+	if err != nil {
+		return err
+	}
+
+	if err = doc.SetVersion(u.version); err != nil {
+		return err
+	}
+
+	return writeXliff(w, doc, "  ")
+}
@@ -0,0 +1,324 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// a String Catalog (.xcstrings) of Xcode holds the strings of all
+// languages in one JSON file:
+//
+//	{
+//	  "sourceLanguage" : "en",
+//	  "strings" : {
+//	    "%lld notes" : {
+//	      "comment" : "...",
+//	      "extractionState" : "manual",
+//	      "localizations" : {
+//	        "de" : {
+//	          "variations" : {
+//	            "plural" : {
+//	              "one" : {
+//	                "stringUnit" : { "state" : "translated", "value" : "%lld Notiz" }
+//	              },
+//	              ...
+//
+// the catalog is kept as it was read (maps of interface{}), so that
+// merging translations back touches nothing else. the strings of a
+// localization are addressed by a path: "" for the stringUnit of the
+// localization itself, "plural.one" or "device.iphone.plural.one" for
+// variations, "substitutions.count.plural.one" for the variations of a
+// substitution. a key with variations becomes a <group
+// restype="x-xcstrings-variations"> holding a trans-unit per path
+// (id "key[plural.one]", resname "plural.one").
+const (
+	XCSTRINGS_VARIATIONS_RESTYPE = "x-xcstrings-variations"
+	XCSTRINGS_CONTEXT_GROUP      = "xcstrings"
+	XCSTRINGS_EXTRACTION_STATE   = "x-xcstrings-extraction-state"
+)
+
+type xcObject map[string]interface{}
+
+// xcCatalog is a String Catalog as read
+type xcCatalog struct {
+	root xcObject
+}
+
+func xcstringsFromFile(fileName string) (*xcCatalog, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := json.NewDecoder(f)
+	d.UseNumber()
+	var root xcObject
+	if err = d.Decode(&root); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	return &xcCatalog{root: root}, nil
+}
+
+func (cat *xcCatalog) sourceLang() string {
+	lang, _ := cat.root["sourceLanguage"].(string)
+	return lang
+}
+
+func (cat *xcCatalog) strings() xcObject {
+	strs, _ := cat.root["strings"].(map[string]interface{})
+	return strs
+}
+
+// keys returns the keys of the strings, sorted
+func (cat *xcCatalog) keys() []string {
+	keys := make([]string, 0, len(cat.strings()))
+	for key := range cat.strings() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// langs returns the languages of the localizations, sorted
+func (cat *xcCatalog) langs() []string {
+	langs := map[string]bool{}
+	for _, key := range cat.keys() {
+		for lang := range xcChild(cat.strings(), key, "localizations") {
+			langs[lang] = true
+		}
+	}
+	sorted := make([]string, 0, len(langs))
+	for lang := range langs {
+		sorted = append(sorted, lang)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// xcChild follows path from obj, nil if it leads nowhere
+func xcChild(obj xcObject, path ...string) xcObject {
+	for _, name := range path {
+		child, ok := obj[name].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		obj = child
+	}
+	return obj
+}
+
+// xcLeaf is a string of a localization
+type xcLeaf struct {
+	path string
+	unit xcObject // the stringUnit
+}
+
+// xcLeaves returns the strings of a localization. plural forms are
+// ordered by quantity, everything else by name.
+func xcLeaves(loc xcObject, prefix string) []xcLeaf {
+
+	var leaves []xcLeaf
+	if unit := xcChild(loc, "stringUnit"); unit != nil {
+		leaves = append(leaves, xcLeaf{prefix, unit})
+	}
+
+	join := func(names ...string) string {
+		if prefix != "" {
+			names = append([]string{prefix}, names...)
+		}
+		return strings.Join(names, ".")
+	}
+
+	variations := xcChild(loc, "variations")
+	for _, kind := range xcSortedKeys(variations) {
+		cases := xcChild(variations, kind)
+		for _, name := range xcSortedKeys(cases) {
+			leaves = append(leaves, xcLeaves(xcChild(cases, name), join(kind, name))...)
+		}
+	}
+
+	substitutions := xcChild(loc, "substitutions")
+	for _, name := range xcSortedKeys(substitutions) {
+		leaves = append(leaves, xcLeaves(xcChild(substitutions, name), join("substitutions", name))...)
+	}
+	return leaves
+}
+
+// xcSortedKeys returns the keys of obj, sorted by name but plural forms
+// and "other" last, in the order of pluralQuantities
+func xcSortedKeys(obj xcObject) []string {
+	rank := func(key string) int {
+		for i, quantity := range pluralQuantities {
+			if key == quantity {
+				return i
+			}
+		}
+		return -1
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ri, rj := rank(keys[i]), rank(keys[j]); ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// xcNode returns the object of loc a path leads to, created if needed.
+// substitutions which are created get the fields of the ones of model
+// (argNum, formatSpecifier).
+func xcNode(loc, model xcObject, path string) xcObject {
+
+	if path == "" {
+		return loc
+	}
+	names := strings.Split(path, ".")
+	obj := loc
+	for i := 0; i+1 < len(names); i += 2 {
+		step := []string{"variations", names[i], names[i+1]}
+		if names[i] == "substitutions" {
+			step = []string{"substitutions", names[i+1]}
+		}
+		for j, name := range step {
+			child := xcChild(obj, name)
+			if child == nil {
+				child = xcObject{}
+				if j == len(step)-1 && names[i] == "substitutions" {
+					for field, value := range xcChild(model, step...) {
+						if field != "variations" {
+							child[field] = value
+						}
+					}
+				}
+				obj[name] = map[string]interface{}(child)
+			}
+			obj = child
+		}
+		if model != nil {
+			model = xcChild(model, step...)
+		}
+	}
+	return obj
+}
+
+// xliffState returns the state of a target for the state of a
+// stringUnit, and the other way around
+func xliffState(xcState string) string {
+	switch xcState {
+	case "translated":
+		return "translated"
+	case "needs_review":
+		return STATE_NEEDS_REVIEW
+	case "new", "":
+		return "new"
+	}
+	return "x-" + xcState
+}
+
+func xcState(xliffState string) string {
+	switch {
+	case xliffState == "new", xliffState == "needs-translation":
+		return "new"
+	case strings.HasPrefix(xliffState, "needs-"):
+		return "needs_review"
+	case strings.HasPrefix(xliffState, "x-"):
+		return strings.TrimPrefix(xliffState, "x-")
+	}
+	return "translated"
+}
+
+// writeXcstrings writes the catalog the way Xcode does: keys sorted,
+// indented by two spaces and " : " between key and value
+func writeXcstrings(w io.Writer, cat *xcCatalog) error {
+	buf := bytes.NewBuffer(nil)
+	if err := xcWriteValue(buf, cat.root, ""); err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func xcWriteValue(buf *bytes.Buffer, value interface{}, indent string) error {
+
+	switch v := value.(type) {
+	case xcObject:
+		return xcWriteValue(buf, map[string]interface{}(v), indent)
+	case map[string]interface{}:
+		if len(v) == 0 {
+			buf.WriteString("{\n\n" + indent + "}")
+			return nil
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteString("{\n")
+		for i, key := range keys {
+			buf.WriteString(indent + "  ")
+			writeJSONString(buf, key)
+			buf.WriteString(" : ")
+			if err := xcWriteValue(buf, v[key], indent+"  "); err != nil {
+				return err
+			}
+			if i+1 < len(keys) {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[\n\n" + indent + "]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, elem := range v {
+			buf.WriteString(indent + "  ")
+			if err := xcWriteValue(buf, elem, indent+"  "); err != nil {
+				return err
+			}
+			if i+1 < len(v) {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + "]")
+	case string:
+		writeJSONString(buf, v)
+	default:
+		out, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(out)
+	}
+	return nil
+}
+
+// writeJSONString writes s quoted, without escaping HTML
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // the newline of Encode
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestXcstringsRoundTrip(t *testing.T) {
+
+	catalog := "testdata/xcstrings/Localizable.xcstrings"
+	dir, err := ioutil.TempDir("", "xcstrings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the output directory is created
+	dir = filepath.Join(dir, "l10n")
+	fx := &fromXcstrings{inFile: catalog, targetLangs: []string{"de", "pl"}, outDir: dir}
+	if err = fx.Convert(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := xliffFromFile(filepath.Join(dir, "de.xlf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUnits(t, &doc.File[0].Body, func(unit *xliffTransUnit) string {
+		return unitState(unit) + ": " + unit.Source.Text(INLINE_PLAIN) + " -> " + unitTarget(unit)
+	}, map[string]string{
+		"App Name":                    ": App Name -> ",
+		"Delete":                      "needs-review-translation: Delete -> Löschen",
+		"Old Title":                   "translated: Old Title -> Alter Titel",
+		"%lld notes[plural.one]":      "translated: %lld note -> %lld Notiz",
+		"%lld notes[plural.other]":    "translated: %lld notes -> %lld Notizen",
+		"Tap to <edit>[device.mac]":   ": Click to <edit> -> ",
+		"Tap to <edit>[device.other]": ": Tap to <edit> -> ",
+		"files_synced[]":              ": Synced %#@files@ -> ",
+		"files_synced[substitutions.files.plural.one]":   ": %arg file -> ",
+		"files_synced[substitutions.files.plural.other]": ": %arg files -> ",
+	})
+
+	tx := &toXcstrings{catalog: catalog, inFiles: []string{filepath.Join(dir, "de.xlf"), filepath.Join(dir, "pl.xlf")}}
+	if err = tx.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	checkConverted(t, tx, catalog)
+}
+
+func TestXcstringsMerge(t *testing.T) {
+
+	catalog := "testdata/xcstrings/Localizable.xcstrings"
+	fx := &fromXcstrings{inFile: catalog, targetLangs: []string{"pl"}}
+	out := bytes.NewBuffer(nil)
+	if err := fx.Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := xliffFromReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	translations := map[string]string{
+		"Delete":         "Usuń",
+		"files_synced[]": "Zsynchronizowano %#@files@",
+		"files_synced[substitutions.files.plural.one]":   "%arg plik",
+		"files_synced[substitutions.files.plural.other]": "%arg plików",
+	}
+	for _, unit := range doc.File[0].Body.Units() {
+		if text, ok := translations[unit.ID]; ok {
+			unit.Target = &xliffTarget{Inner: text, State: "translated"}
+		}
+		if unit.ID == "Delete" {
+			unit.Target.State = "needs-review-translation"
+		}
+	}
+	// a plural form of the target language only
+	for i := range doc.File[0].Body.Group {
+		group := &doc.File[0].Body.Group[i]
+		if group.ID == "files_synced" {
+			group.TransUnit = append(group.TransUnit, xliffTransUnit{
+				ID:     "files_synced[substitutions.files.plural.few]",
+				Source: xliffSource{Inner: "%arg files"},
+				Target: &xliffTarget{Inner: "%arg pliki", State: "final"},
+			})
+			setAttr(&group.TransUnit[len(group.TransUnit)-1].Attrs, "resname", "substitutions.files.plural.few")
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "xcstrings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "pl.xlf")
+	buf := bytes.NewBuffer(nil)
+	if err = writeXliff(buf, doc, "  "); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(xlf, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := &toXcstrings{catalog: catalog, inFiles: []string{xlf}}
+	merged := filepath.Join(dir, "Localizable.xcstrings")
+	if err = tx.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err = tx.Convert(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(merged, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cat, err := xcstringsFromFile(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pl := func(key string) map[string]string {
+		leaves := map[string]string{}
+		for _, leaf := range xcLeaves(xcChild(cat.strings(), key, "localizations", "pl"), "") {
+			leaves[leaf.path] = leaf.unit["state"].(string) + ": " + leaf.unit["value"].(string)
+		}
+		return leaves
+	}
+	if got := pl("Delete"); got[""] != "needs_review: Usuń" {
+		t.Errorf("Delete: got %v", got)
+	}
+	got := pl("files_synced")
+	if got["substitutions.files.plural.few"] != "translated: %arg pliki" || got[""] != "translated: Zsynchronizowano %#@files@" {
+		t.Errorf("files_synced: got %v", got)
+	}
+	substitution := xcChild(cat.strings(), "files_synced", "localizations", "pl", "substitutions", "files")
+	if substitution["formatSpecifier"] != "lld" || substitution["argNum"] == nil {
+		t.Errorf("substitution without the fields of the source: %v", substitution)
+	}
+	if state := xcChild(cat.strings(), "Delete")["extractionState"]; state != "manual" {
+		t.Errorf("extraction state not kept: %v", state)
+	}
+}
@@ -12,42 +12,113 @@ import (
 	"bytes"
 	"encoding/xml"
 	"io"
+	"io/ioutil"
 	"os"
 )
 
+// the state of a target which needs a review: a fuzzy translation, a
+// match of a translation memory, ...
+const STATE_NEEDS_REVIEW = "needs-review-translation"
+
+// xliffSource holds the content of a <source> (or <target>) twice: Content
+// keeps the inline elements (<g>, <x/>, <mrk>, ...) as they were read,
+// Inner is the plain text of it. converters which deal with plain text
+// only just set Inner; if Inner does not match Content anymore, Inner
+// is written.
 type xliffSource struct {
 	XMLName xml.Name
-	Inner   string `xml:",chardata"`
-	Lang    string `xml:"lang,attr"`
-	Space   string `xml:"space,attr,omitempty"`
-	State   string `xml:"state,attr,omitempty"`
+	Inner   string       `xml:",chardata"`
+	Content xliffContent `xml:"-"`
+	Lang    string       `xml:"lang,attr"`
+	Space   string       `xml:"space,attr,omitempty"`
+	State   string       `xml:"state,attr,omitempty"`
+
+	raw *xmlNode
 }
 
 // xliffTarget might containt <mrk> tags which are leftovers from
 // translation tools. as a result, "chardata" of a <target> node
 // might be empty because all the translations are contained inside
-// several <mrk>tags</mrk>. this is why we treat <target> similar to
-// <source> but not equally.
+// several <mrk>tags</mrk>. Inner holds the text of all of them.
 type xliffTarget xliffSource
 
 type xliffTransUnit struct {
-	ID     string       `xml:"id,attr"`
-	Source xliffSource  `xml:"source"`
-	Target *xliffTarget `xml:"target,omitempty"`
-	Note   string       `xml:"note,omitempty"`
+	ID           string              `xml:"id,attr"`
+	Attrs        []xml.Attr          `xml:",any,attr"`
+	Source       xliffSource         `xml:"source"`
+	Target       *xliffTarget        `xml:"target,omitempty"`
+	Note         string              `xml:"note,omitempty"`
+	ContextGroup []xliffContextGroup `xml:"context-group"`
+	AltTrans     []xliffAltTrans     `xml:"alt-trans"`
+
+	raw  *xmlNode
+	raw2 *xmlNode // the <unit> of a XLIFF 2.x, see xliff2.go
+}
+
+// xliffAltTrans is a suggestion for the translation of a trans-unit, eg.
+// a match from a translation memory. Source is nil if the suggestion is
+// for the source of the unit.
+type xliffAltTrans struct {
+	MatchQuality string       `xml:"match-quality,attr,omitempty"`
+	Origin       string       `xml:"origin,attr,omitempty"`
+	Attrs        []xml.Attr   `xml:",any,attr"`
+	Source       *xliffSource `xml:"source"`
+	Target       xliffTarget  `xml:"target"`
+
+	raw *xmlNode
+}
+
+// xliffContextGroup holds information about the context of a trans-unit,
+// eg. where it is used in the code (purpose="location")
+type xliffContextGroup struct {
+	Name    string         `xml:"name,attr,omitempty"`
+	Purpose string         `xml:"purpose,attr,omitempty"`
+	Attrs   []xml.Attr     `xml:",any,attr"`
+	Context []xliffContext `xml:"context"`
+
+	raw *xmlNode
+}
+
+type xliffContext struct {
+	Type  string     `xml:"context-type,attr"`
+	Attrs []xml.Attr `xml:",any,attr"`
+	Inner string     `xml:",chardata"`
+
+	raw *xmlNode
+}
+
+// xliffGroup bundles trans-units (and other groups) which belong
+// together. groups can be nested arbitrarily deep.
+type xliffGroup struct {
+	ID        string           `xml:"id,attr,omitempty"`
+	ResName   string           `xml:"resname,attr,omitempty"`
+	Attrs     []xml.Attr       `xml:",any,attr"`
+	Note      []string         `xml:"note,omitempty"`
+	TransUnit []xliffTransUnit `xml:"trans-unit"`
+	Group     []xliffGroup     `xml:"group"`
+
+	raw  *xmlNode
+	raw2 *xmlNode
 }
 
 type xliffBody struct {
 	XMLName   xml.Name         `xml:"body"`
 	TransUnit []xliffTransUnit `xml:"trans-unit"`
+	Group     []xliffGroup     `xml:"group"`
+
+	raw *xmlNode
 }
 
 type xliffFile struct {
+	ID         string    `xml:"-"` // XLIFF 2.x only
 	Original   string    `xml:"original,attr"`
 	SourceLang string    `xml:"source-language,attr,omitempty"`
 	TargetLang string    `xml:"target-language,attr,omitempty"`
 	DataType   string    `xml:"datatype,attr,omitempty"`
 	Body       xliffBody `xml:"body"`
+
+	raw  *xmlNode
+	raw2 *xmlNode
 }
 
 type xliffDoc struct {
@@ -55,6 +126,11 @@ type xliffDoc struct {
 	Version string      `xml:"version,attr"`
 	Xmlns   string      `xml:"xmlns,attr"`
 	File    []xliffFile `xml:"file"`
+
+	// the document as it was read, nil for new documents. raw2 holds
+	// a XLIFF 2.x, so that it does not end up in a 1.2 and vice versa.
+	raw  *xmlNode
+	raw2 *xmlNode
 }
 
 func newXliffDoc(original, origLang string) *xliffDoc {
@@ -62,7 +138,7 @@ func newXliffDoc(original, origLang string) *xliffDoc {
 	var doc = new(xliffDoc)
 
 	doc.Version = "1.2"
-	doc.Xmlns = "urn:oasis:names:tc:xliff:document:1.2"
+	doc.Xmlns = XLIFF_NS_12
 	doc.File = make([]xliffFile, 1)
 	doc.File[0].Original = original
 	doc.File[0].Body.TransUnit = []xliffTransUnit{}
@@ -84,57 +160,324 @@ func xliffFromFile(fileName string) (*xliffDoc, error) {
 
 func xliffFromReader(r io.Reader) (*xliffDoc, error) {
 
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if isXliff2(data) {
+		return xliff2FromBytes(data)
+	}
+
 	doc := new(xliffDoc)
-	dec := xml.NewDecoder(r)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	if err = dec.Decode(doc); err != nil {
+		return nil, err
+	}
 
-	if err := dec.Decode(doc); err != nil {
+	// keep the document as it was read so that writeXliff is able
+	// to write back everything the typed model does not know about
+	root, err := parseXMLNodes(data)
+	if err != nil {
 		return nil, err
 	}
+	doc.link(root)
+
 	return doc, nil
 }
 
-func (to *xliffTarget) Copy(from *xliffSource) {
-	to.XMLName = from.XMLName
-	to.Inner = from.Inner
-	to.Lang = from.Lang
-	to.Space = from.Space
-	to.State = from.State
+// link connects the elements of doc with the nodes of the document
+// they were read from
+func (doc *xliffDoc) link(root *xmlNode) {
+	doc.raw = root
+	node := root.element("xliff")
+	if node == nil {
+		return
+	}
+	linkAttrs(node, doc.attrs())
+	for i, node := range node.elements("file") {
+		if i < len(doc.File) {
+			doc.File[i].link(node)
+		}
+	}
+}
+
+func (file *xliffFile) link(node *xmlNode) {
+	file.raw = node
+	linkAttrs(node, file.attrs())
+	if body := node.element("body"); body != nil {
+		file.Body.raw = body
+		linkUnits(body, file.Body.TransUnit, file.Body.Group)
+	}
 }
 
-// extract all chardata from a <target>-node, including all subnodes
-func (target *xliffTarget) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+func (group *xliffGroup) link(node *xmlNode) {
+	group.raw = node
+	linkAttrs(node, group.attrs())
+	for i, note := range node.elements("note") {
+		if i < len(group.Note) {
+			note.text = escapedText(group.Note[i])
+		}
+	}
+	linkUnits(node, group.TransUnit, group.Group)
+}
 
-	buf := bytes.NewBuffer(nil)
+func linkUnits(node *xmlNode, units []xliffTransUnit, groups []xliffGroup) {
+	for i, unit := range node.elements("trans-unit") {
+		if i < len(units) {
+			units[i].link(unit)
+		}
+	}
+	for i, group := range node.elements("group") {
+		if i < len(groups) {
+			groups[i].link(group)
+		}
+	}
+}
 
-	for {
-		token, err := d.Token()
-		if err == io.EOF {
-			break
+func (unit *xliffTransUnit) link(node *xmlNode) {
+	unit.raw = node
+	linkAttrs(node, unit.attrs())
+	if source := node.element("source"); source != nil {
+		unit.Source.link(source)
+	}
+	if target := node.element("target"); target != nil && unit.Target != nil {
+		(*xliffSource)(unit.Target).link(target)
+	}
+	if notes := node.elements("note"); len(notes) > 0 {
+		notes[len(notes)-1].text = escapedText(unit.Note)
+	}
+	for i, cg := range node.elements("context-group") {
+		if i < len(unit.ContextGroup) {
+			unit.ContextGroup[i].link(cg)
+		}
+	}
+	for i, alt := range node.elements("alt-trans") {
+		if i < len(unit.AltTrans) {
+			unit.AltTrans[i].link(alt)
 		}
-		if err != nil {
-			return err
+	}
+}
+
+func (alt *xliffAltTrans) link(node *xmlNode) {
+	alt.raw = node
+	linkAttrs(node, alt.attrs())
+	if source := node.element("source"); source != nil && alt.Source != nil {
+		alt.Source.link(source)
+	}
+	if target := node.element("target"); target != nil {
+		(*xliffSource)(&alt.Target).link(target)
+	}
+}
+
+func (cg *xliffContextGroup) link(node *xmlNode) {
+	cg.raw = node
+	linkAttrs(node, cg.attrs())
+	for i, ctx := range node.elements("context") {
+		if i < len(cg.Context) {
+			cg.Context[i].raw = ctx
+			linkAttrs(ctx, cg.Context[i].attrs())
+			ctx.text = escapedText(cg.Context[i].Inner)
 		}
+	}
+}
 
-		switch t := token.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "target" {
-				for _, attr := range t.Attr {
-					switch attr.Name.Local {
-					case "lang":
-						target.Lang = attr.Value
-					case "space":
-						target.Space = attr.Value
-					case "state":
-						target.State = attr.Value
-					}
-				}
+// context returns the content of the first <context> of the given type
+// inside the context-groups named group
+func (unit *xliffTransUnit) context(group, ctxType string) string {
+	for _, cg := range unit.ContextGroup {
+		if cg.Name != group {
+			continue
+		}
+		for _, ctx := range cg.Context {
+			if ctx.Type == ctxType {
+				return ctx.Inner
 			}
-		case xml.CharData:
-			buf.Write(t)
 		}
 	}
+	return ""
+}
+
+// addContext adds a <context> to the context-group named group, the
+// group is created if needed
+func (unit *xliffTransUnit) addContext(group, purpose, ctxType, value string) {
+	ctx := xliffContext{Type: ctxType, Inner: value}
+	for i := range unit.ContextGroup {
+		if cg := &unit.ContextGroup[i]; cg.Name == group && cg.Purpose == purpose {
+			cg.Context = append(cg.Context, ctx)
+			return
+		}
+	}
+	unit.ContextGroup = append(unit.ContextGroup, xliffContextGroup{
+		Name: group, Purpose: purpose, Context: []xliffContext{ctx},
+	})
+}
+
+// attrValue returns the value of the attribute collected via
+// `xml:",any,attr"`
+func attrValue(attrs []xml.Attr, local string) string {
+	for _, attr := range attrs {
+		if attr.Name.Local == local && (attr.Name.Space == "" || attr.Name.Space == xmlNamespace) {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// setAttr sets (or adds) the attribute collected via `xml:",any,attr"`
+func setAttr(attrs *[]xml.Attr, local, value string) {
+	for i, attr := range *attrs {
+		if attr.Name.Local == local && attr.Name.Space == "" {
+			(*attrs)[i].Value = value
+			return
+		}
+	}
+	*attrs = append(*attrs, xml.Attr{Name: xml.Name{Local: local}, Value: value})
+}
+
+// Walk calls fn for each trans-unit of the body, including the ones
+// nested in <group>s. groups holds the enclosing groups, outermost first.
+func (body *xliffBody) Walk(fn func(groups []*xliffGroup, unit *xliffTransUnit)) {
+	walkUnits(nil, body.TransUnit, body.Group, fn)
+}
+
+// Units returns all trans-units of the body, including the ones nested
+// in <group>s
+func (body *xliffBody) Units() []*xliffTransUnit {
+	units := []*xliffTransUnit{}
+	body.Walk(func(_ []*xliffGroup, unit *xliffTransUnit) {
+		units = append(units, unit)
+	})
+	return units
+}
+
+// Add appends unit to the body, nested in groups like the ones given:
+// groups are looked up by id and created (copying id, resname, attributes
+// and notes) if they do not exist. pointers to the units of the body
+// might be invalid afterwards.
+func (body *xliffBody) Add(groups []*xliffGroup, unit xliffTransUnit) {
+	units, sub := &body.TransUnit, &body.Group
+	for _, g := range groups {
+		group := findGroup(*sub, g.ID)
+		if group == nil {
+			*sub = append(*sub, xliffGroup{ID: g.ID, ResName: g.ResName, Attrs: g.Attrs, Note: g.Note})
+			group = &(*sub)[len(*sub)-1]
+		}
+		units, sub = &group.TransUnit, &group.Group
+	}
+	*units = append(*units, unit)
+}
+
+// Remove removes the trans-units for which drop returns true, including
+// the ones nested in <group>s. groups are kept even if they end up empty.
+func (body *xliffBody) Remove(drop func(unit *xliffTransUnit) bool) {
+	removeUnits(&body.TransUnit, body.Group, drop)
+}
+
+func removeUnits(units *[]xliffTransUnit, sub []xliffGroup, drop func(unit *xliffTransUnit) bool) {
+	kept := (*units)[:0]
+	for i := range *units {
+		if !drop(&(*units)[i]) {
+			kept = append(kept, (*units)[i])
+		}
+	}
+	*units = kept
+	for i := range sub {
+		removeUnits(&sub[i].TransUnit, sub[i].Group, drop)
+	}
+}
+
+func walkUnits(groups []*xliffGroup, units []xliffTransUnit, sub []xliffGroup,
+	fn func(groups []*xliffGroup, unit *xliffTransUnit)) {
+
+	for i := range units {
+		fn(groups, &units[i])
+	}
+	for i := range sub {
+		path := append(groups[:len(groups):len(groups)], &sub[i])
+		walkUnits(path, sub[i].TransUnit, sub[i].Group, fn)
+	}
+}
+
+// groupIDs returns the ids of the given groups, skipping groups
+// without id
+func groupIDs(groups []*xliffGroup) []string {
+	ids := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if group.ID != "" {
+			ids = append(ids, group.ID)
+		}
+	}
+	return ids
+}
+
+func (src *xliffSource) link(node *xmlNode) {
+	src.raw = node
+	linkAttrs(node, src.attrs())
+	node.text = src.innerXML()
+}
+
+// attrs returns the attributes of the elements the typed model knows
+// about, used to write and link them
+func (doc *xliffDoc) attrs() []xliffAttr {
+	return []xliffAttr{
+		{"version", &doc.Version, true},
+		{"xmlns", &doc.Xmlns, true},
+	}
+}
+
+func (file *xliffFile) attrs() []xliffAttr {
+	return []xliffAttr{
+		{"original", &file.Original, true},
+		{"source-language", &file.SourceLang, false},
+		{"target-language", &file.TargetLang, false},
+		{"datatype", &file.DataType, false},
+	}
+}
+
+func (group *xliffGroup) attrs() []xliffAttr {
+	return append([]xliffAttr{
+		{"id", &group.ID, false},
+		{"resname", &group.ResName, false},
+	}, anyAttrs(group.Attrs)...)
+}
 
-	target.Inner = buf.String()
+func (unit *xliffTransUnit) attrs() []xliffAttr {
+	return append([]xliffAttr{{"id", &unit.ID, true}}, anyAttrs(unit.Attrs)...)
+}
+
+func (cg *xliffContextGroup) attrs() []xliffAttr {
+	return append([]xliffAttr{
+		{"name", &cg.Name, false},
+		{"purpose", &cg.Purpose, false},
+	}, anyAttrs(cg.Attrs)...)
+}
+
+func (alt *xliffAltTrans) attrs() []xliffAttr {
+	return append([]xliffAttr{
+		{"match-quality", &alt.MatchQuality, false},
+		{"origin", &alt.Origin, false},
+	}, anyAttrs(alt.Attrs)...)
+}
+
+func (ctx *xliffContext) attrs() []xliffAttr {
+	return append([]xliffAttr{{"context-type", &ctx.Type, true}}, anyAttrs(ctx.Attrs)...)
+}
+
+func (src *xliffSource) attrs() []xliffAttr {
+	return []xliffAttr{
+		{"xml:lang", &src.Lang, false},
+		{"xml:space", &src.Space, false},
+		{"state", &src.State, false},
+	}
+}
 
-	return nil
+func (to *xliffTarget) Copy(from *xliffSource) {
+	to.XMLName = from.XMLName
+	to.Inner = from.Inner
+	to.Content = from.Content
+	to.Lang = from.Lang
+	to.Space = from.Space
+	to.State = from.State
 }
@@ -0,0 +1,712 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// XLIFF 2.x is read into the same model as XLIFF 1.2: a <unit> becomes a
+// trans-unit (its segments are joined), <notes> become the notes of the
+// unit or group, srcLang and trgLang become the languages of each file
+// and the inline elements are mapped onto their 1.2 counterparts:
+//
+//   2.x             1.2
+//   <pc>            <g>
+//   <ph/>           <x/>, or <ph> if the original data is known
+//   <sc/>, <ec/>    <bx/>, <ex/>, or <bpt>, <ept> if the original data is known
+//   <mrk>           <mrk>
+//   <cp hex="..."/> the character itself
+//
+// a 2.x file is linked to the nodes it was read from, just like a 1.2
+// one: what the model has no place for (notes of a <file>, modules, the
+// segments of a unit, the category of a note, ...) is written back as it
+// was read, unless the unit changed. it is lost when converting between
+// the versions.
+
+const (
+	XLIFF_NS_12 = "urn:oasis:names:tc:xliff:document:1.2"
+	XLIFF_NS_20 = "urn:oasis:names:tc:xliff:document:2.0"
+)
+
+type xliff2Doc struct {
+	XMLName xml.Name     `xml:"xliff"`
+	Version string       `xml:"version,attr"`
+	SrcLang string       `xml:"srcLang,attr"`
+	TrgLang string       `xml:"trgLang,attr"`
+	File    []xliff2File `xml:"file"`
+}
+
+type xliff2File struct {
+	ID       string        `xml:"id,attr"`
+	Original string        `xml:"original,attr"`
+	Unit     []xliff2Unit  `xml:"unit"`
+	Group    []xliff2Group `xml:"group"`
+}
+
+type xliff2Group struct {
+	ID    string        `xml:"id,attr"`
+	Name  string        `xml:"name,attr"`
+	Notes []string      `xml:"notes>note"`
+	Unit  []xliff2Unit  `xml:"unit"`
+	Group []xliff2Group `xml:"group"`
+}
+
+type xliff2Unit struct {
+	ID        string       `xml:"id,attr"`
+	Name      string       `xml:"name,attr"`
+	Translate string       `xml:"translate,attr"`
+	Notes     []string     `xml:"notes>note"`
+	Data      []xliff2Data `xml:"originalData>data"`
+
+	// <segment>s and <ignorable>s in document order
+	Parts []xliff2Segment `xml:",any"`
+}
+
+type xliff2Data struct {
+	ID    string `xml:"id,attr"`
+	Inner string `xml:",chardata"`
+}
+
+type xliff2Segment struct {
+	XMLName xml.Name
+	State   string       `xml:"state,attr"`
+	Source  xliffSource  `xml:"source"`
+	Target  *xliffTarget `xml:"target"`
+}
+
+// isXliff2 reports if data holds a XLIFF 2.x document
+func isXliff2(data []byte) bool {
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Space == XLIFF_NS_20 {
+				return true
+			}
+			for _, attr := range start.Attr {
+				if attr.Name.Space == "" && attr.Name.Local == "version" {
+					return strings.HasPrefix(attr.Value, "2.")
+				}
+			}
+			return false
+		}
+	}
+}
+
+func xliff2FromBytes(data []byte) (*xliffDoc, error) {
+
+	doc2 := new(xliff2Doc)
+	if err := xml.Unmarshal(data, doc2); err != nil {
+		return nil, err
+	}
+
+	doc := &xliffDoc{Version: doc2.Version, Xmlns: XLIFF_NS_20}
+	for _, file2 := range doc2.File {
+		file := xliffFile{
+			ID:         file2.ID,
+			Original:   file2.Original,
+			SourceLang: doc2.SrcLang,
+			TargetLang: doc2.TrgLang,
+		}
+		if file.Original == "" {
+			file.Original = file2.ID
+		}
+		for _, unit2 := range file2.Unit {
+			file.Body.TransUnit = append(file.Body.TransUnit, unit2.toUnit())
+		}
+		for _, group2 := range file2.Group {
+			file.Body.Group = append(file.Body.Group, group2.toGroup())
+		}
+		doc.File = append(doc.File, file)
+	}
+
+	root, err := parseXMLNodes(data)
+	if err != nil {
+		return nil, err
+	}
+	doc.link2(root)
+
+	return doc, nil
+}
+
+// link2 connects the elements of doc with the nodes of the 2.x document
+// they were read from
+func (doc *xliffDoc) link2(root *xmlNode) {
+	doc.raw2 = root
+	for i, node := range root.element("xliff").elements("file") {
+		if i < len(doc.File) {
+			doc.File[i].raw2 = node
+			linkUnits2(node, doc.File[i].Body.TransUnit, doc.File[i].Body.Group)
+		}
+	}
+}
+
+func linkUnits2(node *xmlNode, units []xliffTransUnit, groups []xliffGroup) {
+	for i, unit := range node.elements("unit") {
+		if i < len(units) {
+			units[i].link2(unit)
+		}
+	}
+	for i, group := range node.elements("group") {
+		if i < len(groups) {
+			groups[i].link2(group)
+		}
+	}
+}
+
+func (group *xliffGroup) link2(node *xmlNode) {
+	group.raw2 = node
+	for i, note := range node.element("notes").elements("note") {
+		if i < len(group.Note) {
+			note.text = escapedText(group.Note[i])
+		}
+	}
+	linkUnits2(node, group.TransUnit, group.Group)
+}
+
+// link2 keeps the unit as it is written, so that its segments are
+// written as they were read as long as it does not change
+func (unit *xliffTransUnit) link2(node *xmlNode) {
+	unit.raw2 = node
+	node.text = unit.segments2()
+	if notes := node.element("notes"); notes != nil {
+		notes.text = escapedText(unit.Note)
+	}
+}
+
+func (group2 *xliff2Group) toGroup() xliffGroup {
+	group := xliffGroup{ID: group2.ID, ResName: group2.Name, Note: group2.Notes}
+	for _, unit2 := range group2.Unit {
+		group.TransUnit = append(group.TransUnit, unit2.toUnit())
+	}
+	for _, sub := range group2.Group {
+		group.Group = append(group.Group, sub.toGroup())
+	}
+	return group
+}
+
+func (unit2 *xliff2Unit) toUnit() xliffTransUnit {
+
+	unit := xliffTransUnit{ID: unit2.ID, Note: strings.Join(unit2.Notes, "\n")}
+	if unit2.Name != "" {
+		unit.Attrs = append(unit.Attrs, xml.Attr{Name: xml.Name{Local: "resname"}, Value: unit2.Name})
+	}
+	if unit2.Translate != "" {
+		unit.Attrs = append(unit.Attrs, xml.Attr{Name: xml.Name{Local: "translate"}, Value: unit2.Translate})
+	}
+
+	data := map[string]string{}
+	for _, d := range unit2.Data {
+		data[d.ID] = d.Inner
+	}
+
+	var (
+		source, target xliffContent
+		hasTarget      bool
+		state          = ""
+	)
+
+	for _, part := range unit2.Parts {
+		switch part.XMLName.Local {
+		case "segment":
+			if part.Target != nil {
+				state = lowerState2(state, part.State)
+			}
+		case "ignorable":
+		default:
+			continue
+		}
+		source = append(source, part.Source.Content...)
+		if part.Target != nil {
+			hasTarget = true
+			target = append(target, part.Target.Content...)
+		} else {
+			target = append(target, part.Source.Content...)
+		}
+	}
+
+	source = inlineFrom2(source, data)
+	unit.Source = xliffSource{Content: source, Inner: source.Plain()}
+	if hasTarget {
+		target = inlineFrom2(target, data)
+		unit.Target = &xliffTarget{Content: target, Inner: target.Plain(), State: stateFrom2(state)}
+	}
+
+	return unit
+}
+
+// inlineFrom2 maps the XLIFF 2.x inline elements to XLIFF 1.2 ones.
+// data holds the <originalData> of the unit.
+func inlineFrom2(content xliffContent, data map[string]string) xliffContent {
+
+	out := xliffContent{}
+	for _, inline := range content {
+
+		if inline.Name == "" {
+			out = out.appendText(inline.Text)
+			continue
+		}
+
+		id := inline.attr("id")
+		code, hasCode := data[inline.attr("dataRef")]
+		var mapped xliffInline
+
+		switch inline.Name {
+		case "cp":
+			if r, err := strconv.ParseUint(inline.attr("hex"), 16, 32); err == nil {
+				out = out.appendText(string(rune(r)))
+			}
+			continue
+		case "pc":
+			mapped = newInline("g", "id", id)
+			mapped.Content = inlineFrom2(inline.Content, data)
+		case "ph":
+			mapped = newInline("x", "id", id)
+			if hasCode {
+				mapped = newInline("ph", "id", id)
+				mapped.Content = xliffContent{{Text: code}}
+			}
+		case "sc":
+			mapped = newInline("bx", "id", id, "rid", id)
+			if hasCode {
+				mapped = newInline("bpt", "id", id, "rid", id)
+				mapped.Content = xliffContent{{Text: code}}
+			}
+		case "ec":
+			ref := inline.attr("startRef")
+			if ref == "" {
+				ref = id
+			}
+			mapped = newInline("ex", "id", ref, "rid", ref)
+			if hasCode {
+				mapped = newInline("ept", "id", ref, "rid", ref)
+				mapped.Content = xliffContent{{Text: code}}
+			}
+		case "mrk":
+			mtype := inline.attr("type")
+			if mtype == "" || mtype == "generic" {
+				mtype = "x-generic"
+			}
+			mapped = newInline("mrk", "mid", id, "mtype", mtype)
+			mapped.Content = inlineFrom2(inline.Content, data)
+		default:
+			// <sm/>, <em/> and unknown elements: keep the text only
+			out = append(out, inlineFrom2(inline.Content, data)...)
+			continue
+		}
+		out = append(out, mapped)
+	}
+	return out
+}
+
+// inlineTo2 maps the XLIFF 1.2 inline elements to XLIFF 2.x ones. the
+// native code of <ph>, <bpt>, <ept> and <it> is collected in data.
+func inlineTo2(content xliffContent, data *[]xliff2Data) xliffContent {
+
+	out := xliffContent{}
+	for _, inline := range content {
+
+		if inline.Name == "" {
+			out = out.appendText(inline.Text)
+			continue
+		}
+
+		id := inline.attr("id")
+		rid := inline.attr("rid")
+		if rid == "" {
+			rid = id
+		}
+		var mapped xliffInline
+
+		switch inline.Name {
+		case "g":
+			mapped = newInline("pc", "id", id)
+			mapped.Content = inlineTo2(inline.Content, data)
+		case "x":
+			mapped = newInline("ph", "id", id)
+		case "bx":
+			mapped = newInline("sc", "id", rid)
+		case "ex":
+			mapped = newInline("ec", "startRef", rid)
+		case "ph", "it":
+			mapped = newInline("ph", "id", id, "dataRef", addData2(data, inline.Content))
+		case "bpt":
+			mapped = newInline("sc", "id", rid, "dataRef", addData2(data, inline.Content))
+		case "ept":
+			mapped = newInline("ec", "startRef", rid, "dataRef", addData2(data, inline.Content))
+		case "mrk":
+			inner := inlineTo2(inline.Content, data)
+			mtype := inline.attr("mtype")
+			if mtype == "seg" || mtype == "" {
+				out = append(out, inner...) // segmentation only
+				continue
+			}
+			if mtype != "term" {
+				mtype = "generic"
+			}
+			mapped = newInline("mrk", "id", inline.attr("mid"), "type", mtype)
+			mapped.Content = inner
+		default:
+			// <sub> and unknown elements
+			continue
+		}
+		out = append(out, mapped)
+	}
+	return out
+}
+
+// addData2 returns the id of the <data> holding the native code of code,
+// source and target share the <data> of equal codes
+func addData2(data *[]xliff2Data, code xliffContent) string {
+	native := code.nativeCode()
+	for _, d := range *data {
+		if d.Inner == native {
+			return d.ID
+		}
+	}
+	id := fmt.Sprintf("d%d", len(*data)+1)
+	*data = append(*data, xliff2Data{ID: id, Inner: native})
+	return id
+}
+
+// nativeCode returns the native code held by the content of a <ph>,
+// <bpt>, <ept> or <it>, without the text of any <sub>
+func (content xliffContent) nativeCode() string {
+	code := ""
+	for _, inline := range content {
+		switch inline.Name {
+		case "":
+			code += inline.Text
+		case "sub":
+		default:
+			code += inline.Content.nativeCode()
+		}
+	}
+	return code
+}
+
+// newInline creates an inline element with the given attributes. attributes
+// with an empty value are skipped.
+func newInline(name string, attrs ...string) xliffInline {
+	inline := xliffInline{Name: name}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			inline.Attrs = append(inline.Attrs, xml.Attr{Name: xml.Name{Local: attrs[i]}, Value: attrs[i+1]})
+		}
+	}
+	return inline
+}
+
+func (content xliffContent) appendText(text string) xliffContent {
+	if n := len(content); n > 0 && content[n-1].Name == "" {
+		content[n-1].Text += text
+		return content
+	}
+	return append(content, xliffInline{Text: text})
+}
+
+// the states of XLIFF 2.x, least advanced first
+var states2 = []string{"initial", "translated", "reviewed", "final"}
+
+func lowerState2(a, b string) string {
+	if b == "" {
+		b = "initial"
+	}
+	if a == "" {
+		return b
+	}
+	for _, state := range states2 {
+		if state == a || state == b {
+			return state
+		}
+	}
+	return a
+}
+
+func stateFrom2(state string) string {
+	switch state {
+	case "initial":
+		return "new"
+	case "reviewed":
+		return "signed-off"
+	}
+	return state
+}
+
+func stateTo2(state string) string {
+	switch state {
+	case "", "translated", "final":
+		return state
+	case "signed-off":
+		return "reviewed"
+	case "needs-review-translation", "needs-review-adaptation", "needs-review-l10n":
+		return "translated"
+	}
+	return "initial"
+}
+
+// SetVersion changes the XLIFF version doc is written as. an empty
+// version keeps the version doc was read with.
+func (doc *xliffDoc) SetVersion(version string) error {
+	switch version {
+	case "":
+	case "1.2":
+		doc.Version, doc.Xmlns = version, XLIFF_NS_12
+	case "2.0", "2.1":
+		doc.Version, doc.Xmlns = version, XLIFF_NS_20
+	default:
+		return fmt.Errorf("unsupported XLIFF version %q", version)
+	}
+	return nil
+}
+
+func (doc *xliffDoc) isXliff2() bool {
+	return strings.HasPrefix(doc.Version, "2.")
+}
+
+// xliffVersionFlag adds the -xliff-version flag to fs
+func xliffVersionFlag(fs *flag.FlagSet, version *string) {
+	fs.StringVar(version, "xliff-version", "", "XLIFF version to write: 1.2, 2.0, 2.1 (default: as read)")
+}
+
+// writeXliff2 writes doc as XLIFF 2.x
+func writeXliff2(w io.Writer, doc *xliffDoc, indent string) error {
+
+	x := &xliffWriter{newline: "\n", unit: indent, pretty: indent != ""}
+
+	var srcLang, trgLang string
+	for i, file := range doc.File {
+		if i == 0 {
+			srcLang, trgLang = file.SourceLang, file.TargetLang
+		} else if file.SourceLang != srcLang || file.TargetLang != trgLang {
+			log.Printf("warning: XLIFF %s supports only one language pair, using %s/%s for %q",
+				doc.Version, srcLang, trgLang, file.Original)
+		}
+	}
+
+	root := doc.raw2.element("xliff")
+	attrs := []xliffAttr{
+		{"xmlns", &doc.Xmlns, true},
+		{"version", &doc.Version, true},
+		{"srcLang", &srcLang, true},
+		{"trgLang", &trgLang, false},
+	}
+	slots := []xmlSlot{{root.elements("file"), len(doc.File), func(i int, _ *xmlNode, indent string) {
+		x.file2(&doc.File[i], i, indent)
+	}}}
+
+	if root == nil {
+		x.buf.WriteString(xml.Header)
+		x.container(nil, "xliff", attrs, "", slots)
+		x.buf.WriteString("\n")
+	} else {
+		x.detectIndent(root)
+		for _, child := range doc.raw2.children {
+			if child == root {
+				x.container(root, "xliff", attrs, "", slots)
+				continue
+			}
+			child.writeTo(&x.buf)
+		}
+	}
+
+	_, err := w.Write(x.buf.Bytes())
+	return err
+}
+
+func (x *xliffWriter) file2(file *xliffFile, i int, indent string) {
+
+	node := file.raw2
+	id := file.ID
+	if id == "" {
+		id = fmt.Sprintf("f%d", i+1)
+	}
+
+	// the id stands in for a missing original when reading
+	original := file.Original
+	if original == file.ID && node != nil {
+		original = ""
+		for _, attr := range node.attrs {
+			if attr.Name.Local == "original" {
+				original = file.Original
+			}
+		}
+	}
+
+	attrs := []xliffAttr{{"id", &id, true}, {"original", &original, false}}
+	x.container(node, "file", attrs, indent, x.unitSlots2(node, file.Body.TransUnit, file.Body.Group))
+}
+
+func (x *xliffWriter) unitSlots2(node *xmlNode, units []xliffTransUnit, groups []xliffGroup) []xmlSlot {
+	return []xmlSlot{
+		{node.elements("unit"), len(units), func(i int, _ *xmlNode, indent string) {
+			x.unit2(&units[i], indent)
+		}},
+		{node.elements("group"), len(groups), func(i int, _ *xmlNode, indent string) {
+			x.group2(&groups[i], indent)
+		}},
+	}
+}
+
+func (x *xliffWriter) group2(group *xliffGroup, indent string) {
+
+	node := group.raw2
+	id := group.ID
+	if id == "" {
+		x.ids++
+		id = fmt.Sprintf("g%d", x.ids)
+	}
+
+	nNotes := 0
+	if len(group.Note) > 0 {
+		nNotes = 1
+	}
+	notes := xmlSlot{node.elements("notes"), nNotes, func(_ int, node *xmlNode, indent string) {
+		x.container(node, "notes", nil, indent, []xmlSlot{{node.elements("note"), len(group.Note), func(i int, node *xmlNode, indent string) {
+			x.text(node, "note", nil, escapedText(group.Note[i]), indent)
+		}}})
+	}}
+
+	attrs := []xliffAttr{{"id", &id, true}, {"name", &group.ResName, false}}
+	x.container(node, "group", attrs, indent, append([]xmlSlot{notes}, x.unitSlots2(node, group.TransUnit, group.Group)...))
+}
+
+func (x *xliffWriter) unit2(unit *xliffTransUnit, indent string) {
+
+	node := unit.raw2
+
+	var name, translate string
+	for _, attr := range unit.Attrs {
+		switch attr.Name.Local {
+		case "resname":
+			name = attr.Value
+		case "translate":
+			translate = attr.Value
+		}
+	}
+	attrs := []xliffAttr{
+		{"id", &unit.ID, true},
+		{"name", &name, false},
+		{"translate", &translate, false},
+	}
+
+	// the model keeps the notes joined: they are written as they were
+	// read unless they changed
+	nNotes := 0
+	if unit.Note != "" {
+		nNotes = 1
+	}
+	notes := xmlSlot{node.elements("notes"), nNotes, func(_ int, node *xmlNode, indent string) {
+		keep := node != nil && node.text == escapedText(unit.Note)
+		list := node.elements("note")
+		n := 1
+		if keep {
+			n = len(list)
+		} else if len(list) > 1 {
+			log.Printf("warning: unit %q: its notes are joined into one", unit.ID)
+		}
+		x.container(node, "notes", nil, indent, []xmlSlot{{list, n, func(_ int, note *xmlNode, indent string) {
+			if keep {
+				note.writeTo(&x.buf)
+				return
+			}
+			x.text(note, "note", nil, escapedText(unit.Note), indent)
+		}}})
+	}}
+
+	// so are the segments, as long as neither source nor target changed.
+	// otherwise they are replaced by one segment.
+	source, target, state, data := unit.content2()
+	if node != nil && node.text == unit.segments2() {
+		x.container(node, "unit", attrs, indent, []xmlSlot{notes})
+		return
+	}
+
+	var parts []*xmlNode
+	if node != nil {
+		for _, child := range node.children {
+			if child.isElement() && (child.local() == "segment" || child.local() == "ignorable") {
+				parts = append(parts, child)
+			}
+		}
+	}
+	if len(parts) > 1 {
+		log.Printf("warning: unit %q: its segments are joined into one", unit.ID)
+	}
+
+	nData, nTarget := 0, 0
+	if len(data) > 0 {
+		nData = 1
+	}
+	if unit.Target != nil {
+		nTarget = 1
+	}
+
+	segment := []xmlSlot{
+		{nil, 1, func(_ int, _ *xmlNode, indent string) {
+			x.text(nil, "source", nil, source, indent)
+		}},
+		{nil, nTarget, func(_ int, _ *xmlNode, indent string) {
+			x.text(nil, "target", nil, target, indent)
+		}},
+	}
+
+	slots := []xmlSlot{
+		notes,
+		{node.elements("originalData"), nData, func(_ int, _ *xmlNode, indent string) {
+			x.container(nil, "originalData", nil, indent, []xmlSlot{{nil, len(data), func(i int, _ *xmlNode, indent string) {
+				x.text(nil, "data", []xliffAttr{{"id", &data[i].ID, true}}, escapedText(data[i].Inner), indent)
+			}}})
+		}},
+		{parts, 1, func(_ int, _ *xmlNode, indent string) {
+			x.container(nil, "segment", []xliffAttr{{"state", &state, false}}, indent, segment)
+		}},
+	}
+	x.container(node, "unit", attrs, indent, slots)
+}
+
+// content2 returns the source and target of unit as XLIFF 2.x, the state
+// of the target and the native code of its inline elements
+func (unit *xliffTransUnit) content2() (source, target, state string, data []xliff2Data) {
+	source = content2(&unit.Source, &data)
+	if unit.Target != nil {
+		target = content2((*xliffSource)(unit.Target), &data)
+		state = stateTo2(unit.Target.State)
+	}
+	return source, target, state, data
+}
+
+// segments2 sums up what unit2 writes for the segments of unit
+func (unit *xliffTransUnit) segments2() string {
+	source, target, state, _ := unit.content2()
+	if unit.Target == nil {
+		return source
+	}
+	return source + "\x00" + target + "\x00" + state
+}
+
+// content2 returns the content of src as XLIFF 2.x
+func content2(src *xliffSource, data *[]xliff2Data) string {
+	if !src.hasContent() {
+		return escapedText(src.Inner)
+	}
+	return inlineTo2(src.Content, data).XML()
+}
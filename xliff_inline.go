@@ -0,0 +1,288 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// the ways inline elements are rendered by Text(). INLINE_TEXT_ONLY is
+// no option of the converters, it is meant for counting words and
+// matching texts.
+const (
+	INLINE_PLAIN       = "plain"       // text, codes as their native code
+	INLINE_PLACEHOLDER = "placeholder" // codes become {id}, paired codes <id>..</id>
+	INLINE_XML         = "xml"         // inline elements as they are
+	INLINE_TEXT_ONLY   = "text-only"   // text only, codes are dropped
+)
+
+// xliffInline is a piece of the content of a <source> or <target>: either
+// text or an inline element (<g>, <x/>, <bx/>, <ex/>, <ph>, <bpt>, <ept>,
+// <it>, <mrk>, <sub>) with its own content.
+type xliffInline struct {
+	Text    string
+	Name    string // name of the inline element, "" for text
+	Attrs   []xml.Attr
+	Content xliffContent
+}
+
+type xliffContent []xliffInline
+
+// readContent reads the content of the current element up to its end
+func readContent(d *xml.Decoder) (xliffContent, error) {
+
+	var content xliffContent
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			content = content.appendText(string(t))
+		case xml.StartElement:
+			inner, err := readContent(d)
+			if err != nil {
+				return nil, err
+			}
+			t = t.Copy()
+			content = append(content, xliffInline{Name: t.Name.Local, Attrs: t.Attr, Content: inner})
+		case xml.EndElement:
+			return content, nil
+		}
+	}
+}
+
+// parseContent parses text with inline elements, as rendered by
+// INLINE_XML
+func parseContent(inner string) (xliffContent, error) {
+	d := xml.NewDecoder(strings.NewReader("<content>" + inner + "</content>"))
+	if _, err := d.Token(); err != nil {
+		return nil, err
+	}
+	content, err := readContent(d)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		content = xliffContent{}
+	}
+	return content, nil
+}
+
+// isCode reports if the inline element holds native code (or is a
+// placeholder for it) rather than text
+func (inline *xliffInline) isCode() bool {
+	switch inline.Name {
+	case "x", "bx", "ex", "ph", "bpt", "ept", "it", "sub":
+		return true
+	}
+	return false
+}
+
+func (inline *xliffInline) attr(local string) string {
+	for _, attr := range inline.Attrs {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Plain returns the text of the content, codes as their native code
+func (content xliffContent) Plain() string {
+	buf := bytes.NewBuffer(nil)
+	content.render(buf, INLINE_PLAIN)
+	return buf.String()
+}
+
+// TextOnly returns the text of the content without any codes
+func (content xliffContent) TextOnly() string {
+	buf := bytes.NewBuffer(nil)
+	content.render(buf, INLINE_TEXT_ONLY)
+	return buf.String()
+}
+
+// XML returns the content as it is written into a XLIFF
+func (content xliffContent) XML() string {
+	buf := bytes.NewBuffer(nil)
+	content.render(buf, INLINE_XML)
+	return buf.String()
+}
+
+// mapText returns a copy of content with fn applied to its text, the
+// text of codes is left untouched
+func (content xliffContent) mapText(fn func(string) string) xliffContent {
+	mapped := make(xliffContent, len(content))
+	for i, inline := range content {
+		switch {
+		case inline.Name == "":
+			inline.Text = fn(inline.Text)
+		case !inline.isCode():
+			inline.Content = inline.Content.mapText(fn)
+		}
+		mapped[i] = inline
+	}
+	return mapped
+}
+
+func (content xliffContent) render(buf *bytes.Buffer, mode string) {
+
+	for i := range content {
+		inline := &content[i]
+
+		if inline.Name == "" {
+			if mode == INLINE_XML {
+				escapeText(buf, inline.Text)
+			} else {
+				buf.WriteString(inline.Text)
+			}
+			continue
+		}
+
+		switch mode {
+		case INLINE_XML:
+			buf.WriteString("<" + inline.Name)
+			for _, attr := range inline.Attrs {
+				switch attr.Name.Space {
+				case "":
+					buf.WriteString(" " + attr.Name.Local)
+				case xmlNamespace:
+					buf.WriteString(" xml:" + attr.Name.Local)
+				default:
+					continue // foreign namespaces are lost
+				}
+				buf.WriteString(`="`)
+				xml.EscapeText(buf, []byte(attr.Value))
+				buf.WriteString(`"`)
+			}
+			if len(inline.Content) == 0 {
+				buf.WriteString("/>")
+				continue
+			}
+			buf.WriteString(">")
+			inline.Content.render(buf, mode)
+			buf.WriteString("</" + inline.Name + ">")
+
+		case INLINE_PLACEHOLDER:
+			id := inline.attr("id")
+			if id == "" {
+				id = inline.Name
+			}
+			switch inline.Name {
+			case "g":
+				fmt.Fprintf(buf, "<%s>", id)
+				inline.Content.render(buf, mode)
+				fmt.Fprintf(buf, "</%s>", id)
+			case "bpt", "bx":
+				fmt.Fprintf(buf, "<%s>", id)
+			case "ept", "ex":
+				if rid := inline.attr("rid"); rid != "" {
+					id = rid
+				}
+				fmt.Fprintf(buf, "</%s>", id)
+			case "x", "ph", "it":
+				fmt.Fprintf(buf, "{%s}", id)
+			case "sub":
+			default:
+				inline.Content.render(buf, mode)
+			}
+
+		case INLINE_TEXT_ONLY:
+			if !inline.isCode() {
+				inline.Content.render(buf, mode)
+			}
+
+		default:
+			inline.Content.render(buf, mode)
+		}
+	}
+}
+
+// Text renders the content of src according to mode. if Inner was changed
+// (and thus does not match the inline content anymore), Inner is used.
+func (src *xliffSource) Text(mode string) string {
+	if !src.hasContent() {
+		if mode == INLINE_XML {
+			return escapedText(src.Inner)
+		}
+		return src.Inner
+	}
+	buf := bytes.NewBuffer(nil)
+	src.Content.render(buf, mode)
+	return buf.String()
+}
+
+func (target *xliffTarget) Text(mode string) string {
+	return (*xliffSource)(target).Text(mode)
+}
+
+// hasContent reports if the inline content is still in sync with Inner
+func (src *xliffSource) hasContent() bool {
+	return src.Content != nil && src.Content.Plain() == src.Inner
+}
+
+// content returns the inline content of src, made up from Inner if they
+// are not in sync
+func (src *xliffSource) content() xliffContent {
+	if src.hasContent() {
+		return src.Content
+	}
+	return xliffContent{{Text: src.Inner}}
+}
+
+// innerXML returns the content of src as it is written
+func (src *xliffSource) innerXML() string {
+	return src.Text(INLINE_XML)
+}
+
+func (src *xliffSource) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	src.XMLName = start.Name
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "lang":
+			src.Lang = attr.Value
+		case "space":
+			src.Space = attr.Value
+		case "state":
+			src.State = attr.Value
+		}
+	}
+
+	content, err := readContent(d)
+	if err != nil {
+		return err
+	}
+	if content == nil {
+		content = xliffContent{}
+	}
+	src.Content = content
+	src.Inner = content.Plain()
+
+	return nil
+}
+
+func (target *xliffTarget) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return (*xliffSource)(target).UnmarshalXML(d, start)
+}
+
+// isValidInline reports if mode is one of the INLINE_ modes
+func isValidInline(mode string) bool {
+	switch mode {
+	case INLINE_PLAIN, INLINE_PLACEHOLDER, INLINE_XML:
+		return true
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"io/ioutil"
 	"strings"
 	"testing"
 )
@@ -57,3 +59,246 @@ func TestReadXliff(t *testing.T) {
 	}
 
 }
+
+func TestReadXliffGroups(t *testing.T) {
+
+	raw := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+	<file original="" source-language="en" target-language="de">
+		<body>
+			<trans-unit id="a">
+				<source>A</source>
+			</trans-unit>
+			<group id="menu" resname="MainMenu" restype="x-menu">
+				<note>the main menu</note>
+				<trans-unit id="b">
+					<source>B</source>
+				</trans-unit>
+				<group id="file">
+					<trans-unit id="c">
+						<source>C</source>
+					</trans-unit>
+				</group>
+			</group>
+		</body>
+	</file>
+</xliff>
+	`
+
+	doc, err := xliffFromReader(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	group := doc.File[0].Body.Group[0]
+	if group.ID != "menu" || group.ResName != "MainMenu" {
+		t.Errorf("expected group 'menu'/'MainMenu', got %q/%q", group.ID, group.ResName)
+	}
+	if len(group.Attrs) != 1 || group.Attrs[0].Value != "x-menu" {
+		t.Errorf("expected restype 'x-menu', got %v", group.Attrs)
+	}
+	if len(group.Note) != 1 || group.Note[0] != "the main menu" {
+		t.Errorf("expected group note, got %q", group.Note)
+	}
+
+	expected := []string{"a", "", "b", "menu", "c", "menu/file"}
+	got := []string{}
+	doc.File[0].Body.Walk(func(groups []*xliffGroup, unit *xliffTransUnit) {
+		got = append(got, unit.ID, strings.Join(groupIDs(groups), "/"))
+	})
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected units %q, got %q", expected, got)
+	}
+
+	units := doc.File[0].Body.Units()
+	units[2].Source.Inner = "changed"
+	if doc.File[0].Body.Group[0].Group[0].TransUnit[0].Source.Inner != "changed" {
+		t.Errorf("expected Units() to point into the document")
+	}
+}
+
+func TestReadXliffInline(t *testing.T) {
+
+	raw := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+	<file original="" source-language="en" target-language="de">
+		<body>
+			<trans-unit id="a">
+				<source>Click <g id="1">here</g> to <x id="2"/>continue<ph id="3">&lt;br/&gt;</ph>, <bpt id="4">&lt;b&gt;</bpt>now<ept id="4">&lt;/b&gt;</ept></source>
+				<target><mrk mtype="seg">Klicke <g id="1">hier</g></mrk> um fortzufahren</target>
+			</trans-unit>
+		</body>
+	</file>
+</xliff>
+`
+
+	doc, err := xliffFromReader(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	source := &doc.File[0].Body.TransUnit[0].Source
+	expected := map[string]string{
+		INLINE_PLAIN:       "Click here to continue<br/>, <b>now</b>",
+		INLINE_TEXT_ONLY:   "Click here to continue, now",
+		INLINE_PLACEHOLDER: "Click <1>here</1> to {2}continue{3}, <4>now</4>",
+		INLINE_XML:         `Click <g id="1">here</g> to <x id="2"/>continue<ph id="3">&lt;br/&gt;</ph>, <bpt id="4">&lt;b&gt;</bpt>now<ept id="4">&lt;/b&gt;</ept>`,
+	}
+	for mode, text := range expected {
+		if got := source.Text(mode); got != text {
+			t.Errorf("%s: expected %q, got %q", mode, text, got)
+		}
+	}
+	if source.Inner != expected[INLINE_PLAIN] {
+		t.Errorf("expected Inner %q, got %q", expected[INLINE_PLAIN], source.Inner)
+	}
+
+	// swapping keeps the markup of both sides
+	unit := &doc.File[0].Body.TransUnit[0]
+	unit.Source.Inner, unit.Target.Inner = unit.Target.Inner, unit.Source.Inner
+	unit.Source.Content, unit.Target.Content = unit.Target.Content, unit.Source.Content
+
+	out := bytes.NewBuffer(nil)
+	if err = writeXliff(out, doc, "  "); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{
+		`<source><mrk mtype="seg">Klicke <g id="1">hier</g></mrk> um fortzufahren</source>`,
+		`<target>Click <g id="1">here</g> to <x id="2"/>continue<ph id="3">&lt;br/&gt;</ph>, <bpt id="4">&lt;b&gt;</bpt>now<ept id="4">&lt;/b&gt;</ept></target>`,
+	} {
+		if !strings.Contains(out.String(), part) {
+			t.Errorf("expected %q in\n%s", part, out)
+		}
+	}
+
+	// a plain Inner replaces the inline content
+	unit.Target.Inner = "plain"
+	if got := unit.Target.Text(INLINE_XML); got != "plain" {
+		t.Errorf("expected changed Inner to win, got %q", got)
+	}
+}
+
+func TestReadXliff2(t *testing.T) {
+
+	doc, err := xliffFromFile("testdata/xliff2/okapi.xlf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.isXliff2() || doc.File[0].SourceLang != "en-US" || doc.File[0].TargetLang != "de-DE" {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+
+	body := &doc.File[0].Body
+	greeting := body.TransUnit[0]
+	if greeting.Note != "Shown on the start page" || greeting.Target.State != "translated" {
+		t.Errorf("unexpected unit: %+v", greeting)
+	}
+	if text := greeting.Target.Text(INLINE_PLACEHOLDER); text != "Hallo <1>Welt</1>!" {
+		t.Errorf("unexpected target %q", text)
+	}
+
+	// segments are joined, the state is the least advanced one
+	del := body.Group[0].TransUnit[0]
+	if del.Source.Inner != "Delete<br/><b>all</b> files? Really!" || del.Target.State != "new" {
+		t.Errorf("unexpected unit: %q %q", del.Source.Inner, del.Target.State)
+	}
+
+	// writing is stable across 1.2 and back
+	var written []string
+	for _, version := range []string{"1.2", "2.1", "1.2", "2.1"} {
+		if err = doc.SetVersion(version); err != nil {
+			t.Fatal(err)
+		}
+		out := bytes.NewBuffer(nil)
+		if err = writeXliff(out, doc, "  "); err != nil {
+			t.Fatal(err)
+		}
+		written = append(written, out.String())
+		if doc, err = xliffFromReader(out); err != nil {
+			t.Fatalf("%s: %s\n%s", version, err, written[len(written)-1])
+		}
+	}
+	if written[1] != written[3] {
+		t.Errorf("round-trip through 1.2 changed the document:\n%s\n%s", written[1], written[3])
+	}
+	for _, part := range []string{
+		`<ph id="1" dataRef="d1"/><sc id="2" dataRef="d2"/>`,
+		`<target>Alle<ph id="1" dataRef="d1"/>`,
+	} {
+		if !strings.Contains(written[1], part) {
+			t.Errorf("expected %q in\n%s", part, written[1])
+		}
+	}
+}
+
+// what the model has no place for is written back as it was read
+func TestWriteXliff2Unchanged(t *testing.T) {
+
+	original, err := ioutil.ReadFile("testdata/xliff2/okapi.xlf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.NewBuffer(nil)
+	if err = (&setLang{inFile: "testdata/xliff2/okapi.xlf", sourceLang: _KEEP, targetLang: _KEEP}).Convert(out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(original) {
+		t.Errorf("expected\n%s\ngot\n%s", original, out)
+	}
+
+	doc, err := xliffFromReader(bytes.NewReader(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := &doc.File[0].Body
+	body.TransUnit[0].Note = "Shown on top of the start page"
+	body.Group[0].TransUnit[0].Target.Inner = "Wirklich alle Dateien löschen?"
+	out.Reset()
+	if err = writeXliff(out, doc, "  "); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{
+		`<file id="f1" original="app/messages.properties">`,
+		`<note>file notes are not part of the model</note>`,
+		`<note category="context">Shown on top of the start page</note>`,
+		`<target>Wirklich alle Dateien löschen?</target>`,
+		`<target>Hallo <pc id="1">Welt</pc>!</target>`,
+	} {
+		if !strings.Contains(out.String(), part) {
+			t.Errorf("expected %q in\n%s", part, out)
+		}
+	}
+	if n := strings.Count(out.String(), "<segment"); n != 3 {
+		t.Errorf("expected the segments of the changed unit to be joined, got %d segments", n)
+	}
+}
+
+// exporters writing plain text keep the native code of placeholders
+func TestPlainCodes(t *testing.T) {
+
+	for _, c := range []struct {
+		name     string
+		conv     converter
+		args     []string
+		expected string
+	}{
+		{"to-json", new(toJSON), nil, `"greeting":"Hallo %@!"`},
+		{"to-ios-strings", new(toIOSStrings), nil, `"greeting" = "Hallo %@!";`},
+		{"to-properties", new(toProperties), nil, `greeting=Hallo %@!`},
+		{"to-yaml", new(toYAML), nil, `greeting: Hallo %@!`},
+		{"to-go-i18n", new(toGoI18n), []string{"-format", "toml"}, `greeting = "Hallo %@!"`},
+		{"to-arb", new(toARB), nil, `"greeting": "Hallo %@!"`},
+		{"to-gotext", new(toGotext), nil, `"translation": "Hallo %@!"`},
+	} {
+		if err := c.conv.ParseArgs("xliffer", append(c.args, "-in", "testdata/inline/ph.xlf")); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		out := bytes.NewBuffer(nil)
+		if err := c.conv.Convert(out); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if !strings.Contains(out.String(), c.expected) {
+			t.Errorf("%s: expected %q in\n%s", c.name, c.expected, out)
+		}
+	}
+}
@@ -0,0 +1,549 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// xliffWriter writes a xliffDoc. elements which were read from a file
+// (and thus are linked to a xmlNode) are written as they were read; only
+// the attributes and the content which were changed in the typed model
+// are written anew. everything the model does not know about (<header>,
+// <alt-trans>, comments, foreign namespaces, whitespace, ...) is kept.
+type xliffWriter struct {
+	buf     bytes.Buffer
+	newline string
+	unit    string // one level of indentation
+	pretty  bool
+	ids     int // counter for generated ids
+}
+
+// xliffAttr is an attribute the typed model knows about
+type xliffAttr struct {
+	name     string  // qualified name, used when the attribute is added
+	value    *string // field of the typed model
+	required bool    // written even if empty
+}
+
+// xmlSlot describes a kind of child elements of which the typed model
+// keeps values. the values take the places of the original elements in
+// the order they were read. additional values are written after the last
+// original element of the slot (or of the slots before it), surplus
+// original elements are dropped.
+type xmlSlot struct {
+	nodes []*xmlNode // original child elements
+	n     int        // number of typed values
+	write func(i int, node *xmlNode, indent string)
+}
+
+// writeXliff writes doc to w. indent is used for documents which were
+// not read from a file, otherwise the indentation of the document is kept.
+func writeXliff(w io.Writer, doc *xliffDoc, indent string) error {
+
+	if doc.isXliff2() {
+		return writeXliff2(w, doc, indent)
+	}
+
+	x := &xliffWriter{newline: "\n", unit: indent, pretty: indent != ""}
+
+	if doc.raw == nil {
+		x.buf.WriteString(xml.Header)
+		x.xliff(doc, nil, "")
+		x.buf.WriteString("\n")
+	} else {
+		root := doc.raw.element("xliff")
+		x.detectIndent(root)
+		for _, child := range doc.raw.children {
+			if child == root {
+				x.xliff(doc, root, "")
+				continue
+			}
+			child.writeTo(&x.buf)
+		}
+	}
+
+	_, err := w.Write(x.buf.Bytes())
+	return err
+}
+
+// detectIndent takes newline and indentation from the whitespace in
+// front of the first child element of node
+func (x *xliffWriter) detectIndent(node *xmlNode) {
+
+	x.pretty, x.unit = false, ""
+	if node == nil {
+		return
+	}
+
+	for i, child := range node.children {
+		if !child.isElement() {
+			continue
+		}
+		if i > 0 && node.children[i-1].isSpace() {
+			ws := string(node.children[i-1].raw)
+			if j := strings.LastIndexByte(ws, '\n'); j >= 0 {
+				x.pretty = true
+				x.unit = ws[j+1:]
+				if strings.HasSuffix(ws[:j+1], "\r\n") {
+					x.newline = "\r\n"
+				}
+			}
+		}
+		return
+	}
+}
+
+func (x *xliffWriter) xliff(doc *xliffDoc, node *xmlNode, indent string) {
+
+	slots := []xmlSlot{
+		{node.elements("file"), len(doc.File), func(i int, _ *xmlNode, indent string) {
+			x.file(&doc.File[i], indent)
+		}},
+	}
+	x.container(node, "xliff", doc.attrs(), indent, slots)
+}
+
+func (x *xliffWriter) file(file *xliffFile, indent string) {
+
+	node := file.raw
+
+	body := &file.Body
+	nBody := 0
+	if node == nil || body.raw != nil || len(body.TransUnit) > 0 || len(body.Group) > 0 {
+		nBody = 1
+	}
+
+	slots := []xmlSlot{
+		{node.elements("body"), nBody, func(_ int, _ *xmlNode, indent string) {
+			x.container(body.raw, "body", nil, indent, x.unitSlots(body.raw, body.TransUnit, body.Group))
+		}},
+	}
+	x.container(node, "file", file.attrs(), indent, slots)
+}
+
+func (x *xliffWriter) group(group *xliffGroup, indent string) {
+
+	node := group.raw
+
+	slots := append([]xmlSlot{
+		{node.elements("note"), len(group.Note), func(i int, node *xmlNode, indent string) {
+			x.text(node, "note", nil, escapedText(group.Note[i]), indent)
+		}},
+	}, x.unitSlots(node, group.TransUnit, group.Group)...)
+
+	x.container(node, "group", group.attrs(), indent, slots)
+}
+
+func (x *xliffWriter) unitSlots(node *xmlNode, units []xliffTransUnit, groups []xliffGroup) []xmlSlot {
+	return []xmlSlot{
+		{node.elements("trans-unit"), len(units), func(i int, _ *xmlNode, indent string) {
+			x.transUnit(&units[i], indent)
+		}},
+		{node.elements("group"), len(groups), func(i int, _ *xmlNode, indent string) {
+			x.group(&groups[i], indent)
+		}},
+	}
+}
+
+func (x *xliffWriter) transUnit(unit *xliffTransUnit, indent string) {
+
+	node := unit.raw
+
+	nSource, nTarget, nNote := 0, 0, 0
+	if node == nil || unit.Source.raw != nil || unit.Source.Inner != "" {
+		nSource = 1
+	}
+	if unit.Target != nil {
+		nTarget = 1
+	}
+	if unit.Note != "" {
+		nNote = 1
+	}
+
+	// the typed model keeps only the last <note>, the others are
+	// written as they are
+	notes := node.elements("note")
+	if len(notes) > 1 {
+		notes = notes[len(notes)-1:]
+	}
+
+	slots := []xmlSlot{
+		{node.elements("source"), nSource, func(_ int, _ *xmlNode, indent string) {
+			x.source(&unit.Source, "source", indent)
+		}},
+		{node.elements("target"), nTarget, func(_ int, _ *xmlNode, indent string) {
+			x.source((*xliffSource)(unit.Target), "target", indent)
+		}},
+		{notes, nNote, func(_ int, node *xmlNode, indent string) {
+			x.text(node, "note", nil, escapedText(unit.Note), indent)
+		}},
+		{node.elements("context-group"), len(unit.ContextGroup), func(i int, _ *xmlNode, indent string) {
+			x.contextGroup(&unit.ContextGroup[i], indent)
+		}},
+		{node.elements("alt-trans"), len(unit.AltTrans), func(i int, _ *xmlNode, indent string) {
+			x.altTrans(&unit.AltTrans[i], indent)
+		}},
+	}
+	x.container(node, "trans-unit", unit.attrs(), indent, slots)
+}
+
+func (x *xliffWriter) altTrans(alt *xliffAltTrans, indent string) {
+	nSource := 0
+	if alt.Source != nil {
+		nSource = 1
+	}
+	slots := []xmlSlot{
+		{alt.raw.elements("source"), nSource, func(_ int, _ *xmlNode, indent string) {
+			x.source(alt.Source, "source", indent)
+		}},
+		{alt.raw.elements("target"), 1, func(_ int, _ *xmlNode, indent string) {
+			x.source((*xliffSource)(&alt.Target), "target", indent)
+		}},
+	}
+	x.container(alt.raw, "alt-trans", alt.attrs(), indent, slots)
+}
+
+func (x *xliffWriter) contextGroup(cg *xliffContextGroup, indent string) {
+	slots := []xmlSlot{
+		{cg.raw.elements("context"), len(cg.Context), func(i int, _ *xmlNode, indent string) {
+			ctx := &cg.Context[i]
+			x.text(ctx.raw, "context", ctx.attrs(), escapedText(ctx.Inner), indent)
+		}},
+	}
+	x.container(cg.raw, "context-group", cg.attrs(), indent, slots)
+}
+
+func (x *xliffWriter) source(src *xliffSource, name string, indent string) {
+	x.text(src.raw, name, src.attrs(), src.innerXML(), indent)
+}
+
+// text writes an element which holds text (and inline elements). content
+// is already escaped. if the content did not change, it is written as it
+// was read.
+func (x *xliffWriter) text(node *xmlNode, name string, attrs []xliffAttr, content string, indent string) {
+
+	keep := node != nil && node.text == content
+	empty := content == ""
+	if keep {
+		empty = len(node.children) == 0
+	}
+
+	x.element(node, name, attrs, empty, func() {
+		if keep {
+			for _, child := range node.children {
+				child.writeTo(&x.buf)
+			}
+			return
+		}
+		x.buf.WriteString(content)
+	})
+}
+
+// container writes an element which holds child elements only
+func (x *xliffWriter) container(node *xmlNode, name string, attrs []xliffAttr, indent string, slots []xmlSlot) {
+
+	empty := node == nil || len(node.children) == 0
+	for _, slot := range slots {
+		if slot.n > 0 {
+			empty = false
+		}
+	}
+
+	x.element(node, name, attrs, empty, func() {
+		x.children(node, indent, slots)
+	})
+}
+
+// element writes an element, using the original start and end tags where
+// possible. content writes the children.
+func (x *xliffWriter) element(node *xmlNode, name string, attrs []xliffAttr, empty bool, content func()) {
+
+	var orig []xml.Attr
+	if node != nil {
+		name = node.name
+		orig = node.attrs
+	}
+	merged, changed := mergeAttrs(orig, attrs, node == nil)
+
+	if node != nil && node.selfClosing() && empty {
+		if changed {
+			x.startTag(name, merged, true)
+		} else {
+			x.buf.Write(node.raw)
+		}
+		return
+	}
+
+	if node != nil && !changed && !node.selfClosing() {
+		x.buf.Write(node.raw)
+	} else {
+		x.startTag(name, merged, false)
+	}
+
+	content()
+
+	if node != nil && !node.selfClosing() {
+		x.buf.Write(node.rawEnd)
+	} else {
+		x.buf.WriteString("</" + name + ">")
+	}
+}
+
+// children writes the children of node: original children which are not
+// part of a slot as they were read, slots from their typed values
+func (x *xliffWriter) children(node *xmlNode, indent string, slots []xmlSlot) {
+
+	var children []*xmlNode
+	if node != nil {
+		children = node.children
+	}
+	childIndent, pretty := x.childIndent(node, indent)
+
+	type place struct{ slot, i int }
+	places := map[*xmlNode]place{}
+	for s := range slots {
+		for i, child := range slots[s].nodes {
+			places[child] = place{s, i}
+		}
+	}
+
+	// additional values of a slot are written after the last original
+	// element of that slot or, if there is none, after the last element
+	// of the slots before. -1 means "at the beginning".
+	after := make([]int, len(slots))
+	last := -1
+	for s := range slots {
+		after[s] = last
+		for i, child := range children {
+			if p, ok := places[child]; ok && p.slot == s {
+				after[s] = i
+			}
+		}
+		if after[s] > last {
+			last = after[s]
+		}
+	}
+
+	written := false
+	appendValues := func(pos int) {
+		for s := range slots {
+			if after[s] != pos {
+				continue
+			}
+			for i := len(slots[s].nodes); i < slots[s].n; i++ {
+				if pretty {
+					x.indent(childIndent)
+				}
+				slots[s].write(i, nil, childIndent)
+				written = true
+			}
+		}
+	}
+
+	appendValues(-1)
+
+	var space *xmlNode // whitespace in front of the next node
+	for i, child := range children {
+		if child.isSpace() {
+			if space != nil {
+				x.buf.Write(space.raw)
+			}
+			space = child
+			continue
+		}
+
+		p, inSlot := places[child]
+		if inSlot && p.i >= slots[p.slot].n {
+			space = nil // drop the element and the whitespace in front of it
+			appendValues(i)
+			continue
+		}
+
+		if space != nil {
+			x.buf.Write(space.raw)
+			space = nil
+		}
+		if inSlot {
+			slots[p.slot].write(p.i, child, childIndent)
+		} else {
+			child.writeTo(&x.buf)
+		}
+		appendValues(i)
+	}
+
+	if space != nil {
+		x.buf.Write(space.raw)
+	} else if written && len(children) == 0 && pretty {
+		x.indent(indent)
+	}
+}
+
+// childIndent returns the indentation of the child elements of node. if
+// the existing child elements are not indented, neither are new ones.
+func (x *xliffWriter) childIndent(node *xmlNode, indent string) (string, bool) {
+	if node != nil {
+		for i, child := range node.children {
+			if !child.isElement() {
+				continue
+			}
+			if i > 0 && node.children[i-1].isSpace() {
+				ws := string(node.children[i-1].raw)
+				if j := strings.LastIndexByte(ws, '\n'); j >= 0 {
+					return ws[j+1:], true
+				}
+			}
+			return "", false
+		}
+	}
+	return indent + x.unit, x.pretty
+}
+
+func (x *xliffWriter) indent(indent string) {
+	x.buf.WriteString(x.newline + indent)
+}
+
+func (x *xliffWriter) startTag(name string, attrs []xml.Attr, selfClosing bool) {
+	x.buf.WriteString("<" + name)
+	for _, attr := range attrs {
+		x.buf.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		xml.EscapeText(&x.buf, []byte(attr.Value))
+		x.buf.WriteString(`"`)
+	}
+	if selfClosing {
+		x.buf.WriteString("/")
+	}
+	x.buf.WriteString(">")
+}
+
+// mergeAttrs updates the original attributes of an element with the
+// values of the typed model. unknown attributes are kept in their place,
+// known attributes with a new value are appended. changed reports if the
+// result differs from orig.
+func mergeAttrs(orig []xml.Attr, attrs []xliffAttr, fresh bool) (merged []xml.Attr, changed bool) {
+
+	used := make([]bool, len(attrs))
+
+	for _, attr := range orig {
+		i := findAttr(attrs, attr.Name)
+		if i == -1 {
+			merged = append(merged, attr)
+			continue
+		}
+		used[i] = true
+		value := *attrs[i].value
+		switch {
+		case value == attr.Value:
+		case value == "" && !attrs[i].required:
+			changed = true
+			continue
+		default:
+			attr.Value = value
+			changed = true
+		}
+		merged = append(merged, attr)
+	}
+
+	for i, attr := range attrs {
+		if used[i] || (*attr.value == "" && !(attr.required && fresh)) {
+			continue
+		}
+		name := xml.Name{Local: attr.name}
+		if j := strings.IndexByte(attr.name, ':'); j >= 0 {
+			name = xml.Name{Space: attr.name[:j], Local: attr.name[j+1:]}
+		}
+		merged = append(merged, xml.Attr{Name: name, Value: *attr.value})
+		changed = true
+	}
+
+	return merged, changed
+}
+
+// findAttr finds the attribute in attrs which matches the raw name. just
+// like encoding/xml the prefix is not taken into account, except for
+// attributes of foreign namespaces.
+func findAttr(attrs []xliffAttr, name xml.Name) int {
+	if name.Space != "" && name.Space != "xml" {
+		return -1
+	}
+	for i := range attrs {
+		local := attrs[i].name
+		if j := strings.IndexByte(local, ':'); j >= 0 {
+			local = local[j+1:]
+		}
+		if local == name.Local {
+			return i
+		}
+	}
+	return -1
+}
+
+// anyAttrs turns the attributes collected via `xml:",any,attr"` into
+// xliffAttrs. attributes of foreign namespaces are left to the original
+// element.
+func anyAttrs(attrs []xml.Attr) []xliffAttr {
+	var known []xliffAttr
+	for i, attr := range attrs {
+		switch attr.Name.Space {
+		case "":
+			known = append(known, xliffAttr{attr.Name.Local, &attrs[i].Value, true})
+		case xmlNamespace:
+			known = append(known, xliffAttr{"xml:" + attr.Name.Local, &attrs[i].Value, true})
+		}
+	}
+	return known
+}
+
+// linkAttrs takes the values of the known attributes from the original
+// element. encoding/xml matches attributes regardless of their namespace,
+// thus eg. "sdl:version" might have overwritten "version".
+func linkAttrs(node *xmlNode, attrs []xliffAttr) {
+	for _, attr := range node.attrs {
+		if i := findAttr(attrs, attr.Name); i >= 0 {
+			*attrs[i].value = attr.Value
+		}
+	}
+}
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+func escapedText(text string) string {
+	buf := bytes.NewBuffer(nil)
+	escapeText(buf, text)
+	return buf.String()
+}
+
+// escapedAttr escapes s for use as value of an attribute
+func escapedAttr(s string) string {
+	buf := bytes.NewBuffer(nil)
+	xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
+
+// escapeText escapes text for use as character data. unlike
+// xml.EscapeText newlines and tabs are kept as they are.
+func escapeText(buf *bytes.Buffer, text string) {
+	for _, r := range text {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+// the converters which rewrite a XLIFF, applied to every file in testdata/
+var goldenConverters = []struct {
+	name string
+	conv func(in string) converter
+}{
+	{"set-lang", func(in string) converter {
+		return &setLang{inFile: in, sourceLang: _KEEP, targetLang: "pt-BR"}
+	}},
+	{"blank-target", func(in string) converter { return &blankTarget{inFile: in} }},
+	{"copy", func(in string) converter { return &copyUnits{inFile: in} }},
+	{"swap-source-target", func(in string) converter { return &swapSourceTarget{inFile: in} }},
+	{"merge", func(in string) converter {
+		return &mergeConv{aFile: in, bFile: filepath.Join("testdata", "angular.xlf")}
+	}},
+}
+
+func goldenInputs(t *testing.T) []string {
+	var inputs []string
+	for _, pattern := range []string{"*.xlf", "*.xliff", "*.sdlxliff"} {
+		names, err := filepath.Glob(filepath.Join("testdata", pattern))
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, names...)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no input files in testdata/")
+	}
+	return inputs
+}
+
+// reading and writing a document without touching it must not change
+// a single byte
+func TestWriteXliffUnchanged(t *testing.T) {
+
+	for _, in := range goldenInputs(t) {
+		raw, err := ioutil.ReadFile(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := xliffFromReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Errorf("%s: %s", in, err)
+			continue
+		}
+		out := bytes.NewBuffer(nil)
+		if err = writeXliff(out, doc, "  "); err != nil {
+			t.Errorf("%s: %s", in, err)
+			continue
+		}
+		if !bytes.Equal(raw, out.Bytes()) {
+			t.Errorf("%s: round-trip changed the document:\n%s", in, out)
+		}
+	}
+}
+
+func TestWriteXliffGolden(t *testing.T) {
+
+	for _, in := range goldenInputs(t) {
+		for _, gc := range goldenConverters {
+
+			golden := filepath.Join("testdata", "golden", filepath.Base(in)+"."+gc.name)
+			out := bytes.NewBuffer(nil)
+			if err := gc.conv(in).Convert(out); err != nil {
+				t.Errorf("%s %s: %s", gc.name, in, err)
+				continue
+			}
+
+			if *updateGolden {
+				if err := ioutil.WriteFile(golden, out.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Errorf("%s (run 'go test -update' to create it)", err)
+				continue
+			}
+			if !bytes.Equal(expected, out.Bytes()) {
+				t.Errorf("%s %s: output differs from %s:\n%s", gc.name, in, golden, out)
+			}
+		}
+	}
+}
+
+func TestWriteXliffNew(t *testing.T) {
+
+	doc := newXliffDoc("app", "en")
+	doc.File[0].Body.TransUnit = append(doc.File[0].Body.TransUnit, xliffTransUnit{
+		ID:     "a",
+		Source: xliffSource{Lang: "en", Inner: "Tom & Jerry"},
+		Target: &xliffTarget{Lang: "de", Inner: "Tom & Jerry", State: "translated"},
+		Note:   "a <note>",
+	})
+
+	out := bytes.NewBuffer(nil)
+	if err := writeXliff(out, doc, "  "); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="app" source-language="en" datatype="html">
+    <body>
+      <trans-unit id="a">
+        <source xml:lang="en">Tom &amp; Jerry</source>
+        <target xml:lang="de" state="translated">Tom &amp; Jerry</target>
+        <note>a &lt;note&gt;</note>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	// the written document reads back the same
+	doc2, err := xliffFromReader(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unit := doc2.File[0].Body.TransUnit[0]
+	if unit.Source.Inner != "Tom & Jerry" || unit.Target.State != "translated" || unit.Note != "a <note>" {
+		t.Errorf("unexpected unit after reading back: %+v", unit)
+	}
+}
@@ -56,6 +56,7 @@ const (
 )
 
 type xlsxConverter struct {
+	name         string // "from-xlsx" or "from-ods"
 	fileName     string
 	skipRows     int
 	sheetNumber  int
@@ -86,22 +87,33 @@ type xlsxExporter interface {
 	Filename() string
 }
 
+// the input format (.xlsx or .ods) is detected, "from-ods" is the same
+// converter under another name
 func init() {
-	registeredConverters["from-xlsx"] = new(xlsxConverter)
+	registeredConverters["from-xlsx"] = &xlsxConverter{name: "from-xlsx"}
+	registeredConverters["from-ods"] = &xlsxConverter{name: "from-ods"}
 }
 
 func (x *xlsxConverter) Description() string {
+	if x.name == "from-ods" {
+		return "Converts an OpenDocument sheet to XLIFF, JSON"
+	}
 	return "Converts an Excel sheet to XLIFF, JSON"
 }
 
 func (x *xlsxConverter) ParseArgs(base string, args []string) error {
 
-	fs := flag.NewFlagSet(base+" from-xlsx", flag.ExitOnError)
+	fs := flag.NewFlagSet(base+" "+x.name, flag.ExitOnError)
 	destType := "xliff"
 	pretty := false
+	nested := false
+	nestSep := ""
+	xliffVersion := ""
 
 	fs.StringVar(&destType, "to", "json", "output format (xliff, json)")
 	fs.BoolVar(&pretty, "pretty", pretty, "pretty print the output files")
+	fs.BoolVar(&nested, "nested", false, "write nested JSON objects (i18next, vue-i18n) instead of flat keys")
+	fs.StringVar(&nestSep, "nest-sep", ".", "separator which splits keys into nested JSON objects")
 	fs.StringVar(&x.fileName, "in", "", "infile")
 	fs.IntVar(&x.skipRows, "skip-rows", 0, "number of rows to skip")
 	fs.IntVar(&x.sheetNumber, "sheet", 1, "number of the sheet containing the translations")
@@ -112,6 +124,7 @@ func (x *xlsxConverter) ParseArgs(base string, args []string) error {
 	fs.StringVar(&x.sourceLang, "source-lang", "en", "source language")
 	fs.StringVar(&x.targetLang, "target-lang", "en", "target language")
 	fs.StringVar(&x.destDir, "dir", "", "output directory")
+	xliffVersionFlag(fs, &xliffVersion)
 
 	err := fs.Parse(args)
 	if err != nil {
@@ -127,9 +140,12 @@ func (x *xlsxConverter) ParseArgs(base string, args []string) error {
 
 	switch destType {
 	case "json":
-		x.exporter = &xlsxJsonExporter{pretty: pretty}
+		if !nested {
+			nestSep = ""
+		}
+		x.exporter = &xlsxJsonExporter{pretty: pretty, nestSep: nestSep}
 	case "xliff":
-		x.exporter = &xlsxXliffExporter{pretty: pretty}
+		x.exporter = &xlsxXliffExporter{pretty: pretty, version: xliffVersion}
 	default:
 		return fmt.Errorf("unsupported 'type': %q", destType)
 	}
@@ -143,7 +159,7 @@ func (conv *xlsxConverter) Prepare() error {
 
 func (conv *xlsxConverter) Convert(w io.Writer) error {
 
-	var xlFile, err = xlsx.OpenFile(conv.fileName)
+	var xlFile, err = openSpreadsheet(conv.fileName)
 	if err != nil {
 		return err
 	}
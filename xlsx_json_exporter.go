@@ -9,14 +9,14 @@
 package main
 
 import (
-	"encoding/json"
 	"os"
 	"path"
 )
 
 type xlsxJsonExporter struct {
-	file   *os.File
-	pretty bool
+	file    *os.File
+	pretty  bool
+	nestSep string // split keys into nested objects, "" for flat keys
 }
 
 func (exp *xlsxJsonExporter) Open(folder, base string) error {
@@ -39,22 +39,13 @@ func (exp *xlsxJsonExporter) Close() error {
 
 func (exp *xlsxJsonExporter) Write(units []xlsxTransUnit) error {
 
-	var (
-		err     error
-		buf     []byte
-		keyVals = make(map[string]string)
-	)
+	var keyVals = make(map[string]string)
 
 	for _, unit := range units {
 		keyVals[unit.Id] = unit.Target
 	}
 
-	if exp.pretty {
-		buf, err = json.MarshalIndent(&keyVals, "", "\t")
-	} else {
-		buf, err = json.Marshal(&keyVals)
-	}
-
+	buf, err := marshalKeyValues(keyVals, exp.pretty, exp.nestSep)
 	if err == nil {
 		_, err = exp.file.Write(buf)
 	}
@@ -9,14 +9,14 @@
 package main
 
 import (
-	"encoding/xml"
 	"os"
 	"path"
 )
 
 type xlsxXliffExporter struct {
-	file   *os.File
-	pretty bool
+	file    *os.File
+	pretty  bool
+	version string
 }
 
 func (exp *xlsxXliffExporter) Open(folder, base string) error {
@@ -42,8 +42,6 @@ func (exp *xlsxXliffExporter) Write(units []xlsxTransUnit) error {
 	var (
 		doc    = newXliffDoc("", units[0].SourceLang)
 		indent = ""
-		buf    []byte
-		err    error
 	)
 
 	if exp.pretty {
@@ -69,16 +67,9 @@ func (exp *xlsxXliffExporter) Write(units []xlsxTransUnit) error {
 		body.TransUnit = append(body.TransUnit, xliffUnit)
 	}
 
-	if buf, err = xml.MarshalIndent(doc, "", indent); err != nil {
+	if err := doc.SetVersion(exp.version); err != nil {
 		return err
 	}
 
-	if err == nil {
-		if _, err = exp.file.WriteString(xml.Header); err != nil {
-			return err
-		}
-		_, err = exp.file.Write(buf)
-	}
-
-	return err
+	return writeXliff(exp.file, doc, indent)
 }
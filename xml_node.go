@@ -0,0 +1,134 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// xmlNode is a node of a document exactly as it was read: every node
+// keeps the raw bytes of the tokens it was made of. together with the
+// typed xliffDoc this allows to write a document back unchanged except
+// for the parts which were modified.
+type xmlNode struct {
+	raw      []byte     // raw token, the start tag for elements
+	rawEnd   []byte     // raw end tag, empty for self-closing elements
+	name     string     // qualified name of an element, "" for other tokens
+	attrs    []xml.Attr // attributes as written, Name.Space holds the prefix
+	children []*xmlNode
+
+	// text holds the content of the node as the typed model would write
+	// it at the time it was read (eg. for a <source>). if the typed model
+	// still writes the same, the node is written as it was read.
+	text string
+}
+
+// parseXMLNodes reads data into a tree of xmlNodes. the returned node is
+// the document itself, its children are the prolog and the root element.
+func parseXMLNodes(data []byte) (*xmlNode, error) {
+
+	var (
+		dec    = xml.NewDecoder(bytes.NewReader(data))
+		root   = new(xmlNode)
+		stack  = []*xmlNode{root}
+		offset int64
+	)
+
+	for {
+		token, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		next := dec.InputOffset()
+		raw := data[offset:next]
+		offset = next
+		parent := stack[len(stack)-1]
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{raw: raw, name: qualifiedName(t.Name), attrs: t.Copy().Attr}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				parent.rawEnd = raw
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			parent.children = append(parent.children, &xmlNode{raw: raw})
+		}
+	}
+
+	return root, nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// local returns the name of the element without its prefix
+func (node *xmlNode) local() string {
+	if i := strings.IndexByte(node.name, ':'); i >= 0 {
+		return node.name[i+1:]
+	}
+	return node.name
+}
+
+func (node *xmlNode) isElement() bool {
+	return node.name != ""
+}
+
+func (node *xmlNode) isSpace() bool {
+	return node.name == "" && len(bytes.TrimSpace(node.raw)) == 0
+}
+
+func (node *xmlNode) selfClosing() bool {
+	return node.isElement() && len(node.rawEnd) == 0
+}
+
+// elements returns all child elements with the given local name
+func (node *xmlNode) elements(local string) []*xmlNode {
+	if node == nil {
+		return nil
+	}
+	var nodes []*xmlNode
+	for _, child := range node.children {
+		if child.isElement() && child.local() == local {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+// element returns the first child element with the given local name
+func (node *xmlNode) element(local string) *xmlNode {
+	if nodes := node.elements(local); len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
+
+// writeTo writes the node and all its children as they were read
+func (node *xmlNode) writeTo(buf *bytes.Buffer) {
+	buf.Write(node.raw)
+	for _, child := range node.children {
+		child.writeTo(buf)
+	}
+	buf.Write(node.rawEnd)
+}
@@ -0,0 +1,224 @@
+// This file is part of *xliffer*
+//
+// Copyright (C) 2017, Travelping GmbH <copyright@travelping.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// the locale files of Rails (config/locales/en.yml) hold nested keys
+// below the language:
+//
+//	en:
+//	  cart:
+//	    # shown above the cart
+//	    title: Your cart
+//	    items:
+//	      one: "%{count} item"
+//	      other: "%{count} items"
+//
+// a text becomes a trans-unit with the dotted keys as id ("cart.title"),
+// the comments before it as note. a mapping of plural forms becomes a
+// <group restype="x-yaml-plural"> holding a unit per form
+// ("cart.items.one"). the units of the group keep the number of texts
+// before it in a <context>, so that it is written back where it was.
+const (
+	YAML_PLURAL_RESTYPE = "x-yaml-plural"
+	YAML_CONTEXT_GROUP  = "yaml"
+	YAML_POSITION       = "x-yaml-position"
+)
+
+// yamlEntry is a text of a locale file
+type yamlEntry struct {
+	Path    []string
+	Value   string
+	Comment string
+	Plural  bool // Path ends with a plural form
+}
+
+func (e *yamlEntry) key() string {
+	return strings.Join(e.Path, ".")
+}
+
+func yamlFromFile(fileName string) (lang string, entries []yamlEntry, err error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	if lang, entries, err = readYAML(f); err != nil {
+		return "", nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	return lang, entries, nil
+}
+
+// readYAML reads the texts of a locale file in order, and the language
+// it is rooted at
+func readYAML(r io.Reader) (lang string, entries []yamlEntry, err error) {
+
+	var doc yaml.Node
+	if err = yaml.NewDecoder(r).Decode(&doc); err != nil {
+		return "", nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return "", nil, fmt.Errorf("expected a mapping")
+	}
+	root := doc.Content[0]
+	if len(root.Content) != 2 || root.Content[1].Kind != yaml.MappingNode {
+		return "", nil, fmt.Errorf("expected a single language at the top")
+	}
+
+	lang = root.Content[0].Value
+	readYAMLMapping(root.Content[1], nil, &entries)
+	return lang, entries, nil
+}
+
+func readYAMLMapping(node *yaml.Node, path []string, entries *[]yamlEntry) {
+
+	plural := isYAMLPlural(node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := append(path[:len(path):len(path)], key.Value)
+		for value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+
+		switch {
+		case value.Kind == yaml.MappingNode:
+			readYAMLMapping(value, keyPath, entries)
+		case value.Kind == yaml.ScalarNode && value.ShortTag() == "!!str":
+			*entries = append(*entries, yamlEntry{
+				Path:    keyPath,
+				Value:   value.Value,
+				Comment: yamlComment(key.HeadComment, value.LineComment),
+				Plural:  plural,
+			})
+		case value.Kind == yaml.ScalarNode && value.ShortTag() == "!!null":
+		default:
+			log.Printf("warning: %s: only texts are supported, ignored", strings.Join(keyPath, "."))
+		}
+	}
+}
+
+// isYAMLPlural tells whether all keys of a mapping are plural forms
+func isYAMLPlural(node *yaml.Node) bool {
+	if len(node.Content) == 0 {
+		return false
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if !isQuantity(node.Content[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// yamlComment returns the text of comments, without the #
+func yamlComment(comments ...string) string {
+	var lines []string
+	for _, comment := range comments {
+		if comment == "" {
+			continue
+		}
+		for _, line := range strings.Split(comment, "\n") {
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(line), "#"), " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeYAML writes entries as locale file of lang, the notes as comments
+func writeYAML(w io.Writer, lang string, entries []yamlEntry) error {
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, e := range entries {
+		node := root
+		for i, key := range e.Path {
+			var child *yaml.Node
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == key {
+					child = node.Content[j+1]
+				}
+			}
+			last := i == len(e.Path)-1
+			if child != nil && (last || child.Kind != yaml.MappingNode) {
+				what := "holds other keys"
+				if child.Kind != yaml.MappingNode {
+					what = "is a text"
+				}
+				return fmt.Errorf("can't write %q: %q %s already", e.key(), strings.Join(e.Path[:i+1], "."), what)
+			}
+			if child == nil {
+				keyNode := yamlScalar(key)
+				child = &yaml.Node{Kind: yaml.MappingNode}
+				if last {
+					child = yamlScalar(e.Value)
+					if e.Comment != "" {
+						keyNode.HeadComment = "# " + strings.Replace(e.Comment, "\n", "\n# ", -1)
+					}
+				}
+				node.Content = append(node.Content, keyNode, child)
+			}
+			node = child
+		}
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			yamlScalar(lang),
+			root,
+		},
+	}}}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yamlScalar returns the node of a text or key, quoted if YAML (1.1, as
+// read by Ruby) would take it for something else
+func yamlScalar(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if yamlNeedsQuotes(value) {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	return node
+}
+
+func yamlNeedsQuotes(value string) bool {
+	if value == "" {
+		return true
+	}
+	switch strings.ToLower(value) {
+	case "y", "n", "yes", "no", "on", "off", "true", "false", "null", "~":
+		return true
+	}
+	if strings.IndexAny(value[:1], "%@`!&*|>'\"#{}[],?:-~ ") >= 0 {
+		return true
+	}
+	if strings.TrimSpace(value) != value || strings.Contains(value, ": ") || strings.Contains(value, " #") {
+		return true
+	}
+	// numbers, times (1:30) and dates
+	return yamlNumeric.MatchString(value)
+}
+
+var yamlNumeric = regexp.MustCompile(`^[-+.]?[0-9][0-9a-fA-Fox_:.eE+-]*$`)
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestYAMLRoundTrip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xlf := filepath.Join(dir, "pl.xlf")
+
+	doc := convertXliff(t, &fromYAML{inFile: "testdata/yaml/en.yml", targetFile: "testdata/yaml/pl.yml"}, xlf)
+	file := doc.File[0]
+	if file.SourceLang != "en" || file.TargetLang != "pl" {
+		t.Errorf("expected en -> pl, got %s -> %s", file.SourceLang, file.TargetLang)
+	}
+	checkUnits(t, &file.Body, describeUnit, map[string]string{
+		"shop.title":            "Shop -> Sklep # title of the start page",
+		"shop.greeting":         "%{name}, welcome! -> %{name}, witaj! # ",
+		"shop.cart.empty":       "Your cart is empty ->  # shown if the cart is empty\n(no items at all)",
+		"shop.cart.items.one":   "%{count} item -> %{count} produkt # ",
+		"shop.cart.items.few":   "%{count} items -> %{count} produkty # ",
+		"shop.cart.items.many":  "%{count} items -> %{count} produktów # ",
+		"shop.cart.items.other": "%{count} items -> %{count} produktu # ",
+		"answers.yes":           "yes -> tak # ",
+		"answers.no":            "no -> nie # ",
+		"answers.unknown":       "~ ->  # ",
+		"answers.opening":       "9:30 ->  # ",
+	})
+	if restype := attrValue(file.Body.Group[0].Attrs, "restype"); file.Body.Group[0].ID != "shop.cart.items" || restype != YAML_PLURAL_RESTYPE {
+		t.Errorf("expected the plural group shop.cart.items, got %s (%s)", file.Body.Group[0].ID, restype)
+	}
+
+	checkConverted(t, &toYAML{inFile: xlf}, "testdata/yaml/pl.yml")
+
+	// the forms of polish only are left out, the plural group stays
+	// in front of cart.empty
+	expected := strings.TrimSuffix(readFile(t, "testdata/yaml/en.yml"), "  number:\n    precision: 2\n")
+	checkText(t, "en.yml", expected, converted(t, &toYAML{inFile: xlf, source: true}))
+}
+
+func TestWriteYAML(t *testing.T) {
+
+	entries := []yamlEntry{
+		{Path: []string{"a", "on"}, Value: "off"},
+		{Path: []string{"a", "percent"}, Value: "%{n} %"},
+		{Path: []string{"a", "date"}, Value: "2017-01-31"},
+		{Path: []string{"a", "plain"}, Value: "1st place"},
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := writeYAML(buf, "de", entries); err != nil {
+		t.Fatal(err)
+	}
+	expected := "de:\n  a:\n    \"on\": \"off\"\n    percent: \"%{n} %\"\n    date: \"2017-01-31\"\n    plain: 1st place\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf)
+	}
+
+	lang, read, err := readYAML(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang != "de" || len(read) != len(entries) || read[0].Value != "off" || read[0].key() != "a.on" {
+		t.Errorf("unexpected %s %v", lang, read)
+	}
+
+	err = writeYAML(ioutil.Discard, "de", append(entries, yamlEntry{Path: []string{"a", "on", "x"}, Value: "x"}))
+	if err == nil || !strings.Contains(err.Error(), `"a.on" is a text already`) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+}